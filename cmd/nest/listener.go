@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"dxkite.cn/meownest/pkg/proxyproto"
+	"dxkite.cn/meownest/src/config"
+)
+
+// 一组可在运行时变更的监听
+type listenerGroup struct {
+	name string
+	// 是否支持 HTTPS 监听
+	tls      bool
+	serve    func(l net.Listener, tls bool) error
+	shutdown func(ctx context.Context, l net.Listener) error
+	// 服务异常退出时写入
+	errCh chan<- error
+
+	mtx     sync.Mutex
+	running map[string]*runningListener
+}
+
+type runningListener struct {
+	ln *config.Listen
+	l  net.Listener
+}
+
+func newListenerGroup(name string, tls bool, serve func(l net.Listener, tls bool) error, shutdown func(ctx context.Context, l net.Listener) error, errCh chan<- error) *listenerGroup {
+	return &listenerGroup{name: name, tls: tls, serve: serve, shutdown: shutdown, errCh: errCh, running: map[string]*runningListener{}}
+}
+
+// 更新监听，启动新增的监听，并在 timeout 内停止移除的监听
+//
+// 新监听与移除的监听地址相同时，等待旧监听停止后再启动
+func (g *listenerGroup) Listen(specs []string, timeout time.Duration) error {
+	next := map[string]*config.Listen{}
+	for _, spec := range specs {
+		ln, err := config.ParseListen(spec)
+		if err != nil {
+			return err
+		}
+		if ln.TLS && !g.tls {
+			return fmt.Errorf("%s does not support https listen %s", g.name, spec)
+		}
+		next[ln.Spec] = ln
+	}
+
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	var wg sync.WaitGroup
+	for spec, r := range g.running {
+		if _, ok := next[spec]; ok {
+			continue
+		}
+		delete(g.running, spec)
+
+		done := make(chan struct{})
+		go func(r *runningListener) {
+			defer close(done)
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := g.shutdown(ctx, r.l); err != nil {
+				log.Printf("%s stop %s error: %s\n", g.name, r.ln.Spec, err)
+			}
+		}(r)
+
+		if conflictListen(r.ln, next) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-done
+			}()
+		}
+	}
+	wg.Wait()
+
+	var errs []error
+	for spec, ln := range next {
+		if _, ok := g.running[spec]; ok {
+			continue
+		}
+
+		l, err := listen(ln)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s listen %s: %w", g.name, spec, err))
+			continue
+		}
+
+		g.running[spec] = &runningListener{ln: ln, l: l}
+		go func(ln *config.Listen) {
+			// 服务注册监听前被移除时监听已关闭，视为正常停止
+			if err := g.serve(l, ln.TLS); err != nil && !errors.Is(err, net.ErrClosed) {
+				// 只通知第一个错误，其余记录日志
+				err = fmt.Errorf("%s %s: %w", g.name, ln.Spec, err)
+				select {
+				case g.errCh <- err:
+				default:
+					log.Println(err)
+				}
+			}
+		}(ln)
+		log.Printf("%s listening on %s\n", g.name, spec)
+	}
+	return errors.Join(errs...)
+}
+
+func conflictListen(ln *config.Listen, next map[string]*config.Listen) bool {
+	for _, v := range next {
+		if v.Network == ln.Network && v.Address == ln.Address {
+			return true
+		}
+	}
+	return false
+}
+
+func listen(ln *config.Listen) (net.Listener, error) {
+	if ln.Network == "unix" {
+		// 清理上次未正常退出遗留的 socket 文件
+		if info, err := os.Stat(ln.Address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(ln.Address)
+		}
+	}
+
+	l, err := net.Listen(ln.Network, ln.Address)
+	if err != nil {
+		return nil, err
+	}
+	if ln.ProxyProtocol {
+		pl := proxyproto.NewListener(l)
+		pl.Trusted = ln.ProxyTrusted
+		return pl, nil
+	}
+	return l, nil
+}
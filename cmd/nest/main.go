@@ -2,12 +2,20 @@ package main
 
 import (
 	"context"
+	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"reflect"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"dxkite.cn/meownest/pkg/acme"
 	"dxkite.cn/meownest/pkg/agent"
+	pkgconfig "dxkite.cn/meownest/pkg/config"
 	"dxkite.cn/meownest/pkg/config/env"
 	"dxkite.cn/meownest/pkg/database"
 	"dxkite.cn/meownest/pkg/database/sqlite"
@@ -61,59 +69,76 @@ func main() {
 	db := ds.Engine().(*gorm.DB)
 	db.AutoMigrate(entity.Certificate{}, entity.User{}, entity.Session{},
 		entity.DynamicStat{},
-		entity.Collection{}, entity.Route{}, entity.Endpoint{}, entity.Authorize{})
-
-	certificateRepository := repository.NewCertificate()
-	certificateService := service.NewCertificate(certificateRepository)
-	certificateServer := server.NewCertificate(certificateService)
-
-	SessionIdName := cfg.SessionName
+		entity.Collection{}, entity.Route{}, entity.Endpoint{}, entity.Authorize{},
+		entity.AuthorizeCredential{},
+		entity.Stream{})
 
 	userRepository := repository.NewUser()
 	sessionRepository := repository.NewSession()
 	userService := service.NewUser(userRepository, sessionRepository, []byte(cfg.SessionCryptoKey))
-	userServer := server.NewUser(userService, SessionIdName)
+	userServer := server.NewUser(userService, cfg.SessionName)
 
 	authorizeRepository := repository.NewAuthorize()
-	authorizeService := service.NewAuthorize(authorizeRepository)
+	authorizeCredentialRepository := repository.NewAuthorizeCredential()
+	endpointRepository := repository.NewEndpoint()
+	routeRepository := repository.NewRoute()
+	collectionRepository := repository.NewCollection()
+	certificateRepository := repository.NewCertificate()
+	streamRepository := repository.NewStream()
+
+	ag := agent.New()
+	ag.SetTunnelTimeout(cfg.AgentTunnelIdleTimeout, cfg.AgentTunnelMaxLifetime)
+	ag.SetHTTPSPort(config.HTTPSPort(cfg.AgentListen))
+	agentService := service.NewAgent(ag,
+		routeRepository, collectionRepository,
+		endpointRepository, authorizeRepository,
+		authorizeCredentialRepository,
+		certificateRepository, streamRepository,
+	)
+	agentServer := server.NewAgent(agentService)
+
+	authorizeService := service.NewAuthorize(authorizeRepository, authorizeCredentialRepository, agentService)
 	authorizeServer := server.NewAuthorize(authorizeService)
 
-	endpointRepository := repository.NewEndpoint()
-	endpointService := service.NewEndpoint(endpointRepository)
+	authorizeCredentialService := service.NewAuthorizeCredential(authorizeCredentialRepository, authorizeRepository, agentService)
+	authorizeCredentialServer := server.NewAuthorizeCredential(authorizeCredentialService)
+
+	endpointService := service.NewEndpoint(endpointRepository, agentService, agentService)
 	endpointServer := server.NewEndpoint(endpointService)
 
-	routeRepository := repository.NewRoute()
-	collectionRepository := repository.NewCollection()
 	collectionService := service.NewCollection(
 		collectionRepository, routeRepository,
 		endpointRepository, authorizeRepository,
+		agentService,
 	)
 
 	routeService := service.NewRoute(
 		routeRepository, endpointRepository,
 		collectionRepository, authorizeRepository,
+		agentService,
 	)
 	routeServer := server.NewRoute(routeService)
 
 	collectionServer := server.NewCollection(collectionService)
 
-	ag := agent.New()
-	agentService := service.NewAgent(ag,
-		routeRepository, collectionRepository,
-		endpointRepository, authorizeRepository,
-	)
-	agentServer := server.NewAgent(agentService)
+	certificateService := service.NewCertificate(certificateRepository, agentService)
+	certificateServer := server.NewCertificate(certificateService)
+
+	streamService := service.NewStream(streamRepository, endpointRepository, agentService)
+	streamServer := server.NewStream(streamService)
 
 	monitorRepository := repository.NewMonitor()
-	// 5秒 统计一次，记录最新1小时数据，5分钟聚合一次
-	monitorService := service.NewMonitor(&service.MonitorConfig{
-		Interval:     3,
-		RollInterval: 300,
-		MaxInterval:  3600,
-	}, monitorRepository)
+	monitorService := service.NewMonitor(newMonitorConfig(&cfg), monitorRepository)
 	monitorServer := server.NewMonitor(monitorService)
 
-	go monitorService.Collection(database.With(context.Background(), ds))
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	monitorDone := make(chan struct{})
+	go func() {
+		monitorService.Collection(database.With(ctx, ds))
+		close(monitorDone)
+	}()
 
 	httpServer := httpserver.New()
 
@@ -134,7 +159,7 @@ func main() {
 
 	httpServer.Use(httpserver.Identity(httpserver.IdentityConfig{
 		Ident: func(ctx *gin.Context) (id uint64, scopes []string, err error) {
-			cookie, _ := ctx.Cookie(SessionIdName)
+			cookie, _ := ctx.Cookie(userServer.SessionName())
 			if cookie != "" {
 				return userService.GetSession(ctx, cookie)
 			}
@@ -158,14 +183,162 @@ func main() {
 	httpServer.HandlePrefix(APIBase, userServer.API())
 	httpServer.HandlePrefix(APIBase, routeServer.API())
 	httpServer.HandlePrefix(APIBase, endpointServer.API())
+	httpServer.HandlePrefix(APIBase, streamServer.API())
 	httpServer.HandlePrefix(APIBase, authorizeServer.API())
+	httpServer.HandlePrefix(APIBase, authorizeCredentialServer.API())
 	httpServer.HandlePrefix(APIBase, collectionServer.API())
 	httpServer.HandlePrefix(APIBase, agentServer.API())
 	httpServer.HandlePrefix(APIBase, monitorServer.API())
 	httpServer.Handle(server.NewSwagger().API())
 
-	go httpServer.Run(":2333")
+	runErr := make(chan error, 1)
+	adminListener := newListenerGroup("admin", false, func(l net.Listener, tls bool) error {
+		return httpServer.Serve(l)
+	}, httpServer.ShutdownListener, runErr)
+	agentListener := newListenerGroup("agent", true, func(l net.Listener, tls bool) error {
+		if tls {
+			return agentService.ServeTLS(l)
+		}
+		return agentService.Serve(l)
+	}, agentService.ShutdownListener, runErr)
+
+	// 监听失败时跳过，其余监听正常启动
+	if err := adminListener.Listen([]string{cfg.AdminListen}, cfg.ShutdownTimeout); err != nil {
+		log.Println("listen error:", err)
+	}
 
 	agentService.LoadRoute(database.With(context.Background(), ds))
-	agentService.Run(":80")
+	agentService.LoadCertificate(database.With(context.Background(), ds))
+	agentService.LoadStream(database.With(context.Background(), ds))
+	if cfg.AcmeDirectory != "" {
+		key, err := acme.LoadOrCreateKey(cfg.AcmeAccountKey)
+		if err != nil {
+			panic(err)
+		}
+
+		solver := acme.NewHTTPSolver()
+		ag.UseChallenge(solver)
+
+		acmeService := service.NewAcme(&service.AcmeConfig{
+			RenewBefore: cfg.AcmeRenewBefore,
+			Interval:    cfg.AcmeInterval,
+		}, acme.NewClient(&acme.Config{
+			Directory: cfg.AcmeDirectory,
+			Email:     cfg.AcmeEmail,
+			Key:       key,
+			Insecure:  cfg.AcmeInsecure,
+		}, solver), collectionRepository, certificateRepository, agentService)
+
+		go acmeService.Run(database.With(ctx, ds))
+	}
+
+	if err := agentListener.Listen(cfg.AgentListen, cfg.ShutdownTimeout); err != nil {
+		log.Println("listen error:", err)
+	}
+
+	// 重新加载配置
+	var cfgMtx sync.Mutex
+	bootCfg := cfg
+	configProvider.Watch(func() {
+		next := config.Config{}
+		if err := configProvider.Bind(&next); err != nil {
+			log.Println("reload config error:", err)
+			return
+		}
+
+		cfgMtx.Lock()
+		defer cfgMtx.Unlock()
+
+		for _, change := range pkgconfig.Diff(&bootCfg, &next) {
+			if change.Restart {
+				log.Printf("config %s changed, restart required\n", change.Name)
+			}
+		}
+
+		userServer.SetSessionName(next.SessionName)
+		monitorService.SetConfig(newMonitorConfig(&next))
+		ag.SetTunnelTimeout(next.AgentTunnelIdleTimeout, next.AgentTunnelMaxLifetime)
+		ag.SetHTTPSPort(config.HTTPSPort(next.AgentListen))
+		if err := adminListener.Listen([]string{next.AdminListen}, next.ShutdownTimeout); err != nil {
+			log.Println("reload listen error:", err)
+		}
+		if err := agentListener.Listen(next.AgentListen, next.ShutdownTimeout); err != nil {
+			log.Println("reload listen error:", err)
+		}
+		cfg = next
+		log.Println("config reloaded")
+	})
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for range hup {
+			if err := configProvider.Reload(); err != nil {
+				log.Println("reload config error:", err)
+			}
+		}
+	}()
+
+	cfgMtx.Lock()
+	watchInterval := cfg.ConfigWatchInterval
+	cfgMtx.Unlock()
+	if watchInterval > 0 {
+		go configProvider.WatchFile(ctx, watchInterval, func(err error) {
+			log.Println("reload config error:", err)
+		})
+	}
+
+	select {
+	case <-ctx.Done():
+		log.Println("shutting down")
+	case err := <-runErr:
+		log.Println("server error:", err)
+	}
+	stop()
+
+	cfgMtx.Lock()
+	timeout := cfg.ShutdownTimeout
+	cfgMtx.Unlock()
+
+	shutdown(timeout, func(ctx context.Context) error {
+		return agentService.Shutdown(ctx)
+	}, func(ctx context.Context) error {
+		return httpServer.Shutdown(ctx)
+	})
+
+	<-monitorDone
+	if err := monitorService.Flush(database.With(context.Background(), ds)); err != nil {
+		log.Println("flush monitor error:", err)
+	}
+
+	if err := ds.Close(); err != nil {
+		log.Println("close data source error:", err)
+	}
+}
+
+func newMonitorConfig(cfg *config.Config) *service.MonitorConfig {
+	return &service.MonitorConfig{
+		Interval:     int(cfg.MonitorInterval / time.Second),
+		RollInterval: int(cfg.MonitorRollInterval / time.Second),
+		MaxInterval:  int(cfg.MonitorMaxInterval / time.Second),
+	}
+}
+
+// 并行停止服务，超过 timeout 后强制关闭
+func shutdown(timeout time.Duration, fns ...func(ctx context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, fn := range fns {
+		wg.Add(1)
+		go func(fn func(ctx context.Context) error) {
+			defer wg.Done()
+			if err := fn(ctx); err != nil {
+				log.Println("shutdown error:", err)
+			}
+		}(fn)
+	}
+	wg.Wait()
 }
@@ -15,6 +15,72 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/agent/match": {
+            "get": {
+                "description": "测试请求会匹配的路由，不转发请求",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Agent"
+                ],
+                "summary": "路由匹配测试",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "请求方法",
+                        "name": "method",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "请求域名",
+                        "name": "host",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "请求路径",
+                        "name": "path",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "请求头 Name: Value",
+                        "name": "header",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.RouteMatch"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    }
+                }
+            }
+        },
         "/agent/reload": {
             "post": {
                 "description": "重载代理服务路由",
@@ -47,6 +113,41 @@ const docTemplate = `{
                 }
             }
         },
+        "/agent/status": {
+            "get": {
+                "description": "当前配置版本与路由加载错误",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Agent"
+                ],
+                "summary": "代理服务状态",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AgentStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    }
+                }
+            }
+        },
         "/authorizes": {
             "get": {
                 "description": "Authorize列表",
@@ -306,6 +407,167 @@ const docTemplate = `{
                 }
             }
         },
+        "/authorizes/{id}/credentials": {
+            "get": {
+                "description": "AuthorizeCredential列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authorize"
+                ],
+                "summary": "AuthorizeCredential列表",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authorize ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "是否包含total",
+                        "name": "include_total",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页数量",
+                        "name": "pre_page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.ListAuthorizeCredentialResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "创建 Basic 用户或 API Key，API Key 只在创建时返回",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authorize"
+                ],
+                "summary": "Create AuthorizeCredential",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authorize ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "数据",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.CreateAuthorizeCredentialParam"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AuthorizeCredential"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    }
+                }
+            }
+        },
+        "/authorizes/{id}/credentials/{credential_id}": {
+            "delete": {
+                "description": "Delete AuthorizeCredential",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authorize"
+                ],
+                "summary": "Delete AuthorizeCredential",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authorize ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Credential ID",
+                        "name": "credential_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    }
+                }
+            }
+        },
         "/certificates": {
             "get": {
                 "description": "证书列表",
@@ -1095,9 +1357,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/monitor/dynamic-stat": {
+        "/endpoints/{id}/health": {
             "get": {
-                "description": "List Dynamic Stat",
+                "description": "后端健康状态",
                 "consumes": [
                     "application/json"
                 ],
@@ -1105,28 +1367,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Monitor"
+                    "Endpoint"
                 ],
-                "summary": "List Dynamic Stat",
+                "summary": "后端健康状态",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "开始时间。默认-1h",
-                        "name": "start_time",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "结束时间，默认当前时间",
-                        "name": "end_time",
-                        "in": "query"
+                        "description": "Endpoint ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/service.DynamicStatResult"
+                            "$ref": "#/definitions/dto.EndpointHealth"
                         }
                     },
                     "400": {
@@ -1144,9 +1401,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/routes": {
+        "/monitor/dynamic-stat": {
             "get": {
-                "description": "路由列表",
+                "description": "List Dynamic Stat",
                 "consumes": [
                     "application/json"
                 ],
@@ -1154,7 +1411,56 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "路由"
+                    "Monitor"
+                ],
+                "summary": "List Dynamic Stat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "开始时间。默认-1h",
+                        "name": "start_time",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间，默认当前时间",
+                        "name": "end_time",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.DynamicStatResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    }
+                }
+            }
+        },
+        "/routes": {
+            "get": {
+                "description": "路由列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "路由"
                 ],
                 "summary": "路由列表",
                 "parameters": [
@@ -1409,9 +1715,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users": {
+        "/streams": {
             "get": {
-                "description": "User list",
+                "description": "Stream list",
                 "consumes": [
                     "application/json"
                 ],
@@ -1419,16 +1725,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Stream"
                 ],
-                "summary": "User list",
+                "summary": "Stream list",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User",
+                        "description": "Stream",
                         "name": "name",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "监听地址",
+                        "name": "listen",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "后端服务ID",
+                        "name": "endpoint_id",
+                        "in": "query"
+                    },
                     {
                         "type": "boolean",
                         "description": "是否包含total",
@@ -1462,7 +1780,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/service.ListUserResult"
+                            "$ref": "#/definitions/service.ListStreamResult"
                         }
                     },
                     "400": {
@@ -1480,7 +1798,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create User",
+                "description": "Create Stream",
                 "consumes": [
                     "application/json"
                 ],
@@ -1488,17 +1806,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Stream"
                 ],
-                "summary": "Create User",
+                "summary": "Create Stream",
                 "parameters": [
                     {
-                        "description": "User data",
+                        "description": "Stream data",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/service.CreateUserParam"
+                            "$ref": "#/definitions/service.CreateStreamParam"
                         }
                     }
                 ],
@@ -1506,7 +1824,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dto.User"
+                            "$ref": "#/definitions/dto.Stream"
                         }
                     },
                     "400": {
@@ -1524,9 +1842,61 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/session": {
+        "/streams/{id}": {
+            "get": {
+                "description": "Get Stream",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stream"
+                ],
+                "summary": "Get Stream",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Stream ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "expand attribute list",
+                        "name": "expand",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Stream"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Create User CreateSession",
+                "description": "Update Stream",
                 "consumes": [
                     "application/json"
                 ],
@@ -1534,17 +1904,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Stream"
                 ],
-                "summary": "Create User CreateSession",
+                "summary": "Update Stream",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Stream ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "description": "data",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/service.CreateUserSessionParam"
+                            "$ref": "#/definitions/service.UpdateStreamParam"
                         }
                     }
                 ],
@@ -1552,7 +1929,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/service.CreateSessionResult"
+                            "$ref": "#/definitions/dto.Stream"
                         }
                     },
                     "400": {
@@ -1570,7 +1947,7 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete User Session",
+                "description": "Delete Stream",
                 "consumes": [
                     "application/json"
                 ],
@@ -1578,9 +1955,18 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Stream"
+                ],
+                "summary": "Delete Stream",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Stream ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Delete User Session",
                 "responses": {
                     "200": {
                         "description": "OK"
@@ -1600,9 +1986,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{id}": {
+        "/users": {
             "get": {
-                "description": "Get User",
+                "description": "User list",
                 "consumes": [
                     "application/json"
                 ],
@@ -1612,14 +1998,31 @@ const docTemplate = `{
                 "tags": [
                     "User"
                 ],
-                "summary": "Get User",
+                "summary": "User list",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "User",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "是否包含total",
+                        "name": "include_total",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页数量",
+                        "name": "pre_page",
+                        "in": "query"
                     },
                     {
                         "type": "array",
@@ -1627,7 +2030,7 @@ const docTemplate = `{
                             "type": "string"
                         },
                         "collectionFormat": "csv",
-                        "description": "expand attribute list",
+                        "description": "展开数据",
                         "name": "expand",
                         "in": "query"
                     }
@@ -1636,7 +2039,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dto.User"
+                            "$ref": "#/definitions/service.ListUserResult"
                         }
                     },
                     "400": {
@@ -1654,7 +2057,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Update User",
+                "description": "Create User",
                 "consumes": [
                     "application/json"
                 ],
@@ -1664,22 +2067,15 @@ const docTemplate = `{
                 "tags": [
                     "User"
                 ],
-                "summary": "Update User",
+                "summary": "Create User",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "data",
+                        "description": "User data",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/service.UpdateUserParam"
+                            "$ref": "#/definitions/service.CreateUserParam"
                         }
                     }
                 ],
@@ -1703,9 +2099,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete User",
+            }
+        },
+        "/users/session": {
+            "post": {
+                "description": "Create User CreateSession",
                 "consumes": [
                     "application/json"
                 ],
@@ -1715,16 +2113,51 @@ const docTemplate = `{
                 "tags": [
                     "User"
                 ],
-                "summary": "Delete User",
+                "summary": "Create User CreateSession",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "UserID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "data",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.CreateUserSessionParam"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.CreateSessionResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
                     }
+                }
+            },
+            "delete": {
+                "description": "Delete User Session",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
                 ],
+                "summary": "Delete User Session",
                 "responses": {
                     "200": {
                         "description": "OK"
@@ -1743,17 +2176,253 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "dto.Authorize": {
-            "type": "object",
-            "properties": {
-                "attribute": {
-                    "description": "鉴权属性",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/value.AuthorizeAttribute"
+        },
+        "/users/{id}": {
+            "get": {
+                "description": "Get User",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get User",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "expand attribute list",
+                        "name": "expand",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Update User",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Update User",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "data",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.UpdateUserParam"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete User",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Delete User",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UserID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dto.AgentRouteError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "错误信息",
+                    "type": "string"
+                },
+                "method": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "route_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AgentStatus": {
+            "type": "object",
+            "properties": {
+                "endpoints": {
+                    "description": "运行中的后端服务数",
+                    "type": "integer"
+                },
+                "errors": {
+                    "description": "加载失败的路由",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AgentRouteError"
+                    }
+                },
+                "loaded_at": {
+                    "description": "加载时间",
+                    "type": "string"
+                },
+                "routes": {
+                    "description": "已加载的路由数",
+                    "type": "integer"
+                },
+                "stream_errors": {
+                    "description": "加载失败的四层转发",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AgentStreamError"
+                    }
+                },
+                "streams": {
+                    "description": "运行中的四层转发数",
+                    "type": "integer"
+                },
+                "tunnels": {
+                    "description": "websocket 等长连接统计",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.TunnelStats"
+                        }
+                    ]
+                },
+                "version": {
+                    "description": "当前配置版本，每次加载递增",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.AgentStreamError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "错误信息",
+                    "type": "string"
+                },
+                "listen": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "network": {
+                    "$ref": "#/definitions/enum.StreamNetwork"
+                },
+                "stream_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.Authorize": {
+            "type": "object",
+            "properties": {
+                "attribute": {
+                    "description": "鉴权属性",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/value.AuthorizeAttribute"
                         }
                     ]
                 },
@@ -1773,6 +2442,39 @@ const docTemplate = `{
                 },
                 "type": {
                     "description": "鉴权类型",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/enum.AuthorizeType"
+                        }
+                    ]
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AuthorizeCredential": {
+            "type": "object",
+            "properties": {
+                "authorize_id": {
+                    "description": "所属鉴权配置ID",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "description": "创建的 API Key，只在创建时返回",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "用户名或 API Key 的身份",
                     "type": "string"
                 },
                 "updated_at": {
@@ -1780,6 +2482,38 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.AuthorizePolicy": {
+            "type": "object",
+            "properties": {
+                "authorize": {
+                    "description": "生效的鉴权配置",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.Authorize"
+                        }
+                    ]
+                },
+                "disabled": {
+                    "description": "来源关闭了继承的鉴权",
+                    "type": "boolean"
+                },
+                "members": {
+                    "description": "组合鉴权按顺序执行的成员",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AuthorizePolicy"
+                    }
+                },
+                "source": {
+                    "description": "策略来源 route 或 collection，为空表示未配置鉴权",
+                    "type": "string"
+                },
+                "source_id": {
+                    "description": "来源的路由或集合ID",
+                    "type": "string"
+                }
+            }
+        },
         "dto.Certificate": {
             "type": "object",
             "properties": {
@@ -1832,6 +2566,10 @@ const docTemplate = `{
                         }
                     ]
                 },
+                "authorize_disabled": {
+                    "description": "关闭继承的鉴权",
+                    "type": "boolean"
+                },
                 "authorize_id": {
                     "description": "鉴权信息ID",
                     "type": "string"
@@ -1854,6 +2592,10 @@ const docTemplate = `{
                     "description": "后端服务",
                     "type": "string"
                 },
+                "https_redirect": {
+                    "description": "HTTP 请求重定向到 HTTPS",
+                    "type": "boolean"
+                },
                 "id": {
                     "type": "string"
                 },
@@ -2022,25 +2764,98 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.EndpointHealth": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "loaded": {
+                    "description": "是否已被代理服务加载",
+                    "type": "boolean"
+                },
+                "targets": {
+                    "description": "目标状态",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.EndpointTargetHealth"
+                    }
+                }
+            }
+        },
+        "dto.EndpointTargetHealth": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "checked_at": {
+                    "description": "最近主动检查时间",
+                    "type": "string"
+                },
+                "conns": {
+                    "description": "当前连接数",
+                    "type": "integer"
+                },
+                "fails": {
+                    "description": "连续失败次数",
+                    "type": "integer"
+                },
+                "last_error": {
+                    "description": "最近错误",
+                    "type": "string"
+                },
+                "network": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "状态 healthy unhealthy ejected unknown",
+                    "type": "string"
+                },
+                "weight": {
+                    "type": "integer"
+                }
+            }
+        },
         "dto.Route": {
             "type": "object",
             "properties": {
                 "authorize": {
-                    "description": "鉴权信息",
+                    "description": "生效的鉴权信息",
                     "allOf": [
                         {
                             "$ref": "#/definitions/dto.Authorize"
                         }
                     ]
                 },
+                "authorize_disabled": {
+                    "description": "关闭继承的鉴权",
+                    "type": "boolean"
+                },
                 "authorize_id": {
                     "description": "鉴权信息ID",
                     "type": "string"
                 },
+                "authorize_policy": {
+                    "description": "生效的鉴权策略",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.AuthorizePolicy"
+                        }
+                    ]
+                },
                 "collection_id": {
                     "description": "分组ID",
                     "type": "string"
                 },
+                "connect": {
+                    "description": "CONNECT 隧道配置",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/value.RouteConnect"
+                        }
+                    ]
+                },
                 "created_at": {
                     "type": "string"
                 },
@@ -2059,6 +2874,10 @@ const docTemplate = `{
                     "description": "后端服务",
                     "type": "string"
                 },
+                "grpc_web": {
+                    "description": "gRPC-Web 转换",
+                    "type": "boolean"
+                },
                 "id": {
                     "type": "string"
                 },
@@ -2118,6 +2937,107 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.RouteMatch": {
+            "type": "object",
+            "properties": {
+                "matched": {
+                    "description": "是否匹配到路由",
+                    "type": "boolean"
+                },
+                "route": {
+                    "description": "匹配到的路由",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.Route"
+                        }
+                    ]
+                },
+                "rule": {
+                    "description": "匹配规则",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.Stream": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "description": "后端服务",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.Endpoint"
+                        }
+                    ]
+                },
+                "endpoint_id": {
+                    "description": "后端服务ID",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "listen": {
+                    "description": "监听地址",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "network": {
+                    "description": "协议 tcp udp",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/enum.StreamNetwork"
+                        }
+                    ]
+                },
+                "server_names": {
+                    "description": "TLS SNI 匹配的域名",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "stats": {
+                    "description": "转发统计，未运行时为空",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.TunnelStats"
+                        }
+                    ]
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.TunnelStats": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "当前连接数，UDP 为活跃会话数",
+                    "type": "integer"
+                },
+                "bytes_in": {
+                    "description": "客户端发往后端的字节数",
+                    "type": "integer"
+                },
+                "bytes_out": {
+                    "description": "后端发往客户端的字节数",
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "累计连接数",
+                    "type": "integer"
+                }
+            }
+        },
         "dto.User": {
             "type": "object",
             "properties": {
@@ -2151,6 +3071,29 @@ const docTemplate = `{
                 }
             }
         },
+        "enum.AuthorizeType": {
+            "type": "string",
+            "enum": [
+                "binary",
+                "jwt",
+                "basic",
+                "apikey",
+                "forward",
+                "oidc",
+                "any_of",
+                "all_of"
+            ],
+            "x-enum-varnames": [
+                "AuthorizeTypeBinary",
+                "AuthorizeTypeJWT",
+                "AuthorizeTypeBasic",
+                "AuthorizeTypeAPIKey",
+                "AuthorizeTypeForward",
+                "AuthorizeTypeOIDC",
+                "AuthorizeTypeAnyOf",
+                "AuthorizeTypeAllOf"
+            ]
+        },
         "enum.EndpointType": {
             "type": "string",
             "enum": [
@@ -2164,11 +3107,17 @@ const docTemplate = `{
             "type": "string",
             "enum": [
                 "exact",
-                "prefix"
+                "prefix",
+                "param",
+                "regex",
+                "grpc"
             ],
             "x-enum-varnames": [
                 "RoutePathTypeExact",
-                "RoutePathTypePrefix"
+                "RoutePathTypePrefix",
+                "RoutePathTypeParam",
+                "RoutePathTypeRegex",
+                "RoutePathTypeGRPC"
             ]
         },
         "enum.RouteStatus": {
@@ -2182,6 +3131,17 @@ const docTemplate = `{
                 "RouteStatusInactive"
             ]
         },
+        "enum.StreamNetwork": {
+            "type": "string",
+            "enum": [
+                "tcp",
+                "udp"
+            ],
+            "x-enum-varnames": [
+                "StreamNetworkTCP",
+                "StreamNetworkUDP"
+            ]
+        },
         "enum.UserStatus": {
             "type": "string",
             "enum": [
@@ -2218,11 +3178,34 @@ const docTemplate = `{
                 }
             }
         },
+        "service.CreateAuthorizeCredentialParam": {
+            "type": "object",
+            "required": [
+                "id",
+                "name"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "鉴权配置ID",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "用户名或 API Key 的身份",
+                    "type": "string"
+                },
+                "password": {
+                    "description": "Basic 认证的密码，API Key 由服务生成",
+                    "type": "string"
+                }
+            }
+        },
         "service.CreateAuthorizeParam": {
             "type": "object",
             "required": [
                 "attribute",
-                "description",
                 "name",
                 "type"
             ],
@@ -2237,7 +3220,7 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "type": {
-                    "type": "string"
+                    "$ref": "#/definitions/enum.AuthorizeType"
                 }
             }
         },
@@ -2266,6 +3249,10 @@ const docTemplate = `{
                 "name"
             ],
             "properties": {
+                "authorize_disabled": {
+                    "description": "关闭继承的鉴权，用于公开的子集合",
+                    "type": "boolean"
+                },
                 "authorize_id": {
                     "description": "鉴权配置",
                     "type": "string"
@@ -2278,6 +3265,10 @@ const docTemplate = `{
                     "description": "绑定的后端服务",
                     "type": "string"
                 },
+                "https_redirect": {
+                    "description": "HTTP 请求重定向到 HTTPS，子集合与路由继承",
+                    "type": "boolean"
+                },
                 "name": {
                     "description": "分组名",
                     "type": "string"
@@ -2287,7 +3278,7 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "server_names": {
-                    "description": "绑定的域名",
+                    "description": "绑定的域名，支持通配符 *.example.com，未设置时继承上级集合",
                     "type": "array",
                     "items": {
                         "type": "string"
@@ -2335,11 +3326,14 @@ const docTemplate = `{
                 "collection_id",
                 "match_options",
                 "method",
-                "name",
-                "path",
-                "path_type"
+                "modify_options",
+                "name"
             ],
             "properties": {
+                "authorize_disabled": {
+                    "description": "关闭继承的鉴权，用于公开的路由",
+                    "type": "boolean"
+                },
                 "authorize_id": {
                     "description": "鉴权配置",
                     "type": "string"
@@ -2348,6 +3342,14 @@ const docTemplate = `{
                     "description": "路由分组ID",
                     "type": "string"
                 },
+                "connect": {
+                    "description": "CONNECT 隧道，设置后路由只处理目标在允许列表中的 CONNECT 请求",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/value.RouteConnect"
+                        }
+                    ]
+                },
                 "description": {
                     "description": "路由描述",
                     "type": "string"
@@ -2356,6 +3358,10 @@ const docTemplate = `{
                     "description": "绑定的后端服务",
                     "type": "string"
                 },
+                "grpc_web": {
+                    "description": "将 gRPC-Web 请求转换为 gRPC 转发",
+                    "type": "boolean"
+                },
                 "match_options": {
                     "description": "特殊匹配规则",
                     "type": "array",
@@ -2382,7 +3388,7 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "path": {
-                    "description": "匹配路径",
+                    "description": "匹配路径，CONNECT 路由可为空",
                     "type": "string"
                 },
                 "path_rewrite": {
@@ -2395,19 +3401,18 @@ const docTemplate = `{
                 },
                 "path_type": {
                     "description": "匹配路径",
+                    "enum": [
+                        "exact",
+                        "prefix",
+                        "param",
+                        "regex",
+                        "grpc"
+                    ],
                     "allOf": [
                         {
                             "$ref": "#/definitions/enum.RoutePathType"
                         }
                     ]
-                },
-                "status": {
-                    "description": "路由状态",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/enum.RouteStatus"
-                        }
-                    ]
                 }
             }
         },
@@ -2429,11 +3434,55 @@ const docTemplate = `{
                 "token": {
                     "type": "string"
                 },
-                "type": {
+                "type": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.CreateStreamParam": {
+            "type": "object",
+            "required": [
+                "endpoint_id",
+                "listen",
+                "name",
+                "network"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "endpoint_id": {
+                    "description": "后端服务ID",
+                    "type": "string"
+                },
+                "listen": {
+                    "description": "监听地址，如 :3306",
                     "type": "string"
                 },
-                "user_id": {
+                "name": {
                     "type": "string"
+                },
+                "network": {
+                    "description": "协议 tcp udp",
+                    "enum": [
+                        "tcp",
+                        "udp"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/enum.StreamNetwork"
+                        }
+                    ]
+                },
+                "server_names": {
+                    "description": "TLS SNI 匹配的域名，支持通配符 *.example.com，仅 tcp 有效\n相同监听地址的多个转发按 SNI 选择，未设置的作为默认转发\n设置后该监听地址的每个连接都需等待客户端发送数据（最长 5 秒）后才连接后端，\n服务端先发送数据的协议（如 MySQL、SMTP）需使用单独的监听地址",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
@@ -2486,6 +3535,20 @@ const docTemplate = `{
                 }
             }
         },
+        "service.ListAuthorizeCredentialResult": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AuthorizeCredential"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
         "service.ListAuthorizeResult": {
             "type": "object",
             "properties": {
@@ -2556,6 +3619,20 @@ const docTemplate = `{
                 }
             }
         },
+        "service.ListStreamResult": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.Stream"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
         "service.ListUserResult": {
             "type": "object",
             "properties": {
@@ -2574,7 +3651,6 @@ const docTemplate = `{
             "type": "object",
             "required": [
                 "attribute",
-                "description",
                 "id",
                 "name",
                 "type"
@@ -2593,7 +3669,7 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "type": {
-                    "type": "string"
+                    "$ref": "#/definitions/enum.AuthorizeType"
                 }
             }
         },
@@ -2627,6 +3703,10 @@ const docTemplate = `{
                 "name"
             ],
             "properties": {
+                "authorize_disabled": {
+                    "description": "关闭继承的鉴权，用于公开的子集合",
+                    "type": "boolean"
+                },
                 "authorize_id": {
                     "description": "鉴权配置",
                     "type": "string"
@@ -2639,6 +3719,10 @@ const docTemplate = `{
                     "description": "绑定的后端服务",
                     "type": "string"
                 },
+                "https_redirect": {
+                    "description": "HTTP 请求重定向到 HTTPS，子集合与路由继承",
+                    "type": "boolean"
+                },
                 "id": {
                     "type": "string"
                 },
@@ -2651,7 +3735,7 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "server_names": {
-                    "description": "绑定的域名",
+                    "description": "绑定的域名，支持通配符 *.example.com，未设置时继承上级集合",
                     "type": "array",
                     "items": {
                         "type": "string"
@@ -2701,132 +3785,480 @@ const docTemplate = `{
             "type": "object",
             "required": [
                 "id",
-                "match_options"
+                "match_options",
+                "modify_options"
             ],
             "properties": {
+                "authorize_disabled": {
+                    "description": "关闭继承的鉴权",
+                    "type": "boolean"
+                },
                 "authorize_id": {
                     "description": "鉴权配置",
                     "type": "string"
                 },
-                "collection_id": {
-                    "description": "路由分组ID",
+                "collection_id": {
+                    "description": "路由分组ID",
+                    "type": "string"
+                },
+                "connect": {
+                    "description": "CONNECT 隧道",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/value.RouteConnect"
+                        }
+                    ]
+                },
+                "description": {
+                    "description": "路由描述",
+                    "type": "string"
+                },
+                "endpoint_id": {
+                    "description": "绑定的后端服务",
+                    "type": "string"
+                },
+                "grpc_web": {
+                    "description": "将 gRPC-Web 请求转换为 gRPC 转发",
+                    "type": "boolean"
+                },
+                "id": {
+                    "description": "ID",
+                    "type": "string"
+                },
+                "match_options": {
+                    "description": "特殊匹配规则",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/value.MatchOption"
+                    }
+                },
+                "method": {
+                    "description": "支持方法",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "modify_options": {
+                    "description": "数据编辑",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/value.ModifyOption"
+                    }
+                },
+                "name": {
+                    "description": "路由名称",
+                    "type": "string"
+                },
+                "path": {
+                    "description": "匹配路径",
+                    "type": "string"
+                },
+                "path_rewrite": {
+                    "description": "路径重写",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/value.PathRewrite"
+                        }
+                    ]
+                },
+                "path_type": {
+                    "description": "匹配路径类型",
+                    "enum": [
+                        "exact",
+                        "prefix",
+                        "param",
+                        "regex",
+                        "grpc"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/enum.RoutePathType"
+                        }
+                    ]
+                },
+                "status": {
+                    "description": "路由状态",
+                    "enum": [
+                        "active",
+                        "inactive"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/enum.RouteStatus"
+                        }
+                    ]
+                }
+            }
+        },
+        "service.UpdateStreamParam": {
+            "type": "object",
+            "required": [
+                "endpoint_id",
+                "id",
+                "listen",
+                "name",
+                "network"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "endpoint_id": {
+                    "description": "后端服务ID",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "listen": {
+                    "description": "监听地址，如 :3306",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "network": {
+                    "description": "协议 tcp udp",
+                    "enum": [
+                        "tcp",
+                        "udp"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/enum.StreamNetwork"
+                        }
+                    ]
+                },
+                "server_names": {
+                    "description": "TLS SNI 匹配的域名，支持通配符 *.example.com，仅 tcp 有效\n相同监听地址的多个转发按 SNI 选择，未设置的作为默认转发\n设置后该监听地址的每个连接都需等待客户端发送数据（最长 5 秒）后才连接后端，\n服务端先发送数据的协议（如 MySQL、SMTP）需使用单独的监听地址",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "service.UpdateUserParam": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "value.AuthorizeAttribute": {
+            "type": "object",
+            "properties": {
+                "apikey": {
+                    "$ref": "#/definitions/value.AuthorizeAttributeAPIKey"
+                },
+                "basic": {
+                    "$ref": "#/definitions/value.AuthorizeAttributeBasic"
+                },
+                "binary": {
+                    "$ref": "#/definitions/value.AuthorizeAttributeBinary"
+                },
+                "forward": {
+                    "$ref": "#/definitions/value.AuthorizeAttributeForward"
+                },
+                "jwt": {
+                    "$ref": "#/definitions/value.AuthorizeAttributeJWT"
+                },
+                "oidc": {
+                    "$ref": "#/definitions/value.AuthorizeAttributeOIDC"
+                },
+                "policy": {
+                    "$ref": "#/definitions/value.AuthorizeAttributePolicy"
+                }
+            }
+        },
+        "value.AuthorizeAttributeAPIKey": {
+            "type": "object",
+            "required": [
+                "sources"
+            ],
+            "properties": {
+                "header": {
+                    "description": "认证通过后写入凭证名称的请求头",
+                    "type": "string"
+                },
+                "sources": {
+                    "description": "凭证来源，为空时使用 X-API-Key 请求头",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/value.AuthorizeSource"
+                    }
+                }
+            }
+        },
+        "value.AuthorizeAttributeBasic": {
+            "type": "object",
+            "required": [
+                "sources"
+            ],
+            "properties": {
+                "header": {
+                    "description": "认证通过后写入用户名的请求头",
+                    "type": "string"
+                },
+                "realm": {
+                    "description": "认证域",
+                    "type": "string"
+                },
+                "sources": {
+                    "description": "凭证来源，为空时使用 Authorization 请求头",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/value.AuthorizeSource"
+                    }
+                }
+            }
+        },
+        "value.AuthorizeAttributeBinary": {
+            "type": "object",
+            "required": [
+                "sources"
+            ],
+            "properties": {
+                "header": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "sources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/value.AuthorizeSource"
+                    }
+                }
+            }
+        },
+        "value.AuthorizeAttributeForward": {
+            "type": "object",
+            "required": [
+                "endpoint_id"
+            ],
+            "properties": {
+                "cache_ttl": {
+                    "description": "鉴权结果缓存时间，单位秒，为 0 或未配置请求头与 Cookie 时不缓存\n按请求方法、域名、地址、客户端 IP 与凭证缓存",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "cookies": {
+                    "description": "转发到鉴权服务的 Cookie",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "endpoint_id": {
+                    "description": "鉴权服务的后端服务ID",
                     "type": "string"
                 },
-                "description": {
-                    "description": "路由描述",
-                    "type": "string"
+                "headers": {
+                    "description": "转发到鉴权服务的请求头",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "endpoint_id": {
-                    "description": "绑定的后端服务",
+                "method": {
+                    "description": "子请求方法，默认 GET",
                     "type": "string"
                 },
-                "id": {
-                    "description": "ID",
+                "path": {
+                    "description": "子请求路径，默认 /",
                     "type": "string"
                 },
-                "match_options": {
-                    "description": "特殊匹配规则",
+                "response_headers": {
+                    "description": "鉴权通过后复制到后端请求的响应头",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/value.MatchOption"
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "value.AuthorizeAttributeJWT": {
+            "type": "object",
+            "required": [
+                "algorithms",
+                "claim_headers",
+                "sources"
+            ],
+            "properties": {
+                "algorithms": {
+                    "description": "允许的签名算法",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
                     }
                 },
-                "method": {
-                    "description": "支持方法",
+                "audience": {
+                    "description": "受众，匹配任意一个",
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
-                "modify_options": {
-                    "description": "数据编辑",
+                "claim_headers": {
+                    "description": "转发到后端的声明",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/value.ModifyOption"
+                        "$ref": "#/definitions/value.AuthorizeClaimHeader"
                     }
                 },
-                "name": {
-                    "description": "路由名称",
+                "issuer": {
+                    "description": "签发者",
                     "type": "string"
                 },
-                "path": {
-                    "description": "匹配路径",
+                "jwks": {
+                    "description": "JWKS 地址，http(s) URL 或文件路径，设置后忽略 Key",
                     "type": "string"
                 },
-                "path_rewrite": {
-                    "description": "路径重写",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/value.PathRewrite"
-                        }
-                    ]
+                "jwks_cache_ttl": {
+                    "description": "JWKS 缓存时间，单位秒",
+                    "type": "integer",
+                    "minimum": 0
                 },
-                "path_type": {
-                    "description": "匹配路径类型",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/enum.RoutePathType"
-                        }
-                    ]
+                "key": {
+                    "description": "HS256 共享密钥或 PEM 格式公钥",
+                    "type": "string"
                 },
-                "status": {
-                    "description": "路由状态",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/enum.RouteStatus"
-                        }
-                    ]
+                "leeway": {
+                    "description": "时间校验误差，单位秒",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "require_exp": {
+                    "description": "是否要求令牌包含 exp 声明，默认要求",
+                    "type": "boolean"
+                },
+                "sources": {
+                    "description": "令牌来源，为空时使用 Authorization: Bearer",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/value.AuthorizeSource"
+                    }
                 }
             }
         },
-        "service.UpdateUserParam": {
+        "value.AuthorizeAttributeOIDC": {
             "type": "object",
             "required": [
-                "id"
+                "callback_path",
+                "claim_headers",
+                "client_id",
+                "cookie_key",
+                "issuer"
             ],
             "properties": {
-                "id": {
+                "algorithms": {
+                    "description": "ID Token 允许的签名算法，为空时使用 RS256 与 ES256",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "callback_path": {
+                    "description": "登录回调路径，需能匹配到使用此鉴权的路由",
                     "type": "string"
                 },
-                "name": {
+                "claim_headers": {
+                    "description": "转发到后端的声明",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/value.AuthorizeClaimHeader"
+                    }
+                },
+                "client_id": {
                     "type": "string"
                 },
-                "password": {
+                "client_secret": {
+                    "type": "string"
+                },
+                "cookie_key": {
+                    "description": "会话 Cookie 加密密钥，长度 16、24 或 32",
+                    "type": "string"
+                },
+                "cookie_name": {
+                    "type": "string"
+                },
+                "cookie_secure": {
+                    "description": "始终设置 Cookie 的 Secure 标记，TLS 由前置代理终止时开启",
+                    "type": "boolean"
+                },
+                "issuer": {
+                    "description": "提供方 issuer",
                     "type": "string"
                 },
+                "leeway": {
+                    "description": "时间校验误差，单位秒",
+                    "type": "integer",
+                    "minimum": 0
+                },
                 "scopes": {
+                    "description": "为空时使用 openid profile email",
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
+                },
+                "session_ttl": {
+                    "description": "会话有效期，单位秒，为 0 时使用 ID Token 的过期时间",
+                    "type": "integer",
+                    "minimum": 0
                 }
             }
         },
-        "value.AuthorizeAttribute": {
+        "value.AuthorizeAttributePolicy": {
             "type": "object",
+            "required": [
+                "authorize_ids"
+            ],
             "properties": {
-                "binary": {
-                    "$ref": "#/definitions/value.AuthorizeAttributeBinary"
+                "authorize_ids": {
+                    "description": "按顺序执行的鉴权配置ID",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "value.AuthorizeAttributeBinary": {
+        "value.AuthorizeClaimHeader": {
             "type": "object",
             "required": [
-                "sources"
+                "claim",
+                "header"
             ],
             "properties": {
-                "header": {
+                "claim": {
+                    "description": "声明名称",
                     "type": "string"
                 },
-                "key": {
+                "header": {
+                    "description": "请求头",
                     "type": "string"
-                },
-                "sources": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/value.AuthorizeSource"
-                    }
                 }
             }
         },
@@ -2855,6 +4287,186 @@ const docTemplate = `{
                 }
             }
         },
+        "value.ForwardEndpointActiveCheck": {
+            "type": "object",
+            "required": [
+                "interval",
+                "type"
+            ],
+            "properties": {
+                "expect_status": {
+                    "description": "HTTP 期望状态码，默认 2xx/3xx",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "healthy_threshold": {
+                    "description": "连续成功次数达到后恢复",
+                    "type": "integer"
+                },
+                "interval": {
+                    "description": "检查间隔（毫秒）",
+                    "type": "integer",
+                    "minimum": 100
+                },
+                "path": {
+                    "description": "HTTP 检查路径",
+                    "type": "string"
+                },
+                "timeout": {
+                    "description": "检查超时（毫秒），默认为检查间隔",
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "检查方式",
+                    "type": "string",
+                    "enum": [
+                        "tcp",
+                        "http"
+                    ]
+                },
+                "unhealthy_threshold": {
+                    "description": "连续失败次数达到后标记不健康",
+                    "type": "integer"
+                }
+            }
+        },
+        "value.ForwardEndpointBalance": {
+            "type": "object",
+            "required": [
+                "type"
+            ],
+            "properties": {
+                "name": {
+                    "description": "一致性哈希取值名称",
+                    "type": "string"
+                },
+                "source": {
+                    "description": "一致性哈希取值来源 header cookie query ip",
+                    "type": "string"
+                },
+                "sticky": {
+                    "description": "会话保持",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/value.ForwardEndpointSticky"
+                        }
+                    ]
+                },
+                "type": {
+                    "description": "均衡策略",
+                    "type": "string",
+                    "enum": [
+                        "random",
+                        "round_robin",
+                        "least_conn",
+                        "hash"
+                    ]
+                }
+            }
+        },
+        "value.ForwardEndpointHealthCheck": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "主动检查",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/value.ForwardEndpointActiveCheck"
+                        }
+                    ]
+                },
+                "passive": {
+                    "description": "被动检查",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/value.ForwardEndpointPassiveCheck"
+                        }
+                    ]
+                }
+            }
+        },
+        "value.ForwardEndpointPassiveCheck": {
+            "type": "object",
+            "required": [
+                "fail_timeout",
+                "max_fails"
+            ],
+            "properties": {
+                "fail_timeout": {
+                    "description": "摘除时长（毫秒）",
+                    "type": "integer"
+                },
+                "max_fails": {
+                    "description": "连续连接失败次数达到后摘除",
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "value.ForwardEndpointPool": {
+            "type": "object",
+            "properties": {
+                "idle_timeout": {
+                    "description": "空闲超时（毫秒），0 不超时",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "max_conns": {
+                    "description": "每个目标最大连接数，0 不限制",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "max_idle": {
+                    "description": "每个目标最大空闲连接数，0 不复用连接",
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "value.ForwardEndpointRetry": {
+            "type": "object",
+            "required": [
+                "max_attempts"
+            ],
+            "properties": {
+                "max_attempts": {
+                    "description": "最大尝试次数，包括首次请求",
+                    "type": "integer",
+                    "maximum": 10,
+                    "minimum": 1
+                },
+                "max_body_size": {
+                    "description": "缓存请求体的最大长度（字节），超过时不重试，默认 1MB",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "non_idempotent": {
+                    "description": "允许重试非幂等请求（POST/PATCH）",
+                    "type": "boolean"
+                },
+                "per_try_timeout": {
+                    "description": "单次尝试超时（毫秒），0 不限制",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "retry_on": {
+                    "description": "重试的错误类型 connect_error read_error",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "retry_status": {
+                    "description": "重试的响应状态码",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
         "value.ForwardEndpointStatic": {
             "type": "object",
             "required": [
@@ -2867,11 +4479,59 @@ const docTemplate = `{
                         "$ref": "#/definitions/value.ForwardEndpointTarget"
                     }
                 },
+                "balance": {
+                    "description": "负载均衡，默认加权随机",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/value.ForwardEndpointBalance"
+                        }
+                    ]
+                },
+                "health_check": {
+                    "description": "健康检查",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/value.ForwardEndpointHealthCheck"
+                        }
+                    ]
+                },
+                "pool": {
+                    "description": "连接池，默认启用",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/value.ForwardEndpointPool"
+                        }
+                    ]
+                },
+                "retry": {
+                    "description": "重试策略",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/value.ForwardEndpointRetry"
+                        }
+                    ]
+                },
                 "timeout": {
                     "type": "integer"
                 }
             }
         },
+        "value.ForwardEndpointSticky": {
+            "type": "object",
+            "required": [
+                "cookie"
+            ],
+            "properties": {
+                "cookie": {
+                    "description": "会话保持 cookie 名",
+                    "type": "string"
+                },
+                "max_age": {
+                    "description": "cookie 有效期（秒），0 为会话 cookie",
+                    "type": "integer"
+                }
+            }
+        },
         "value.ForwardEndpointTarget": {
             "type": "object",
             "required": [
@@ -2886,6 +4546,19 @@ const docTemplate = `{
                 "network": {
                     "type": "string"
                 },
+                "protocol": {
+                    "description": "后端协议 http1 h2 h2c，默认 http1，h2 使用 TLS 连接",
+                    "type": "string",
+                    "enum": [
+                        "http1",
+                        "h2",
+                        "h2c"
+                    ]
+                },
+                "tls_insecure_skip_verify": {
+                    "description": "h2 不验证后端证书",
+                    "type": "boolean"
+                },
                 "weight": {
                     "type": "integer"
                 }
@@ -2932,14 +4605,25 @@ const docTemplate = `{
                 },
                 "source": {
                     "description": "编辑源",
-                    "type": "string"
+                    "type": "string",
+                    "enum": [
+                        "header",
+                        "query",
+                        "cookie",
+                        "response_header"
+                    ]
                 },
                 "type": {
                     "description": "编辑类型",
-                    "type": "string"
+                    "type": "string",
+                    "enum": [
+                        "add",
+                        "set",
+                        "delete"
+                    ]
                 },
                 "value": {
-                    "description": "值",
+                    "description": "值，支持模板变量 {path.name} {header.name} {query.name} {cookie.name} {ip}",
                     "type": "string"
                 }
             }
@@ -2954,6 +4638,26 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "value.RouteConnect": {
+            "type": "object",
+            "required": [
+                "allow"
+            ],
+            "properties": {
+                "allow": {
+                    "description": "允许的目标，格式 host:port，host 支持 *.example.com、* 与 CIDR，省略端口匹配任意端口",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "timeout": {
+                    "description": "连接目标超时，单位毫秒",
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,193 @@
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"golang.org/x/crypto/acme"
+)
+
+var ErrNoChallenge = errors.New("http-01 challenge not offered")
+
+// 验证处理，发布与清理 HTTP-01 验证内容
+type Solver interface {
+	Present(token, keyAuth string)
+	CleanUp(token string)
+}
+
+type Config struct {
+	// ACME 服务目录地址
+	Directory string
+	// 账号邮箱
+	Email string
+	// 账号私钥
+	Key crypto.Signer
+	// 不校验 ACME 服务证书，用于 pebble 等本地测试服务
+	Insecure bool
+}
+
+type Client struct {
+	client *acme.Client
+	email  string
+	solver Solver
+}
+
+func NewClient(cfg *Config, solver Solver) *Client {
+	client := &acme.Client{Key: cfg.Key, DirectoryURL: cfg.Directory}
+	if cfg.Insecure {
+		client.HTTPClient = &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}}
+	}
+	return &Client{client: client, email: cfg.Email, solver: solver}
+}
+
+// 注册账号，账号已存在时忽略
+func (c *Client) Register(ctx context.Context) error {
+	acct := &acme.Account{}
+	if c.email != "" {
+		acct.Contact = []string{"mailto:" + c.email}
+	}
+	_, err := c.client.Register(ctx, acct, acme.AcceptTOS)
+	if err != nil && err != acme.ErrAccountAlreadyExists {
+		return err
+	}
+	return nil
+}
+
+// 签发证书，返回 PEM 编码的证书链与私钥
+func (c *Client) Obtain(ctx context.Context, domains []string) (certPEM, keyPEM []byte, err error) {
+	order, err := c.client.AuthorizeOrder(ctx, acme.DomainIDs(domains...))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, url := range order.AuthzURLs {
+		if err := c.authorize(ctx, url); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	order, err = c.client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domains[0]},
+		DNSNames: domains,
+	}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	der, _, err := c.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, b := range der {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: b})...)
+	}
+
+	keyPEM, err = EncodeKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certPEM, keyPEM, nil
+}
+
+func (c *Client) authorize(ctx context.Context, url string) error {
+	authz, err := c.client.GetAuthorization(ctx, url)
+	if err != nil {
+		return err
+	}
+
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var chal *acme.Challenge
+	for _, v := range authz.Challenges {
+		if v.Type == "http-01" {
+			chal = v
+			break
+		}
+	}
+
+	if chal == nil {
+		return fmt.Errorf("%w: %s", ErrNoChallenge, authz.Identifier.Value)
+	}
+
+	keyAuth, err := c.client.HTTP01ChallengeResponse(chal.Token)
+	if err != nil {
+		return err
+	}
+
+	c.solver.Present(chal.Token, keyAuth)
+	defer c.solver.CleanUp(chal.Token)
+
+	if _, err := c.client.Accept(ctx, chal); err != nil {
+		return err
+	}
+
+	if _, err := c.client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return err
+	}
+	return nil
+}
+
+// 读取账号私钥，不存在时创建
+func LoadOrCreateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("invalid account key")
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = EncodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func EncodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	b, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b}), nil
+}
@@ -0,0 +1,107 @@
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHTTPSolver(t *testing.T) {
+	s := NewHTTPSolver()
+	s.Present("token", "token.key")
+
+	w := httptest.NewRecorder()
+	if !s.ServeChallenge(w, httptest.NewRequest(http.MethodGet, "/.well-known/acme-challenge/token", nil)) {
+		t.Fatal("ServeChallenge() = false, want true")
+	}
+	if w.Body.String() != "token.key" {
+		t.Errorf("ServeChallenge() body = %q, want token.key", w.Body.String())
+	}
+
+	s.CleanUp("token")
+	if s.ServeChallenge(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/.well-known/acme-challenge/token", nil)) {
+		t.Error("ServeChallenge() = true after CleanUp")
+	}
+
+	if s.ServeChallenge(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/index.html", nil)) {
+		t.Error("ServeChallenge() = true for normal request")
+	}
+}
+
+func TestLoadOrCreateKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "account.pem")
+
+	key, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !loaded.(*ecdsa.PrivateKey).Equal(key) {
+		t.Error("LoadOrCreateKey() loaded different key")
+	}
+}
+
+// 使用 pebble 测试签发，需设置 PEBBLE_DIRECTORY，如
+// PEBBLE_DIRECTORY=https://localhost:14000/dir PEBBLE_HTTP_ADDR=:5002 PEBBLE_DOMAIN=localhost
+func TestObtainPebble(t *testing.T) {
+	directory := os.Getenv("PEBBLE_DIRECTORY")
+	if directory == "" {
+		t.Skip("PEBBLE_DIRECTORY not set")
+	}
+
+	addr := os.Getenv("PEBBLE_HTTP_ADDR")
+	if addr == "" {
+		addr = ":5002"
+	}
+
+	domain := os.Getenv("PEBBLE_DOMAIN")
+	if domain == "" {
+		domain = "localhost"
+	}
+
+	solver := NewHTTPSolver()
+	svr := &http.Server{Addr: addr, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !solver.ServeChallenge(w, r) {
+			http.NotFound(w, r)
+		}
+	})}
+	go svr.ListenAndServe()
+	defer svr.Close()
+
+	key, err := LoadOrCreateKey(filepath.Join(t.TempDir(), "account.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	client := NewClient(&Config{Directory: directory, Key: key, Insecure: true}, solver)
+	if err := client.Register(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM, keyPEM, err := client.Obtain(ctx, []string{domain})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cert.Certificate) == 0 {
+		t.Error("Obtain() returned empty certificate chain")
+	}
+}
@@ -0,0 +1,51 @@
+package acme
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const challengePath = "/.well-known/acme-challenge/"
+
+// HTTP-01 验证，由 80 端口的服务响应验证请求
+type HTTPSolver struct {
+	mtx    sync.RWMutex
+	tokens map[string]string
+}
+
+func NewHTTPSolver() *HTTPSolver {
+	return &HTTPSolver{tokens: map[string]string{}}
+}
+
+func (s *HTTPSolver) Present(token, keyAuth string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.tokens[token] = keyAuth
+}
+
+func (s *HTTPSolver) CleanUp(token string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.tokens, token)
+}
+
+// 响应验证请求，非验证请求返回 false
+func (s *HTTPSolver) ServeChallenge(w http.ResponseWriter, req *http.Request) bool {
+	if !strings.HasPrefix(req.URL.Path, challengePath) {
+		return false
+	}
+
+	s.mtx.RLock()
+	keyAuth, ok := s.tokens[strings.TrimPrefix(req.URL.Path, challengePath)]
+	s.mtx.RUnlock()
+
+	if !ok {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, keyAuth)
+	return true
+}
@@ -45,6 +45,10 @@ func (a *binaryAuth) HandleAuthorizeCheck(w http.ResponseWriter, req *http.Reque
 	return false
 }
 
+func (a *binaryAuth) AuthorizeHeaders() []string {
+	return []string{a.header}
+}
+
 func (a *binaryAuth) validateToken(tokStr string) (*token.BinaryToken, error) {
 	tok := &token.BinaryToken{}
 
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"dxkite.cn/meownest/pkg/passwd"
+)
+
+// 凭证，Hash 由 passwd.NewHash 生成
+type Credential struct {
+	// 认证通过后转发到后端的身份
+	Principal string
+	Hash      string
+}
+
+// 按用户名或 API Key ID 查找凭证
+type CredentialTable map[string]*Credential
+
+// 名称不存在时用于校验的哈希，使耗时与名称存在时一致
+const dummyCredentialHash = "2K-i4q9mdW4jk4isyBQcnbZnZVLSlDVDE3XxZXTUqPY.S9GIExUXdFv27bn0_WktUA"
+
+func (t CredentialTable) verify(name, secret string) (*Credential, bool) {
+	c, ok := t[name]
+	if !ok {
+		passwd.VerifyHash(secret, dummyCredentialHash)
+		return nil, false
+	}
+	if ok, err := passwd.VerifyHash(secret, c.Hash); err != nil || !ok {
+		return nil, false
+	}
+	return c, true
+}
+
+// HTTP Basic 认证，sources 为空时读取 Authorization 请求头
+func NewBasicAuth(realm, header string, sources []*AuthorizeSource, table CredentialTable) AuthorizeHandler {
+	if len(sources) == 0 {
+		sources = []*AuthorizeSource{{Source: "header", Name: "Authorization"}}
+	}
+	return &basicAuth{realm: realm, header: header, source: sources, table: table}
+}
+
+type basicAuth struct {
+	realm  string
+	header string
+	source []*AuthorizeSource
+	table  CredentialTable
+}
+
+func (a *basicAuth) HandleAuthorizeCheck(w http.ResponseWriter, req *http.Request) bool {
+	if a.header != "" {
+		req.Header.Del(a.header)
+	}
+
+	for _, v := range a.source {
+		name, password, ok := parseBasicAuth(VarFrom(req, v.Source, v.Name))
+		if !ok {
+			continue
+		}
+		if c, ok := a.table.verify(name, password); ok {
+			if a.header != "" {
+				req.Header.Set(a.header, c.Principal)
+			}
+			return true
+		}
+		break
+	}
+
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", a.realm))
+	http.Error(w, "invalid credential", http.StatusUnauthorized)
+	return false
+}
+
+func (a *basicAuth) AuthorizeHeaders() []string {
+	return []string{a.header}
+}
+
+func parseBasicAuth(v string) (name, password string, ok bool) {
+	if len(v) < 6 || !strings.EqualFold(v[:6], "basic ") {
+		return "", "", false
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v[6:]))
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(b), ":")
+}
+
+// API Key 认证，Key 格式为 <ID>.<密钥>，sources 为空时读取 X-API-Key 请求头
+func NewAPIKeyAuth(header string, sources []*AuthorizeSource, table CredentialTable) AuthorizeHandler {
+	if len(sources) == 0 {
+		sources = []*AuthorizeSource{{Source: "header", Name: "X-API-Key"}}
+	}
+	return &apiKeyAuth{header: header, source: sources, table: table}
+}
+
+type apiKeyAuth struct {
+	header string
+	source []*AuthorizeSource
+	table  CredentialTable
+}
+
+func (a *apiKeyAuth) HandleAuthorizeCheck(w http.ResponseWriter, req *http.Request) bool {
+	if a.header != "" {
+		req.Header.Del(a.header)
+	}
+
+	for _, v := range a.source {
+		key := VarFrom(req, v.Source, v.Name)
+		if key == "" {
+			continue
+		}
+		id, secret, _ := strings.Cut(key, ".")
+		if c, ok := a.table.verify(id, secret); ok {
+			if a.header != "" {
+				req.Header.Set(a.header, c.Principal)
+			}
+			return true
+		}
+		break
+	}
+
+	http.Error(w, "invalid api key", http.StatusUnauthorized)
+	return false
+}
+
+func (a *apiKeyAuth) AuthorizeHeaders() []string {
+	return []string{a.header}
+}
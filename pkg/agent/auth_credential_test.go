@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dxkite.cn/meownest/pkg/passwd"
+)
+
+func testCredentialTable(t *testing.T, secrets map[string]string) CredentialTable {
+	t.Helper()
+	table := CredentialTable{}
+	for name, secret := range secrets {
+		hash, err := passwd.NewHash(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		table[name] = &Credential{Principal: "principal-" + name, Hash: hash}
+	}
+	return table
+}
+
+func TestCredentialTableUnknown(t *testing.T) {
+	// 名称不存在时同样完成一次哈希校验
+	if ok, err := passwd.VerifyHash("secret", dummyCredentialHash); err != nil || ok {
+		t.Errorf("VerifyHash(dummy) = %v, %v, want false, nil", ok, err)
+	}
+
+	table := testCredentialTable(t, map[string]string{"alice": "secret"})
+	if _, ok := table.verify("bob", "secret"); ok {
+		t.Error("verify(unknown) = true, want false")
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	table := testCredentialTable(t, map[string]string{"alice": "secret"})
+	auth := NewBasicAuth("internal", "X-User", nil, table)
+	proxy := NewBasicAuth("internal", "X-User", []*AuthorizeSource{{Source: "header", Name: "Proxy-Authorization"}}, table)
+
+	tests := []struct {
+		name     string
+		auth     AuthorizeHandler
+		header   string
+		user     string
+		password string
+		want     bool
+	}{
+		{"valid", auth, "Authorization", "alice", "secret", true},
+		{"wrong password", auth, "Authorization", "alice", "other", false},
+		{"unknown user", auth, "Authorization", "bob", "secret", false},
+		{"missing", auth, "", "", "", false},
+		{"proxy source", proxy, "Proxy-Authorization", "alice", "secret", true},
+		{"proxy source ignores authorization", proxy, "Authorization", "alice", "secret", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User", "spoofed")
+		if tt.header != "" {
+			r := &http.Request{Header: http.Header{}}
+			r.SetBasicAuth(tt.user, tt.password)
+			req.Header.Set(tt.header, r.Header.Get("Authorization"))
+		}
+		w := httptest.NewRecorder()
+		if got := tt.auth.HandleAuthorizeCheck(w, req); got != tt.want {
+			t.Errorf("%s: HandleAuthorizeCheck() = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		if !tt.want {
+			if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != `Basic realm="internal"` {
+				t.Errorf("%s: status = %d WWW-Authenticate = %q", tt.name, w.Code, w.Header().Get("WWW-Authenticate"))
+			}
+			continue
+		}
+		if got := req.Header.Get("X-User"); got != "principal-alice" {
+			t.Errorf("%s: X-User = %q, want principal-alice", tt.name, got)
+		}
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	table := testCredentialTable(t, map[string]string{"credential_a": "secret"})
+	auth := NewAPIKeyAuth("X-Client", []*AuthorizeSource{
+		{Source: "header", Name: "X-API-Key"},
+		{Source: "query", Name: "api_key"},
+	}, table)
+
+	tests := []struct {
+		name string
+		url  string
+		key  string
+		want bool
+	}{
+		{"header", "/", "credential_a.secret", true},
+		{"query", "/?api_key=credential_a.secret", "", true},
+		{"wrong secret", "/", "credential_a.other", false},
+		{"unknown id", "/", "credential_b.secret", false},
+		{"no separator", "/", "credential_a", false},
+		{"missing", "/", "", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		req.Header.Set("X-Client", "spoofed")
+		if tt.key != "" {
+			req.Header.Set("X-API-Key", tt.key)
+		}
+		w := httptest.NewRecorder()
+		if got := auth.HandleAuthorizeCheck(w, req); got != tt.want {
+			t.Errorf("%s: HandleAuthorizeCheck() = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		want := "principal-credential_a"
+		if !tt.want {
+			want = ""
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("%s: status = %d, want 401", tt.name, w.Code)
+			}
+		}
+		if got := req.Header.Get("X-Client"); got != want {
+			t.Errorf("%s: X-Client = %q, want %q", tt.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 鉴权结果最大缓存数
+const forwardAuthMaxCache = 4096
+
+// 拒绝时返回给客户端的鉴权响应头
+var forwardAuthDenyHeaders = []string{"WWW-Authenticate", "Content-Type", "Location", "Set-Cookie"}
+
+type ForwardAuthConfig struct {
+	// 鉴权服务
+	Upstream *Upstream
+	// 子请求方法，默认 GET
+	Method string
+	// 子请求路径，默认 /
+	Path string
+	// 转发到鉴权服务的请求头
+	Headers []string
+	// 转发到鉴权服务的 Cookie
+	Cookies []string
+	// 鉴权通过后复制到后端请求的响应头
+	ResponseHeaders []string
+	// 鉴权结果缓存时间，为 0 或未配置请求头与 Cookie 时不缓存
+	//
+	// 缓存键包括请求方法、域名、地址、客户端 IP 以及转发的请求头与 Cookie
+	CacheTTL time.Duration
+}
+
+// 外部鉴权，行为与 nginx auth_request 一致
+//
+// 鉴权服务返回 2xx 时放行，401/403 时将鉴权响应返回给客户端，其他状态返回 500
+func NewForwardAuth(config *ForwardAuthConfig) *ForwardAuth {
+	a := &ForwardAuth{config: config, handler: NewStaticForwardHandler(config.Upstream)}
+	// 没有凭证时所有请求共用同一个缓存键，不缓存
+	if config.CacheTTL > 0 && len(config.Headers)+len(config.Cookies) > 0 {
+		a.cache = map[string]*forwardAuthResult{}
+	}
+	return a
+}
+
+type ForwardAuth struct {
+	config  *ForwardAuthConfig
+	handler *StaticForwardHandler
+
+	mtx   sync.Mutex
+	cache map[string]*forwardAuthResult
+}
+
+type forwardAuthResult struct {
+	expireAt time.Time
+	status   int
+	header   http.Header
+	body     []byte
+}
+
+// 启动鉴权服务的健康检查
+func (a *ForwardAuth) Start() {
+	a.config.Upstream.Start()
+}
+
+// 停止健康检查并关闭连接
+func (a *ForwardAuth) Stop() {
+	a.config.Upstream.Stop()
+}
+
+func (a *ForwardAuth) HandleAuthorizeCheck(w http.ResponseWriter, req *http.Request) bool {
+	for _, v := range a.config.ResponseHeaders {
+		req.Header.Del(v)
+	}
+
+	key := a.cacheKey(req)
+	rst := a.loadCache(key)
+	if rst == nil {
+		rst = a.request(req)
+		if rst == nil {
+			http.Error(w, "auth request error", http.StatusInternalServerError)
+			return false
+		}
+		a.storeCache(key, rst)
+	}
+
+	if rst.status >= 200 && rst.status < 300 {
+		for name, values := range rst.header {
+			req.Header[name] = append([]string(nil), values...)
+		}
+		return true
+	}
+
+	for name, values := range rst.header {
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	w.WriteHeader(rst.status)
+	w.Write(rst.body)
+	return false
+}
+
+func (a *ForwardAuth) AuthorizeHeaders() []string {
+	return a.config.ResponseHeaders
+}
+
+// 发送鉴权子请求，鉴权服务异常时返回 nil
+func (a *ForwardAuth) request(req *http.Request) *forwardAuthResult {
+	method := a.config.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	path := a.config.Path
+	if path == "" {
+		path = "/"
+	}
+
+	sub, err := http.NewRequestWithContext(req.Context(), method, path, nil)
+	if err != nil {
+		printLog("auth request %s error: %s\n", path, err.Error())
+		return nil
+	}
+
+	sub.Host = req.Host
+	sub.RemoteAddr = req.RemoteAddr
+	for _, name := range a.config.Headers {
+		if values := req.Header.Values(name); len(values) > 0 {
+			sub.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	for _, name := range a.config.Cookies {
+		if c, err := req.Cookie(name); err == nil {
+			sub.AddCookie(c)
+		}
+	}
+
+	proto := "http"
+	if req.TLS != nil {
+		proto = "https"
+	}
+	sub.Header.Set("X-Forwarded-Method", req.Method)
+	sub.Header.Set("X-Forwarded-Proto", proto)
+	sub.Header.Set("X-Forwarded-Host", req.Host)
+	sub.Header.Set("X-Forwarded-Uri", req.URL.RequestURI())
+	sub.Header.Set("X-Forwarded-For", RemoteIP(req))
+
+	rw := newAuthResponseWriter()
+	a.handler.HandleRequest(rw, sub)
+
+	rst := &forwardAuthResult{status: rw.status, header: http.Header{}}
+	switch {
+	case rw.status >= 200 && rw.status < 300:
+		for _, name := range a.config.ResponseHeaders {
+			if values := rw.header.Values(name); len(values) > 0 {
+				rst.header[http.CanonicalHeaderKey(name)] = values
+			}
+		}
+	case rw.status == http.StatusUnauthorized || rw.status == http.StatusForbidden:
+		for _, name := range forwardAuthDenyHeaders {
+			if values := rw.header.Values(name); len(values) > 0 {
+				rst.header[http.CanonicalHeaderKey(name)] = values
+			}
+		}
+		rst.body = rw.body.Bytes()
+	default:
+		printLog("auth request %s unexpected status %d\n", path, rw.status)
+		return nil
+	}
+	return rst
+}
+
+// 以转发到鉴权服务的请求信息作为缓存键，同一凭证访问不同地址分别鉴权
+func (a *ForwardAuth) cacheKey(req *http.Request) string {
+	if a.cache == nil {
+		return ""
+	}
+	h := sha256.New()
+	for _, v := range []string{req.Method, req.Host, req.URL.RequestURI(), RemoteIP(req)} {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	for _, name := range a.config.Headers {
+		h.Write([]byte(strings.Join(req.Header.Values(name), "\n")))
+		h.Write([]byte{0})
+	}
+	for _, name := range a.config.Cookies {
+		if c, err := req.Cookie(name); err == nil {
+			h.Write([]byte(c.Value))
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (a *ForwardAuth) loadCache(key string) *forwardAuthResult {
+	if key == "" {
+		return nil
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	rst, ok := a.cache[key]
+	if !ok || time.Now().After(rst.expireAt) {
+		return nil
+	}
+	return rst
+}
+
+func (a *ForwardAuth) storeCache(key string, rst *forwardAuthResult) {
+	if key == "" {
+		return
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	now := time.Now()
+	if len(a.cache) >= forwardAuthMaxCache {
+		for k, v := range a.cache {
+			if now.After(v.expireAt) {
+				delete(a.cache, k)
+			}
+		}
+		// 仍然过多时清空
+		if len(a.cache) >= forwardAuthMaxCache {
+			a.cache = map[string]*forwardAuthResult{}
+		}
+	}
+	rst.expireAt = now.Add(a.config.CacheTTL)
+	a.cache[key] = rst
+}
@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForwardAuth(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/verify" || r.Header.Get("X-Forwarded-Uri") != "/api?a=1" || r.Header.Get("X-Forwarded-Method") != http.MethodPost {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// 未选择的请求头不转发
+		if r.Header.Get("X-Other") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if c, err := r.Cookie("session"); err == nil && c.Value == "s1" {
+			w.Header().Set("X-User", "bob")
+			return
+		}
+		switch r.Header.Get("Authorization") {
+		case "token-alice":
+			w.Header().Set("X-User", "alice")
+			w.Header().Set("X-Internal", "secret")
+		case "token-forbidden":
+			http.Error(w, "forbidden", http.StatusForbidden)
+		case "token-error":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			http.Error(w, "login required", http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	newAuth := func(ttl time.Duration) *ForwardAuth {
+		target := &EndpointTarget{Network: "tcp", Address: server.Listener.Addr().String()}
+		return NewForwardAuth(&ForwardAuthConfig{
+			Upstream:        NewUpstream([]*EndpointTarget{target}, time.Second),
+			Path:            "/verify",
+			Headers:         []string{"Authorization"},
+			Cookies:         []string{"session"},
+			ResponseHeaders: []string{"X-User"},
+			CacheTTL:        ttl,
+		})
+	}
+
+	check := func(auth *ForwardAuth, token, cookie string) (*httptest.ResponseRecorder, *http.Request, bool) {
+		req := httptest.NewRequest(http.MethodPost, "/api?a=1", nil)
+		req.Header.Set("X-User", "spoofed")
+		req.Header.Set("X-Other", "1")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "session", Value: cookie})
+		}
+		w := httptest.NewRecorder()
+		ok := auth.HandleAuthorizeCheck(w, req)
+		return w, req, ok
+	}
+
+	auth := newAuth(0)
+	tests := []struct {
+		name   string
+		token  string
+		cookie string
+		status int
+		user   string
+	}{
+		{"allow", "token-alice", "", 0, "alice"},
+		{"cookie", "", "s1", 0, "bob"},
+		{"unauthorized", "", "", http.StatusUnauthorized, ""},
+		{"forbidden", "token-forbidden", "", http.StatusForbidden, ""},
+		{"auth error", "token-error", "", http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		w, req, ok := check(auth, tt.token, tt.cookie)
+		if ok != (tt.status == 0) {
+			t.Errorf("%s: HandleAuthorizeCheck() = %v (%d %s)", tt.name, ok, w.Code, w.Body.String())
+			continue
+		}
+		if ok {
+			if got := req.Header.Get("X-User"); got != tt.user {
+				t.Errorf("%s: X-User = %q, want %q", tt.name, got, tt.user)
+			}
+			if got := req.Header.Get("X-Internal"); got != "" {
+				t.Errorf("%s: X-Internal = %q, want not copied", tt.name, got)
+			}
+			continue
+		}
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if req.Header.Get("X-User") != "" {
+			t.Errorf("%s: X-User not removed", tt.name)
+		}
+	}
+
+	w, _, _ := check(auth, "", "")
+	if got := w.Header().Get("WWW-Authenticate"); got != `Bearer realm="api"` {
+		t.Errorf("WWW-Authenticate = %q", got)
+	}
+	if !strings.Contains(w.Body.String(), "login required") {
+		t.Errorf("body = %q, want auth response body", w.Body.String())
+	}
+
+	// 缓存按凭证区分，鉴权服务异常不缓存
+	cached := newAuth(time.Minute)
+	atomic.StoreInt32(&requests, 0)
+	for i := 0; i < 3; i++ {
+		if _, req, ok := check(cached, "token-alice", ""); !ok || req.Header.Get("X-User") != "alice" {
+			t.Fatalf("cached allow rejected")
+		}
+		if _, _, ok := check(cached, "token-forbidden", ""); ok {
+			t.Fatalf("cached deny accepted")
+		}
+		check(cached, "token-error", "")
+	}
+	if n := atomic.LoadInt32(&requests); n != 5 {
+		t.Errorf("requests = %d, want 5", n)
+	}
+
+	cached.mtx.Lock()
+	for _, v := range cached.cache {
+		v.expireAt = time.Now().Add(-time.Second)
+	}
+	cached.mtx.Unlock()
+	check(cached, "token-alice", "")
+	if n := atomic.LoadInt32(&requests); n != 6 {
+		t.Errorf("requests = %d, want 6 after expire", n)
+	}
+	cached.Stop()
+	auth.Stop()
+}
+
+func TestForwardAuthCacheKey(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("X-Forwarded-Uri") != "/public" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	newAuth := func(headers []string) *ForwardAuth {
+		target := &EndpointTarget{Network: "tcp", Address: server.Listener.Addr().String()}
+		auth := NewForwardAuth(&ForwardAuthConfig{
+			Upstream: NewUpstream([]*EndpointTarget{target}, time.Second),
+			Headers:  headers,
+			CacheTTL: time.Minute,
+		})
+		t.Cleanup(auth.Stop)
+		return auth
+	}
+	check := func(auth *ForwardAuth, path, addr string) bool {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = addr
+		req.Header.Set("Authorization", "token")
+		return auth.HandleAuthorizeCheck(httptest.NewRecorder(), req)
+	}
+
+	// 同一凭证访问不同地址分别鉴权
+	auth := newAuth([]string{"Authorization"})
+	if !check(auth, "/public", "10.0.0.1:1000") {
+		t.Fatal("/public rejected")
+	}
+	if check(auth, "/admin", "10.0.0.1:1000") {
+		t.Fatal("/public result reused for /admin")
+	}
+	check(auth, "/public", "10.0.0.1:2000")
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("requests = %d, want 2 cached per path", n)
+	}
+	check(auth, "/public", "10.0.0.2:1000")
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("requests = %d, want 3 cached per client", n)
+	}
+
+	// 未配置凭证时不缓存
+	atomic.StoreInt32(&requests, 0)
+	auth = newAuth(nil)
+	check(auth, "/public", "10.0.0.1:1000")
+	check(auth, "/public", "10.0.0.1:1000")
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("requests = %d, want 2 without credential", n)
+	}
+}
@@ -0,0 +1,244 @@
+package agent
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJWKSCacheTTL = 10 * time.Minute
+	// 未找到 kid 时重新加载的最小间隔，避免伪造 kid 频繁请求
+	jwksRefreshInterval = 10 * time.Second
+	jwksFetchTimeout    = 10 * time.Second
+	jwksMaxSize         = 1 << 20
+)
+
+// 从文件或 URL 加载的 JWK 集合，缓存到过期后重新加载
+//
+// 加载失败时继续使用已缓存的密钥；加载在锁外进行，同一时间只有一个加载，其余请求等待结果
+type JWKS struct {
+	location string
+	ttl      time.Duration
+	client   *http.Client
+
+	mtx       sync.RWMutex
+	keys      []*jwk
+	fetchedAt time.Time
+	// 最近一次加载，包括失败
+	triedAt time.Time
+	// 正在进行的加载，完成后关闭
+	loading chan struct{}
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// 对称密钥
+	K string `json:"k"`
+
+	key interface{}
+}
+
+// location 为 http(s) URL 或文件路径，ttl 为 0 时使用默认缓存时间
+func NewJWKS(location string, ttl time.Duration) *JWKS {
+	if ttl <= 0 {
+		ttl = defaultJWKSCacheTTL
+	}
+	return &JWKS{location: location, ttl: ttl, client: &http.Client{Timeout: jwksFetchTimeout}}
+}
+
+func (s *JWKS) JWTKey(kid, alg string) (interface{}, error) {
+	key, expired := s.lookup(kid, alg)
+	if key != nil && !expired {
+		return key, nil
+	}
+
+	// 缓存过期，或密钥轮换后缓存中没有新的 kid
+	s.refresh()
+	if key, _ = s.lookup(kid, alg); key != nil {
+		return key, nil
+	}
+	return nil, ErrJWTKeyNotFound
+}
+
+func (s *JWKS) lookup(kid, alg string) (interface{}, bool) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.find(kid, alg), time.Since(s.fetchedAt) > s.ttl
+}
+
+// 重新加载密钥，已有加载时等待其完成，距上次加载不足刷新间隔时跳过
+func (s *JWKS) refresh() {
+	s.mtx.Lock()
+	if loading := s.loading; loading != nil {
+		s.mtx.Unlock()
+		<-loading
+		return
+	}
+	now := time.Now()
+	if now.Sub(s.triedAt) <= jwksRefreshInterval {
+		s.mtx.Unlock()
+		return
+	}
+	s.triedAt = now
+	loading := make(chan struct{})
+	s.loading = loading
+	s.mtx.Unlock()
+
+	keys, err := s.load()
+
+	s.mtx.Lock()
+	if err != nil {
+		printLog("load jwks %s error: %s\n", s.location, err.Error())
+	} else {
+		s.keys = keys
+		s.fetchedAt = now
+	}
+	s.loading = nil
+	s.mtx.Unlock()
+	close(loading)
+}
+
+func (s *JWKS) find(kid, alg string) interface{} {
+	for _, v := range s.keys {
+		if kid != "" && v.Kid != kid {
+			continue
+		}
+		if v.Alg != "" && v.Alg != alg {
+			continue
+		}
+		if v.Use != "" && v.Use != "sig" {
+			continue
+		}
+		if jwkMatchAlg(v.key, alg) {
+			return v.key
+		}
+	}
+	return nil
+}
+
+func jwkMatchAlg(key interface{}, alg string) bool {
+	switch alg {
+	case JWTAlgHS256:
+		_, ok := key.([]byte)
+		return ok
+	case JWTAlgRS256:
+		_, ok := key.(*rsa.PublicKey)
+		return ok
+	case JWTAlgES256:
+		k, ok := key.(*ecdsa.PublicKey)
+		return ok && k.Curve == elliptic.P256()
+	}
+	return false
+}
+
+func (s *JWKS) load() ([]*jwk, error) {
+	b, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	set := struct {
+		Keys []*jwk `json:"keys"`
+	}{}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+
+	keys := []*jwk{}
+	for _, v := range set.Keys {
+		key, err := v.parse()
+		if err != nil {
+			// 跳过不支持的密钥
+			printLog("skip jwk %s: %s\n", v.Kid, err.Error())
+			continue
+		}
+		v.key = key
+		keys = append(keys, v)
+	}
+	return keys, nil
+}
+
+func (s *JWKS) read() ([]byte, error) {
+	if !strings.HasPrefix(s.location, "http://") && !strings.HasPrefix(s.location, "https://") {
+		return os.ReadFile(strings.TrimPrefix(s.location, "file://"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, jwksMaxSize))
+}
+
+func (k *jwk) parse() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() > 1<<31-1 || exp.Int64() < 3 {
+			return nil, fmt.Errorf("invalid rsa exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("invalid ec point")
+		}
+		return pub, nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
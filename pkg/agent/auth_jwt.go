@@ -0,0 +1,340 @@
+package agent
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgRS256 = "RS256"
+	JWTAlgES256 = "ES256"
+)
+
+var (
+	ErrJWTMalformed    = errors.New("malformed token")
+	ErrJWTAlgorithm    = errors.New("unsupported token algorithm")
+	ErrJWTSignature    = errors.New("invalid token signature")
+	ErrJWTExpired      = errors.New("token expired")
+	ErrJWTMissingExp   = errors.New("missing token expiration")
+	ErrJWTNotBefore    = errors.New("token not valid yet")
+	ErrJWTIssuer       = errors.New("invalid token issuer")
+	ErrJWTAudience     = errors.New("invalid token audience")
+	ErrJWTKeyNotFound  = errors.New("token key not found")
+	ErrJWTTokenMissing = errors.New("missing token")
+)
+
+// JWT 验证密钥
+type JWTKeySource interface {
+	// 按 kid 与算法查找密钥，HS256 返回 []byte，RS256 与 ES256 返回公钥
+	JWTKey(kid, alg string) (interface{}, error)
+}
+
+// 固定密钥，只用于与密钥类型一致的算法
+type jwtStaticKey struct {
+	key interface{}
+}
+
+// PEM 格式的 RSA 或 P-256 公钥用于 RS256 与 ES256，其他作为 HS256 共享密钥
+func NewJWTStaticKey(key string) (JWTKeySource, error) {
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return &jwtStaticKey{key: []byte(key)}, nil
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if !jwkMatchAlg(pub, JWTAlgRS256) && !jwkMatchAlg(pub, JWTAlgES256) {
+		return nil, errors.New("unsupported public key type")
+	}
+	return &jwtStaticKey{key: pub}, nil
+}
+
+func (k *jwtStaticKey) JWTKey(kid, alg string) (interface{}, error) {
+	// 避免使用公钥作为 HS256 密钥验证
+	if !jwkMatchAlg(k.key, alg) {
+		return nil, ErrJWTKeyNotFound
+	}
+	return k.key, nil
+}
+
+// 声明转发到后端请求头
+type JWTClaimHeader struct {
+	Claim  string
+	Header string
+}
+
+type JWTAuthConfig struct {
+	// 允许的算法，为空时只允许 HS256
+	Algorithms []string
+	Keys       JWTKeySource
+	Issuer     string
+	// 任意一个匹配即可
+	Audience []string
+	// 时间校验允许的误差
+	Leeway time.Duration
+	// 要求令牌包含 exp 声明
+	RequireExp bool
+	// 令牌来源，为空时读取 Authorization: Bearer
+	Sources      []*AuthorizeSource
+	ClaimHeaders []*JWTClaimHeader
+}
+
+func NewJWTAuth(config *JWTAuthConfig) AuthorizeHandler {
+	return &jwtAuth{config: config}
+}
+
+type jwtAuth struct {
+	config *JWTAuthConfig
+}
+
+func (a *jwtAuth) HandleAuthorizeCheck(w http.ResponseWriter, req *http.Request) bool {
+	// 防止客户端伪造声明请求头
+	for _, v := range a.config.ClaimHeaders {
+		req.Header.Del(v.Header)
+	}
+
+	tok := a.token(req)
+	if tok == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		http.Error(w, ErrJWTTokenMissing.Error(), http.StatusUnauthorized)
+		return false
+	}
+
+	claims, err := a.verify(tok, time.Now())
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return false
+	}
+
+	for _, v := range a.config.ClaimHeaders {
+		if value, ok := claims[v.Claim]; ok {
+			req.Header.Set(v.Header, jwtClaimString(value))
+		}
+	}
+	return true
+}
+
+func (a *jwtAuth) AuthorizeHeaders() []string {
+	return claimHeaders(a.config.ClaimHeaders)
+}
+
+func claimHeaders(items []*JWTClaimHeader) []string {
+	headers := []string{}
+	for _, v := range items {
+		headers = append(headers, v.Header)
+	}
+	return headers
+}
+
+func (a *jwtAuth) token(req *http.Request) string {
+	if len(a.config.Sources) == 0 {
+		return bearerToken(req.Header.Get("Authorization"))
+	}
+	for _, v := range a.config.Sources {
+		tok := VarFrom(req, v.Source, v.Name)
+		if v.Source == "header" && strings.EqualFold(v.Name, "Authorization") {
+			tok = bearerToken(tok)
+		}
+		if tok != "" {
+			return tok
+		}
+	}
+	return ""
+}
+
+func bearerToken(v string) string {
+	if len(v) > 7 && strings.EqualFold(v[:7], "bearer ") {
+		return strings.TrimSpace(v[7:])
+	}
+	return ""
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// 校验签名与声明，返回令牌的声明
+func (a *jwtAuth) verify(tok string, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(tok, ".")
+	if len(parts) != 3 {
+		return nil, ErrJWTMalformed
+	}
+
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	algorithms := a.config.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{JWTAlgHS256}
+	}
+	if !InStringSlice(header.Alg, algorithms) {
+		return nil, ErrJWTAlgorithm
+	}
+
+	if a.config.Keys == nil {
+		return nil, ErrJWTKeyNotFound
+	}
+	key, err := a.config.Keys.JWTKey(header.Kid, header.Alg)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJWTMalformed
+	}
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := a.validateClaims(claims, now); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return ErrJWTMalformed
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrJWTMalformed
+	}
+	return nil
+}
+
+func verifyJWTSignature(alg string, key interface{}, signed string, sig []byte) error {
+	hash := sha256.Sum256([]byte(signed))
+	switch alg {
+	case JWTAlgHS256:
+		secret, ok := key.([]byte)
+		if !ok || len(secret) == 0 {
+			return ErrJWTKeyNotFound
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		if subtle.ConstantTimeCompare(mac.Sum(nil), sig) != 1 {
+			return ErrJWTSignature
+		}
+		return nil
+	case JWTAlgRS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrJWTKeyNotFound
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig); err != nil {
+			return ErrJWTSignature
+		}
+		return nil
+	case JWTAlgES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrJWTKeyNotFound
+		}
+		// 签名为 r 与 s 拼接
+		if len(sig) != 64 {
+			return ErrJWTSignature
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, hash[:], r, s) {
+			return ErrJWTSignature
+		}
+		return nil
+	}
+	return ErrJWTAlgorithm
+}
+
+func (a *jwtAuth) validateClaims(claims map[string]interface{}, now time.Time) error {
+	leeway := a.config.Leeway
+
+	if exp, ok := claims["exp"]; ok {
+		v, ok := exp.(float64)
+		if !ok {
+			return ErrJWTMalformed
+		}
+		if now.After(time.Unix(int64(v), 0).Add(leeway)) {
+			return ErrJWTExpired
+		}
+	} else if a.config.RequireExp {
+		return ErrJWTMissingExp
+	}
+
+	if nbf, ok := claims["nbf"]; ok {
+		v, ok := nbf.(float64)
+		if !ok {
+			return ErrJWTMalformed
+		}
+		if now.Add(leeway).Before(time.Unix(int64(v), 0)) {
+			return ErrJWTNotBefore
+		}
+	}
+
+	if a.config.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != a.config.Issuer {
+			return ErrJWTIssuer
+		}
+	}
+
+	if len(a.config.Audience) > 0 {
+		matched := false
+		switch aud := claims["aud"].(type) {
+		case string:
+			matched = InStringSlice(aud, a.config.Audience)
+		case []interface{}:
+			for _, v := range aud {
+				if s, ok := v.(string); ok && InStringSlice(s, a.config.Audience) {
+					matched = true
+					break
+				}
+			}
+		}
+		if !matched {
+			return ErrJWTAudience
+		}
+	}
+	return nil
+}
+
+// 声明值转为请求头，非字符串使用 JSON
+func jwtClaimString(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,302 @@
+package agent
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func signJWT(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	hash := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch alg {
+	case JWTAlgHS256:
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case JWTAlgRS256:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, hash[:]); err != nil {
+			t.Fatal(err)
+		}
+	case JWTAlgES256:
+		r, s, err := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func publicKeyPEM(t *testing.T, key interface{}) string {
+	t.Helper()
+	b, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b}))
+}
+
+func checkJWT(t *testing.T, auth AuthorizeHandler, tok string) (*httptest.ResponseRecorder, *http.Request, bool) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if tok != "" {
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+	req.Header.Set("X-User", "spoofed")
+	w := httptest.NewRecorder()
+	ok := auth.HandleAuthorizeCheck(w, req)
+	return w, req, ok
+}
+
+func TestJWTAuth(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	secret := []byte("secret")
+
+	now := time.Now().Unix()
+	valid := map[string]interface{}{"sub": "alice", "iss": "issuer", "aud": []string{"api"}, "exp": now + 60, "roles": []string{"admin"}}
+
+	newAuth := func(key string, alg ...string) AuthorizeHandler {
+		keys, err := NewJWTStaticKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewJWTAuth(&JWTAuthConfig{
+			Algorithms: alg,
+			Keys:       keys,
+			Issuer:     "issuer",
+			Audience:   []string{"api", "web"},
+			RequireExp: true,
+			ClaimHeaders: []*JWTClaimHeader{
+				{Claim: "sub", Header: "X-User"},
+				{Claim: "roles", Header: "X-Roles"},
+			},
+		})
+	}
+
+	hs := newAuth(string(secret), JWTAlgHS256)
+	rs := newAuth(publicKeyPEM(t, &rsaKey.PublicKey), JWTAlgRS256, JWTAlgHS256)
+	es := newAuth(publicKeyPEM(t, &ecKey.PublicKey), JWTAlgES256)
+	keys, _ := NewJWTStaticKey(string(secret))
+	optionalExp := NewJWTAuth(&JWTAuthConfig{Algorithms: []string{JWTAlgHS256}, Keys: keys, ClaimHeaders: []*JWTClaimHeader{{Claim: "sub", Header: "X-User"}, {Claim: "roles", Header: "X-Roles"}}})
+
+	with := func(k string, v interface{}) map[string]interface{} {
+		c := map[string]interface{}{}
+		for key, value := range valid {
+			c[key] = value
+		}
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name string
+		auth AuthorizeHandler
+		tok  string
+		want bool
+	}{
+		{"hs256", hs, signJWT(t, JWTAlgHS256, "", secret, valid), true},
+		{"rs256", rs, signJWT(t, JWTAlgRS256, "", rsaKey, valid), true},
+		{"es256", es, signJWT(t, JWTAlgES256, "", ecKey, valid), true},
+		{"missing", hs, "", false},
+		{"malformed", hs, "a.b", false},
+		{"bad signature", hs, signJWT(t, JWTAlgHS256, "", []byte("other"), valid), false},
+		{"algorithm not allowed", es, signJWT(t, JWTAlgHS256, "", secret, valid), false},
+		{"public key as hmac secret", rs, signJWT(t, JWTAlgHS256, "", []byte(publicKeyPEM(t, &rsaKey.PublicKey)), valid), false},
+		{"none", hs, signJWT(t, "none", "", nil, valid), false},
+		{"expired", hs, signJWT(t, JWTAlgHS256, "", secret, with("exp", now-10)), false},
+		{"not before", hs, signJWT(t, JWTAlgHS256, "", secret, with("nbf", now+60)), false},
+		{"issuer", hs, signJWT(t, JWTAlgHS256, "", secret, with("iss", "other")), false},
+		{"audience", hs, signJWT(t, JWTAlgHS256, "", secret, with("aud", "other")), false},
+		{"audience string", hs, signJWT(t, JWTAlgHS256, "", secret, with("aud", "web")), true},
+		{"missing audience", hs, signJWT(t, JWTAlgHS256, "", secret, with("aud", nil)), false},
+		{"missing exp", hs, signJWT(t, JWTAlgHS256, "", secret, with("exp", nil)), false},
+		{"optional exp", optionalExp, signJWT(t, JWTAlgHS256, "", secret, with("exp", nil)), true},
+	}
+	for _, tt := range tests {
+		w, req, ok := checkJWT(t, tt.auth, tt.tok)
+		if ok != tt.want {
+			t.Errorf("%s: HandleAuthorizeCheck() = %v, want %v (%s)", tt.name, ok, tt.want, w.Body.String())
+			continue
+		}
+		if !ok {
+			if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("%s: status = %d, want 401 with WWW-Authenticate", tt.name, w.Code)
+			}
+			continue
+		}
+		if got := req.Header.Get("X-User"); got != "alice" {
+			t.Errorf("%s: X-User = %q, want alice", tt.name, got)
+		}
+		if got := req.Header.Get("X-Roles"); got != `["admin"]` {
+			t.Errorf("%s: X-Roles = %q, want [\"admin\"]", tt.name, got)
+		}
+	}
+}
+
+func jwksJSON(t *testing.T, keys map[string]interface{}) []byte {
+	t.Helper()
+	set := []map[string]string{}
+	for kid, key := range keys {
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			set = append(set, map[string]string{
+				"kty": "RSA", "kid": kid, "alg": JWTAlgRS256,
+				"n": base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			x, y := make([]byte, 32), make([]byte, 32)
+			k.X.FillBytes(x)
+			k.Y.FillBytes(y)
+			set = append(set, map[string]string{
+				"kty": "EC", "kid": kid, "crv": "P-256",
+				"x": base64.RawURLEncoding.EncodeToString(x),
+				"y": base64.RawURLEncoding.EncodeToString(y),
+			})
+		}
+	}
+	b, _ := json.Marshal(map[string]interface{}{"keys": set})
+	return b
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	claims := map[string]interface{}{"sub": "alice", "exp": time.Now().Unix() + 60}
+
+	var body atomic.Value
+	body.Store(jwksJSON(t, map[string]interface{}{"rsa": &rsaKey.PublicKey}))
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Write(body.Load().([]byte))
+	}))
+	defer server.Close()
+
+	jwks := NewJWKS(server.URL, time.Hour)
+	auth := NewJWTAuth(&JWTAuthConfig{Algorithms: []string{JWTAlgRS256, JWTAlgES256}, Keys: jwks})
+
+	for i := 0; i < 3; i++ {
+		if _, _, ok := checkJWT(t, auth, signJWT(t, JWTAlgRS256, "rsa", rsaKey, claims)); !ok {
+			t.Fatal("rsa token rejected")
+		}
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("fetches = %d, want 1 cached", n)
+	}
+
+	// 密钥轮换，未知 kid 在刷新间隔内不重复请求
+	body.Store(jwksJSON(t, map[string]interface{}{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey}))
+	if _, _, ok := checkJWT(t, auth, signJWT(t, JWTAlgES256, "ec", ecKey, claims)); ok {
+		t.Error("ec token accepted before refresh interval")
+	}
+	jwks.mtx.Lock()
+	jwks.triedAt = time.Now().Add(-jwksRefreshInterval - time.Second)
+	jwks.mtx.Unlock()
+	if _, _, ok := checkJWT(t, auth, signJWT(t, JWTAlgES256, "ec", ecKey, claims)); !ok {
+		t.Error("ec token rejected after refresh")
+	}
+	if n := atomic.LoadInt32(&fetches); n != 2 {
+		t.Errorf("fetches = %d, want 2", n)
+	}
+
+	// 加载失败时继续使用缓存
+	server.Close()
+	jwks.mtx.Lock()
+	jwks.fetchedAt = time.Now().Add(-2 * time.Hour)
+	jwks.triedAt = time.Time{}
+	jwks.mtx.Unlock()
+	if _, _, ok := checkJWT(t, auth, signJWT(t, JWTAlgRS256, "rsa", rsaKey, claims)); !ok {
+		t.Error("cached key rejected after fetch error")
+	}
+
+	// 文件
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(file, jwksJSON(t, map[string]interface{}{"ec": &ecKey.PublicKey}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	auth = NewJWTAuth(&JWTAuthConfig{Algorithms: []string{JWTAlgES256}, Keys: NewJWKS(file, 0)})
+	if _, _, ok := checkJWT(t, auth, signJWT(t, JWTAlgES256, "", ecKey, claims)); !ok {
+		t.Error("file jwks token rejected")
+	}
+}
+
+// 并发请求只加载一次 JWKS，加载期间不持有锁
+func TestJWKSRefreshSingleFlight(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	body := jwksJSON(t, map[string]interface{}{"rsa": &rsaKey.PublicKey})
+	var fetches int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	jwks := NewJWKS(server.URL, time.Hour)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := jwks.JWTKey("rsa", JWTAlgRS256); err != nil {
+				t.Errorf("JWTKey() error = %v", err)
+			}
+		}()
+	}
+
+	// 加载中读取缓存不阻塞
+	time.Sleep(50 * time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		jwks.lookup("rsa", JWTAlgRS256)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("lookup blocked by refresh")
+	}
+
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("fetches = %d, want 1", n)
+	}
+}
@@ -0,0 +1,460 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"dxkite.cn/meownest/pkg/token"
+)
+
+const (
+	defaultOIDCCookieName = "meownest_oidc"
+	// 登录跳转到回调的最长时间
+	oidcStateTTL     = 10 * time.Minute
+	oidcFetchTimeout = 10 * time.Second
+	oidcMaxSize      = 1 << 20
+)
+
+var (
+	ErrOIDCState    = errors.New("oidc: invalid state")
+	ErrOIDCNonce    = errors.New("oidc: invalid nonce")
+	ErrOIDCIssuer   = errors.New("oidc: issuer mismatch")
+	ErrOIDCIDToken  = errors.New("oidc: missing id_token")
+	ErrOIDCSession  = errors.New("oidc: invalid session")
+	ErrOIDCDiscover = errors.New("oidc: discovery failed")
+)
+
+type OIDCAuthConfig struct {
+	// 提供方，从 <Issuer>/.well-known/openid-configuration 读取端点
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	// 回调路径，需能匹配到使用此鉴权的路由
+	CallbackPath string
+	// 为空时使用 openid profile email
+	Scopes []string
+	// ID Token 允许的签名算法，为空时使用 RS256 与 ES256
+	Algorithms []string
+	// 加密会话 Cookie
+	Crypto     token.Crypto
+	CookieName string
+	// 始终设置 Cookie 的 Secure 标记，TLS 由前置代理终止时开启；关闭时按连接是否为 TLS 设置
+	CookieSecure bool
+	// 会话有效期，为 0 时使用 ID Token 的过期时间
+	SessionTTL time.Duration
+	Leeway     time.Duration
+	// 转发到后端的声明，保存在会话中
+	ClaimHeaders []*JWTClaimHeader
+}
+
+// OIDC 授权码登录
+//
+// 未登录的 GET 请求跳转到提供方登录，其他请求返回 401；
+// 回调校验 ID Token 后写入加密的会话 Cookie 并跳转回原地址
+func NewOIDCAuth(config *OIDCAuthConfig) AuthorizeHandler {
+	if config.CookieName == "" {
+		config.CookieName = defaultOIDCCookieName
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "profile", "email"}
+	}
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = []string{JWTAlgRS256, JWTAlgES256}
+	}
+
+	provider := &oidcProvider{issuer: config.Issuer, client: &http.Client{Timeout: oidcFetchTimeout}}
+	return &oidcAuth{
+		config:   config,
+		provider: provider,
+		verifier: &jwtAuth{config: &JWTAuthConfig{
+			Algorithms: config.Algorithms,
+			Keys:       provider,
+			Issuer:     config.Issuer,
+			Audience:   []string{config.ClientId},
+			Leeway:     config.Leeway,
+			RequireExp: true,
+		}},
+	}
+}
+
+type oidcAuth struct {
+	config   *OIDCAuthConfig
+	provider *oidcProvider
+	verifier *jwtAuth
+}
+
+// 会话 Cookie 内容
+type oidcSession struct {
+	ExpireAt int64                  `json:"exp"`
+	Claims   map[string]interface{} `json:"claims"`
+}
+
+// 登录跳转时保存的状态
+type oidcState struct {
+	ExpireAt int64  `json:"exp"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	// 登录后跳转的本站地址
+	URI string `json:"uri"`
+}
+
+func (a *oidcAuth) HandleAuthorizeCheck(w http.ResponseWriter, req *http.Request) bool {
+	// 防止客户端伪造声明请求头
+	for _, v := range a.config.ClaimHeaders {
+		req.Header.Del(v.Header)
+	}
+
+	if req.URL.Path == a.config.CallbackPath {
+		a.handleCallback(w, req)
+		return false
+	}
+
+	session := &oidcSession{}
+	if err := a.readCookie(req, a.config.CookieName, session); err == nil && time.Now().Unix() < session.ExpireAt {
+		for _, v := range a.config.ClaimHeaders {
+			if value, ok := session.Claims[v.Claim]; ok {
+				req.Header.Set(v.Header, jwtClaimString(value))
+			}
+		}
+		return true
+	}
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		http.Error(w, ErrOIDCSession.Error(), http.StatusUnauthorized)
+		return false
+	}
+	a.redirectLogin(w, req)
+	return false
+}
+
+func (a *oidcAuth) AuthorizeHeaders() []string {
+	return claimHeaders(a.config.ClaimHeaders)
+}
+
+// 跳转到提供方登录
+func (a *oidcAuth) redirectLogin(w http.ResponseWriter, req *http.Request) {
+	meta, err := a.provider.metadata()
+	if err != nil {
+		printLog("oidc discovery %s error: %s\n", a.config.Issuer, err.Error())
+		http.Error(w, ErrOIDCDiscover.Error(), http.StatusBadGateway)
+		return
+	}
+
+	state := &oidcState{
+		ExpireAt: time.Now().Add(oidcStateTTL).Unix(),
+		State:    oidcRandom(),
+		Nonce:    oidcRandom(),
+		URI:      req.URL.RequestURI(),
+	}
+	if err := a.writeCookie(w, req, a.stateCookieName(), state, oidcStateTTL); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", a.config.ClientId)
+	query.Set("redirect_uri", a.redirectURI(req))
+	query.Set("scope", strings.Join(a.config.Scopes, " "))
+	query.Set("state", state.State)
+	query.Set("nonce", state.Nonce)
+
+	location := meta.AuthorizationEndpoint
+	if strings.Contains(location, "?") {
+		location += "&" + query.Encode()
+	} else {
+		location += "?" + query.Encode()
+	}
+	http.Redirect(w, req, location, http.StatusFound)
+}
+
+func (a *oidcAuth) handleCallback(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	if e := query.Get("error"); e != "" {
+		http.Error(w, "oidc: "+e, http.StatusUnauthorized)
+		return
+	}
+
+	state := &oidcState{}
+	if err := a.readCookie(req, a.stateCookieName(), state); err != nil ||
+		time.Now().Unix() > state.ExpireAt || state.State == "" || state.State != query.Get("state") {
+		http.Error(w, ErrOIDCState.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	idToken, err := a.exchange(req, query.Get("code"))
+	if err != nil {
+		printLog("oidc token exchange error: %s\n", err.Error())
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	now := time.Now()
+	claims, err := a.verifier.verify(idToken, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if nonce, _ := claims["nonce"].(string); nonce != state.Nonce {
+		http.Error(w, ErrOIDCNonce.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// 会话只保存需要转发的声明，控制 Cookie 大小
+	session := &oidcSession{Claims: map[string]interface{}{}}
+	for _, v := range a.config.ClaimHeaders {
+		if value, ok := claims[v.Claim]; ok {
+			session.Claims[v.Claim] = value
+		}
+	}
+	ttl := a.config.SessionTTL
+	if ttl <= 0 {
+		exp, _ := claims["exp"].(float64)
+		ttl = time.Unix(int64(exp), 0).Sub(now)
+	}
+	if ttl <= 0 {
+		http.Error(w, ErrJWTExpired.Error(), http.StatusUnauthorized)
+		return
+	}
+	session.ExpireAt = now.Add(ttl).Unix()
+
+	if err := a.writeCookie(w, req, a.config.CookieName, session, ttl); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.clearCookie(w, req, a.stateCookieName())
+	http.Redirect(w, req, oidcRedirectPath(state.URI), http.StatusFound)
+}
+
+// 使用授权码换取 ID Token
+func (a *oidcAuth) exchange(req *http.Request, code string) (string, error) {
+	meta, err := a.provider.metadata()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", a.redirectURI(req))
+	form.Set("client_id", a.config.ClientId)
+
+	ctx, cancel := context.WithTimeout(req.Context(), oidcFetchTimeout)
+	defer cancel()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Accept", "application/json")
+	if a.config.ClientSecret != "" {
+		r.SetBasicAuth(url.QueryEscape(a.config.ClientId), url.QueryEscape(a.config.ClientSecret))
+	}
+
+	resp, err := a.provider.client.Do(r)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oidc: token endpoint status %d", resp.StatusCode)
+	}
+
+	var rst struct {
+		IdToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxSize)).Decode(&rst); err != nil {
+		return "", err
+	}
+	if rst.IdToken == "" {
+		return "", ErrOIDCIDToken
+	}
+	return rst.IdToken, nil
+}
+
+func (a *oidcAuth) redirectURI(req *http.Request) string {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host + a.config.CallbackPath
+}
+
+func (a *oidcAuth) stateCookieName() string {
+	return a.config.CookieName + "_state"
+}
+
+func (a *oidcAuth) readCookie(req *http.Request, name string, v interface{}) error {
+	c, err := req.Cookie(name)
+	if err != nil {
+		return err
+	}
+	b, err := base64.RawURLEncoding.DecodeString(c.Value)
+	if err != nil {
+		return err
+	}
+	data, err := a.config.Crypto.Decrypt(b)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (a *oidcAuth) writeCookie(w http.ResponseWriter, req *http.Request, name string, v interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	b, err := a.config.Crypto.Encrypt(data)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    base64.RawURLEncoding.EncodeToString(b),
+		Path:     "/",
+		MaxAge:   int(ttl / time.Second),
+		Secure:   a.cookieSecure(req),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+func (a *oidcAuth) clearCookie(w http.ResponseWriter, req *http.Request, name string) {
+	http.SetCookie(w, &http.Cookie{Name: name, Path: "/", MaxAge: -1, Secure: a.cookieSecure(req), HttpOnly: true})
+}
+
+func (a *oidcAuth) cookieSecure(req *http.Request) bool {
+	return a.config.CookieSecure || req.TLS != nil
+}
+
+// 登录后只允许跳转到站内路径，防止 //host 或 /\host 形式的开放重定向
+func oidcRedirectPath(uri string) string {
+	if !strings.HasPrefix(uri, "/") || strings.HasPrefix(uri, "//") || strings.HasPrefix(uri, "/\\") {
+		return "/"
+	}
+	return uri
+}
+
+func oidcRandom() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// 提供方配置，首次使用时读取，失败后间隔重试
+//
+// 读取在锁外进行，同一时间只有一个读取，其余请求等待结果
+type oidcProvider struct {
+	issuer string
+	client *http.Client
+
+	mtx     sync.Mutex
+	meta    *oidcMetadata
+	jwks    *JWKS
+	triedAt time.Time
+	// 正在进行的读取，完成后关闭
+	loading chan struct{}
+}
+
+func (p *oidcProvider) JWTKey(kid, alg string) (interface{}, error) {
+	if _, err := p.metadata(); err != nil {
+		return nil, err
+	}
+	p.mtx.Lock()
+	jwks := p.jwks
+	p.mtx.Unlock()
+	return jwks.JWTKey(kid, alg)
+}
+
+func (p *oidcProvider) metadata() (*oidcMetadata, error) {
+	p.mtx.Lock()
+	if p.meta != nil {
+		meta := p.meta
+		p.mtx.Unlock()
+		return meta, nil
+	}
+	if loading := p.loading; loading != nil {
+		p.mtx.Unlock()
+		<-loading
+		p.mtx.Lock()
+		meta := p.meta
+		p.mtx.Unlock()
+		if meta == nil {
+			return nil, ErrOIDCDiscover
+		}
+		return meta, nil
+	}
+	if time.Since(p.triedAt) < jwksRefreshInterval {
+		p.mtx.Unlock()
+		return nil, ErrOIDCDiscover
+	}
+	p.triedAt = time.Now()
+	loading := make(chan struct{})
+	p.loading = loading
+	p.mtx.Unlock()
+
+	meta, err := p.discover()
+
+	p.mtx.Lock()
+	if err == nil {
+		p.meta = meta
+		p.jwks = NewJWKS(meta.JwksURI, 0)
+	}
+	p.loading = nil
+	p.mtx.Unlock()
+	close(loading)
+	return meta, err
+}
+
+func (p *oidcProvider) discover() (*oidcMetadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), oidcFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	meta := &oidcMetadata{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxSize)).Decode(meta); err != nil {
+		return nil, err
+	}
+	// 提供方的 issuer 需与配置完全一致
+	if meta.Issuer != p.issuer {
+		return nil, ErrOIDCIssuer
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JwksURI == "" {
+		return nil, ErrOIDCDiscover
+	}
+	return meta, nil
+}
@@ -0,0 +1,257 @@
+package agent
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dxkite.cn/meownest/pkg/token"
+)
+
+// 模拟 OIDC 提供方，授权码即 ID Token 中的 nonce
+func newMockOIDCProvider(t *testing.T, key *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(jwksJSON(t, map[string]interface{}{"k1": &key.PublicKey}))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "client" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("redirect_uri") != "http://app.local/oauth2/callback" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "access",
+			"id_token": signJWT(t, JWTAlgRS256, "k1", key, map[string]interface{}{
+				"iss": server.URL, "aud": "client", "sub": "alice", "email": "alice@example.com",
+				"nonce": r.PostFormValue("code"), "exp": time.Now().Unix() + 300,
+			}),
+		})
+	})
+	server = httptest.NewServer(mux)
+	return server
+}
+
+func TestOIDCAuth(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	provider := newMockOIDCProvider(t, key)
+	defer provider.Close()
+
+	auth := NewOIDCAuth(&OIDCAuthConfig{
+		Issuer:       provider.URL,
+		ClientId:     "client",
+		ClientSecret: "secret",
+		CallbackPath: "/oauth2/callback",
+		Crypto:       token.NewAesCrypto([]byte("12345678901234567890123456789012")),
+		ClaimHeaders: []*JWTClaimHeader{{Claim: "sub", Header: "X-User"}, {Claim: "email", Header: "X-Email"}},
+	})
+
+	do := func(method, target string, cookies ...*http.Cookie) (*httptest.ResponseRecorder, *http.Request, bool) {
+		req := httptest.NewRequest(method, "http://app.local"+target, nil)
+		req.Header.Set("X-User", "spoofed")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		ok := auth.HandleAuthorizeCheck(w, req)
+		return w, req, ok
+	}
+	cookie := func(w *httptest.ResponseRecorder, name string) *http.Cookie {
+		for _, c := range w.Result().Cookies() {
+			if c.Name == name {
+				return c
+			}
+		}
+		t.Fatalf("cookie %s not set", name)
+		return nil
+	}
+
+	// 未登录跳转到提供方
+	w, _, ok := do(http.MethodGet, "/dashboard?tab=1")
+	if ok || w.Code != http.StatusFound {
+		t.Fatalf("login redirect: ok = %v status = %d", ok, w.Code)
+	}
+	location, _ := url.Parse(w.Header().Get("Location"))
+	query := location.Query()
+	if location.Path != "/authorize" || query.Get("client_id") != "client" || query.Get("redirect_uri") != "http://app.local/oauth2/callback" {
+		t.Fatalf("Location = %s", location)
+	}
+	state := cookie(w, defaultOIDCCookieName+"_state")
+
+	if w, _, ok := do(http.MethodPost, "/dashboard"); ok || w.Code != http.StatusUnauthorized {
+		t.Errorf("post without session: ok = %v status = %d", ok, w.Code)
+	}
+
+	callback := func(code, st string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		w, _, ok := do(http.MethodGet, "/oauth2/callback?"+url.Values{"code": {code}, "state": {st}}.Encode(), cookies...)
+		if ok {
+			t.Fatal("callback passed to upstream")
+		}
+		return w
+	}
+
+	if w := callback(query.Get("nonce"), "other", state); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong state: status = %d", w.Code)
+	}
+	if w := callback(query.Get("nonce"), query.Get("state")); w.Code != http.StatusUnauthorized {
+		t.Errorf("missing state cookie: status = %d", w.Code)
+	}
+	if w := callback("other-nonce", query.Get("state"), state); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong nonce: status = %d", w.Code)
+	}
+
+	w = callback(query.Get("nonce"), query.Get("state"), state)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/dashboard?tab=1" {
+		t.Fatalf("callback: status = %d Location = %q body = %s", w.Code, w.Header().Get("Location"), w.Body.String())
+	}
+	session := cookie(w, defaultOIDCCookieName)
+	if !session.HttpOnly {
+		t.Error("session cookie not HttpOnly")
+	}
+
+	_, req, ok := do(http.MethodPost, "/dashboard", session)
+	if !ok {
+		t.Fatal("session rejected")
+	}
+	if got := req.Header.Get("X-User"); got != "alice" {
+		t.Errorf("X-User = %q, want alice", got)
+	}
+	if got := req.Header.Get("X-Email"); got != "alice@example.com" {
+		t.Errorf("X-Email = %q", got)
+	}
+
+	tampered := &http.Cookie{Name: session.Name, Value: session.Value[:len(session.Value)-2] + "AA"}
+	if w, _, ok := do(http.MethodGet, "/dashboard", tampered); ok || w.Code != http.StatusFound {
+		t.Errorf("tampered session: ok = %v status = %d", ok, w.Code)
+	}
+}
+
+// 登录后不跳转到站外地址，前置代理终止 TLS 时 Cookie 仍设置 Secure
+func TestOIDCRedirectPath(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	provider := newMockOIDCProvider(t, key)
+	defer provider.Close()
+
+	auth := NewOIDCAuth(&OIDCAuthConfig{
+		Issuer:       provider.URL,
+		ClientId:     "client",
+		ClientSecret: "secret",
+		CallbackPath: "/oauth2/callback",
+		Crypto:       token.NewAesCrypto([]byte("12345678901234567890123456789012")),
+		CookieSecure: true,
+	})
+
+	login := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		auth.HandleAuthorizeCheck(w, httptest.NewRequest(http.MethodGet, "http://app.local"+target, nil))
+		location, _ := url.Parse(w.Header().Get("Location"))
+		query := location.Query()
+
+		req := httptest.NewRequest(http.MethodGet, "http://app.local/oauth2/callback?"+url.Values{"code": {query.Get("nonce")}, "state": {query.Get("state")}}.Encode(), nil)
+		for _, c := range w.Result().Cookies() {
+			if !c.Secure {
+				t.Errorf("cookie %s not secure", c.Name)
+			}
+			req.AddCookie(c)
+		}
+		w = httptest.NewRecorder()
+		auth.HandleAuthorizeCheck(w, req)
+		return w
+	}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"/dashboard?tab=1", "/dashboard?tab=1"},
+		{"//evil.example/x", "/"},
+	}
+	for _, tt := range tests {
+		w := login(tt.target)
+		if w.Code != http.StatusFound || w.Header().Get("Location") != tt.want {
+			t.Errorf("%s: status = %d Location = %q, want %q", tt.target, w.Code, w.Header().Get("Location"), tt.want)
+		}
+		for _, c := range w.Result().Cookies() {
+			if !c.Secure {
+				t.Errorf("%s: cookie %s not secure", tt.target, c.Name)
+			}
+		}
+	}
+
+	for _, uri := range []string{"/\\evil.example", "https://evil.example", ""} {
+		if got := oidcRedirectPath(uri); got != "/" {
+			t.Errorf("oidcRedirectPath(%q) = %q, want /", uri, got)
+		}
+	}
+}
+
+// 读取提供方配置时不持有锁，并发请求只读取一次
+func TestOIDCProviderMetadata(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/jwks",
+		})
+	}))
+	defer server.Close()
+
+	p := &oidcProvider{issuer: server.URL, client: &http.Client{Timeout: oidcFetchTimeout}}
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := p.metadata()
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < 100 && atomic.LoadInt32(&calls) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !p.mtx.TryLock() {
+		close(release)
+		t.Fatal("lock held during discover")
+	}
+	p.mtx.Unlock()
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("metadata() error = %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("discover calls = %d, want 1", n)
+	}
+}
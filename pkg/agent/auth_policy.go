@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// 鉴权响应体最大读取长度
+const authResponseMaxBody = 64 * 1024
+
+// 鉴权通过后写入后端请求的请求头
+//
+// 组合鉴权据此清除客户端伪造的请求头，包括未执行的鉴权
+type AuthorizeHeaderProvider interface {
+	AuthorizeHeaders() []string
+}
+
+func authorizeHeaders(handlers []AuthorizeHandler) []string {
+	headers := []string{}
+	for _, h := range handlers {
+		v, ok := h.(AuthorizeHeaderProvider)
+		if !ok {
+			continue
+		}
+		for _, name := range v.AuthorizeHeaders() {
+			if name != "" {
+				headers = append(headers, name)
+			}
+		}
+	}
+	return headers
+}
+
+// 任意一个鉴权通过即放行
+//
+// 每个鉴权在请求副本上执行，通过时使用该副本的请求头并保留其响应头，如刷新会话的 Set-Cookie；
+// 全部失败时返回第一个鉴权的响应，浏览器登录类鉴权应放在第一个
+func NewAnyOfAuth(handlers ...AuthorizeHandler) AuthorizeHandler {
+	return &anyOfAuth{handlers: handlers}
+}
+
+type anyOfAuth struct {
+	handlers []AuthorizeHandler
+}
+
+func (a *anyOfAuth) HandleAuthorizeCheck(w http.ResponseWriter, req *http.Request) bool {
+	for _, v := range a.AuthorizeHeaders() {
+		req.Header.Del(v)
+	}
+
+	var first *authResponseWriter
+	for _, h := range a.handlers {
+		r := req.Clone(req.Context())
+		rw := newAuthResponseWriter()
+		if h.HandleAuthorizeCheck(rw, r) {
+			req.Header = r.Header
+			for k, v := range rw.header {
+				w.Header()[k] = append(w.Header()[k], v...)
+			}
+			return true
+		}
+		if first == nil {
+			first = rw
+		}
+	}
+
+	if first == nil {
+		http.Error(w, "no authorize", http.StatusUnauthorized)
+		return false
+	}
+	first.writeTo(w)
+	return false
+}
+
+func (a *anyOfAuth) AuthorizeHeaders() []string {
+	return authorizeHeaders(a.handlers)
+}
+
+func (a *anyOfAuth) Start() {
+	startAuthorize(a.handlers)
+}
+
+func (a *anyOfAuth) Stop() {
+	stopAuthorize(a.handlers)
+}
+
+// 全部鉴权通过才放行，按顺序执行，失败时返回该鉴权的响应
+func NewAllOfAuth(handlers ...AuthorizeHandler) AuthorizeHandler {
+	return &allOfAuth{handlers: handlers}
+}
+
+type allOfAuth struct {
+	handlers []AuthorizeHandler
+}
+
+func (a *allOfAuth) HandleAuthorizeCheck(w http.ResponseWriter, req *http.Request) bool {
+	for _, v := range a.AuthorizeHeaders() {
+		req.Header.Del(v)
+	}
+
+	for _, h := range a.handlers {
+		if !h.HandleAuthorizeCheck(w, req) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *allOfAuth) AuthorizeHeaders() []string {
+	return authorizeHeaders(a.handlers)
+}
+
+func (a *allOfAuth) Start() {
+	startAuthorize(a.handlers)
+}
+
+func (a *allOfAuth) Stop() {
+	stopAuthorize(a.handlers)
+}
+
+func startAuthorize(handlers []AuthorizeHandler) {
+	for _, h := range handlers {
+		if v, ok := h.(interface{ Start() }); ok {
+			v.Start()
+		}
+	}
+}
+
+func stopAuthorize(handlers []AuthorizeHandler) {
+	for _, h := range handlers {
+		if v, ok := h.(interface{ Stop() }); ok {
+			v.Stop()
+		}
+	}
+}
+
+// 记录鉴权响应，用于外部鉴权与组合鉴权
+type authResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newAuthResponseWriter() *authResponseWriter {
+	return &authResponseWriter{header: http.Header{}}
+}
+
+func (w *authResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *authResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *authResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if n := authResponseMaxBody - w.body.Len(); n > 0 {
+		if len(b) > n {
+			w.body.Write(b[:n])
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return len(b), nil
+}
+
+func (w *authResponseWriter) writeTo(dst http.ResponseWriter) {
+	for k, v := range w.header {
+		dst.Header()[k] = v
+	}
+	status := w.status
+	if status == 0 {
+		status = http.StatusUnauthorized
+	}
+	dst.WriteHeader(status)
+	dst.Write(w.body.Bytes())
+}
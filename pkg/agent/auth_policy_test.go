@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuthorizePolicy(t *testing.T) {
+	secret := []byte("secret")
+	keys, err := NewJWTStaticKey(string(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwt := NewJWTAuth(&JWTAuthConfig{
+		Algorithms:   []string{JWTAlgHS256},
+		Keys:         keys,
+		ClaimHeaders: []*JWTClaimHeader{{Claim: "sub", Header: "X-User"}},
+	})
+	apiKey := NewAPIKeyAuth("X-Client", nil, testCredentialTable(t, map[string]string{"credential_a": "key"}))
+	tok := signJWT(t, JWTAlgHS256, "", secret, map[string]interface{}{"sub": "alice", "exp": time.Now().Unix() + 60})
+
+	anyOf := NewAnyOfAuth(jwt, apiKey)
+	allOf := NewAllOfAuth(jwt, apiKey)
+
+	tests := []struct {
+		name   string
+		auth   AuthorizeHandler
+		token  string
+		key    string
+		want   bool
+		user   string
+		client string
+	}{
+		{"any jwt", anyOf, tok, "", true, "alice", ""},
+		{"any apikey", anyOf, "", "credential_a.key", true, "", "principal-credential_a"},
+		{"any both", anyOf, tok, "credential_a.key", true, "alice", ""},
+		{"any none", anyOf, "", "", false, "", ""},
+		{"any invalid", anyOf, "bad", "credential_a.bad", false, "", ""},
+		{"all both", allOf, tok, "credential_a.key", true, "alice", "principal-credential_a"},
+		{"all jwt only", allOf, tok, "", false, "", ""},
+		{"all apikey only", allOf, "", "credential_a.key", false, "", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User", "spoofed")
+		req.Header.Set("X-Client", "spoofed")
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		if tt.key != "" {
+			req.Header.Set("X-API-Key", tt.key)
+		}
+		w := httptest.NewRecorder()
+		if got := tt.auth.HandleAuthorizeCheck(w, req); got != tt.want {
+			t.Errorf("%s: HandleAuthorizeCheck() = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		if !tt.want {
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("%s: status = %d, want 401", tt.name, w.Code)
+			}
+			if tt.auth == anyOf && w.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("%s: want first authorize response", tt.name)
+			}
+			continue
+		}
+		if got := req.Header.Get("X-User"); got != tt.user {
+			t.Errorf("%s: X-User = %q, want %q", tt.name, got, tt.user)
+		}
+		if got := req.Header.Get("X-Client"); got != tt.client {
+			t.Errorf("%s: X-Client = %q, want %q", tt.name, got, tt.client)
+		}
+	}
+}
+
+// 通过时写入响应头
+type testCookieAuth struct{}
+
+func (testCookieAuth) HandleAuthorizeCheck(w http.ResponseWriter, req *http.Request) bool {
+	w.Header().Add("Set-Cookie", "session=refreshed")
+	return true
+}
+
+func TestAnyOfAuthResponseHeader(t *testing.T) {
+	apiKey := NewAPIKeyAuth("", nil, CredentialTable{})
+	auth := NewAnyOfAuth(apiKey, testCookieAuth{})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	if !auth.HandleAuthorizeCheck(w, req) {
+		t.Fatal("HandleAuthorizeCheck() = false, want true")
+	}
+	if got := w.Header().Values("Set-Cookie"); len(got) != 1 || got[0] != "session=refreshed" {
+		t.Errorf("Set-Cookie = %q, want session=refreshed", got)
+	}
+}
@@ -0,0 +1,214 @@
+package agent
+
+import (
+	"crypto/tls"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	BalanceRandom     = "random"
+	BalanceRoundRobin = "round_robin"
+	BalanceLeastConn  = "least_conn"
+	BalanceHash       = "hash"
+)
+
+// 负载均衡策略
+// 从可用的目标中选取一个，targets 为空时返回 nil
+type Balancer interface {
+	Select(req *http.Request, targets []*EndpointTarget) *EndpointTarget
+}
+
+type EndpointTarget struct {
+	Network string
+	Address string
+	Weight  int
+	// 后端协议 http1 h2 h2c，为空时使用 http1
+	Protocol string
+	// h2 使用的 TLS 配置，为空时使用默认配置
+	TLSConfig *tls.Config
+
+	// 当前连接数
+	conns int64
+	// 平滑加权轮询当前权重
+	current int
+	// 健康状态
+	health targetHealth
+	// HTTP/2 连接，首次转发时创建
+	h2Once sync.Once
+	h2     *http2.Transport
+}
+
+func (t *EndpointTarget) weight() int {
+	if t.Weight <= 0 {
+		return 1
+	}
+	return t.Weight
+}
+
+// 目标唯一标识，用于会话保持
+func (t *EndpointTarget) Key() string {
+	return strconv.FormatUint(uint64(hashString(t.Network+"://"+t.Address)), 36)
+}
+
+// 当前连接数
+func (t *EndpointTarget) Conns() int64 {
+	return atomic.LoadInt64(&t.conns)
+}
+
+func (t *EndpointTarget) String() string {
+	return t.Network + "://" + t.Address
+}
+
+// 加权随机
+func NewRandomBalancer() Balancer {
+	return &randomBalancer{}
+}
+
+type randomBalancer struct {
+}
+
+func (b *randomBalancer) Select(req *http.Request, targets []*EndpointTarget) *EndpointTarget {
+	return weightedRandom(targets)
+}
+
+func weightedRandom(targets []*EndpointTarget) *EndpointTarget {
+	total := 0
+	for _, t := range targets {
+		total += t.weight()
+	}
+	if total == 0 {
+		return nil
+	}
+
+	n := rand.Intn(total)
+	for _, t := range targets {
+		if n < t.weight() {
+			return t
+		}
+		n -= t.weight()
+	}
+	return nil
+}
+
+// 平滑加权轮询
+func NewRoundRobinBalancer() Balancer {
+	return &roundRobinBalancer{mtx: &sync.Mutex{}}
+}
+
+type roundRobinBalancer struct {
+	mtx *sync.Mutex
+}
+
+func (b *roundRobinBalancer) Select(req *http.Request, targets []*EndpointTarget) *EndpointTarget {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	var best *EndpointTarget
+	total := 0
+	for _, t := range targets {
+		t.current += t.weight()
+		total += t.weight()
+		if best == nil || t.current > best.current {
+			best = t
+		}
+	}
+
+	if best != nil {
+		best.current -= total
+	}
+	return best
+}
+
+// 加权最少连接，连接数相同时加权随机
+func NewLeastConnBalancer() Balancer {
+	return &leastConnBalancer{}
+}
+
+type leastConnBalancer struct {
+}
+
+func (b *leastConnBalancer) Select(req *http.Request, targets []*EndpointTarget) *EndpointTarget {
+	var least []*EndpointTarget
+	for _, t := range targets {
+		if len(least) == 0 {
+			least = append(least, t)
+			continue
+		}
+		// t.conns/t.weight 与 least[0].conns/least[0].weight 比较
+		a := t.Conns() * int64(least[0].weight())
+		b := least[0].Conns() * int64(t.weight())
+		switch {
+		case a < b:
+			least = append(least[:0], t)
+		case a == b:
+			least = append(least, t)
+		}
+	}
+	return weightedRandom(least)
+}
+
+// 一致性哈希
+// 按请求来源取值计算哈希，取值为空时加权随机
+func NewHashBalancer(targets []*EndpointTarget, source, name string) Balancer {
+	b := &hashBalancer{source: source, name: name}
+	for _, t := range targets {
+		for i := 0; i < t.weight()*hashReplicas; i++ {
+			b.ring = append(b.ring, hashNode{hash: hashString(t.String() + "#" + strconv.Itoa(i)), target: t})
+		}
+	}
+	sort.Slice(b.ring, func(i, j int) bool {
+		return b.ring[i].hash < b.ring[j].hash
+	})
+	return b
+}
+
+const hashReplicas = 40
+
+type hashNode struct {
+	hash   uint32
+	target *EndpointTarget
+}
+
+type hashBalancer struct {
+	source string
+	name   string
+	ring   []hashNode
+}
+
+func (b *hashBalancer) Select(req *http.Request, targets []*EndpointTarget) *EndpointTarget {
+	key := VarFrom(req, b.source, b.name)
+	if key == "" || len(b.ring) == 0 {
+		return weightedRandom(targets)
+	}
+
+	h := hashString(key)
+	n := len(b.ring)
+	i := sort.Search(n, func(i int) bool {
+		return b.ring[i].hash >= h
+	})
+
+	// 顺时针查找第一个可用目标
+	for j := 0; j < n; j++ {
+		node := b.ring[(i+j)%n]
+		for _, t := range targets {
+			if t == node.target {
+				return t
+			}
+		}
+	}
+	return weightedRandom(targets)
+}
+
+func hashString(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testTargets() []*EndpointTarget {
+	return []*EndpointTarget{
+		{Network: "tcp", Address: "127.0.0.1:8001", Weight: 5},
+		{Network: "tcp", Address: "127.0.0.1:8002", Weight: 1},
+		{Network: "tcp", Address: "127.0.0.1:8003", Weight: 1},
+	}
+}
+
+func TestRoundRobinBalancer(t *testing.T) {
+	targets := testTargets()
+	b := NewRoundRobinBalancer()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	count := map[string]int{}
+	for i := 0; i < 70; i++ {
+		count[b.Select(req, targets).Address]++
+	}
+
+	want := map[string]int{"127.0.0.1:8001": 50, "127.0.0.1:8002": 10, "127.0.0.1:8003": 10}
+	for k, v := range want {
+		if count[k] != v {
+			t.Errorf("Select() %s = %d, want %d", k, count[k], v)
+		}
+	}
+}
+
+func TestLeastConnBalancer(t *testing.T) {
+	targets := testTargets()
+	targets[0].conns = 10
+	targets[1].conns = 1
+	b := NewLeastConnBalancer()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	if got := b.Select(req, targets); got != targets[2] {
+		t.Errorf("Select() = %v, want %v", got, targets[2])
+	}
+}
+
+func TestHashBalancer(t *testing.T) {
+	targets := testTargets()
+	b := NewHashBalancer(targets, "header", "X-User")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-User", "user-1")
+
+	first := b.Select(req, targets)
+	for i := 0; i < 10; i++ {
+		if got := b.Select(req, targets); got != first {
+			t.Fatalf("Select() = %v, want %v", got, first)
+		}
+	}
+
+	// 目标不可用时迁移到其他目标
+	available := []*EndpointTarget{}
+	for _, v := range targets {
+		if v != first {
+			available = append(available, v)
+		}
+	}
+	if got := b.Select(req, available); got == nil || got == first {
+		t.Errorf("Select() = %v, want other target", got)
+	}
+}
+
+func TestUpstreamSticky(t *testing.T) {
+	u := NewUpstream(testTargets(), 0)
+	u.Sticky = &StickySession{Cookie: "backend"}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	first := u.Select(w, req)
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != first.Key() {
+		t.Fatalf("Select() cookies = %v, want %s", cookies, first.Key())
+	}
+
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(cookies[0])
+		if got := u.Select(httptest.NewRecorder(), req); got != first {
+			t.Fatalf("Select() = %v, want %v", got, first)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"strings"
+	"sync"
+)
+
+var ErrCertificateNotFound = errors.New("certificate not found")
+
+// 证书存储，按 SNI 选择证书
+type CertificateStore struct {
+	mtx   *sync.RWMutex
+	names map[string]*tls.Certificate
+	def   *tls.Certificate
+}
+
+func NewCertificateStore() *CertificateStore {
+	return &CertificateStore{mtx: &sync.RWMutex{}, names: map[string]*tls.Certificate{}}
+}
+
+// 替换全部证书，同一域名存在多个证书时使用过期时间最晚的
+func (s *CertificateStore) Update(certs []*tls.Certificate) error {
+	names := map[string]*tls.Certificate{}
+	var def *tls.Certificate
+
+	for _, cert := range certs {
+		if cert.Leaf == nil {
+			if len(cert.Certificate) == 0 {
+				return errors.New("empty certificate")
+			}
+			leaf, err := x509.ParseCertificate(cert.Certificate[0])
+			if err != nil {
+				return err
+			}
+			cert.Leaf = leaf
+		}
+
+		for _, name := range cert.Leaf.DNSNames {
+			name = strings.ToLower(name)
+			if v, ok := names[name]; !ok || v.Leaf.NotAfter.Before(cert.Leaf.NotAfter) {
+				names[name] = cert
+			}
+		}
+
+		if def == nil {
+			def = cert
+		}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.names = names
+	s.def = def
+	return nil
+}
+
+// 匹配域名证书，支持通配符证书 *.example.com
+func (s *CertificateStore) Match(serverName string) *tls.Certificate {
+	name := strings.ToLower(strings.TrimSuffix(serverName, "."))
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if cert, ok := s.names[name]; ok {
+		return cert
+	}
+
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		if cert, ok := s.names["*"+name[i:]]; ok {
+			return cert
+		}
+	}
+	return nil
+}
+
+func (s *CertificateStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if hello.ServerName != "" {
+		if cert := s.Match(hello.ServerName); cert != nil {
+			return cert, nil
+		}
+	}
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	// 未携带 SNI 或无匹配时使用默认证书
+	if s.def != nil {
+		return s.def, nil
+	}
+	return nil, ErrCertificateNotFound
+}
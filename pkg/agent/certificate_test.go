@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func newTestCertificate(t testing.TB, notAfter time.Time, names ...string) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestCertificateStore(t *testing.T) {
+	expire := time.Now().Add(24 * time.Hour)
+	def := newTestCertificate(t, expire, "default.test")
+	exact := newTestCertificate(t, expire, "www.example.com")
+	newer := newTestCertificate(t, expire.Add(time.Hour), "www.example.com")
+	wildcard := newTestCertificate(t, expire, "*.example.com")
+
+	store := NewCertificateStore()
+	if _, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: "www.example.com"}); err != ErrCertificateNotFound {
+		t.Fatalf("GetCertificate() err = %v, want %v", err, ErrCertificateNotFound)
+	}
+
+	if err := store.Update([]*tls.Certificate{def, exact, newer, wildcard}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want *tls.Certificate
+	}{
+		{"www.example.com", newer},
+		{"WWW.Example.com.", newer},
+		{"api.example.com", wildcard},
+		{"a.b.example.com", def},
+		{"", def},
+		{"other.test", def},
+	}
+	for _, tt := range tests {
+		got, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: tt.name})
+		if err != nil || got != tt.want {
+			t.Errorf("GetCertificate(%q) = %v %v, want %v", tt.name, got.Leaf.DNSNames, err, tt.want.Leaf.DNSNames)
+		}
+	}
+}
@@ -1,16 +1,14 @@
 package agent
 
 import (
-	"bufio"
 	"io"
-	"net"
 	"net/http"
 	"strings"
 	"time"
 )
 
 type ForwardProvider interface {
-	ForwardTarget() (network, address string, timeout time.Duration)
+	ForwardTarget(req *http.Request) (network, address string, timeout time.Duration)
 }
 
 type ForwardRewriteRequestProvider interface {
@@ -21,7 +19,17 @@ type ForwardRewriteResponseProvider interface {
 	RewriteResponse(resp *http.Response) error
 }
 
-type TargetHandler func() (network, address string, timeout time.Duration)
+// 上报转发结果，err 为空表示目标响应正常
+type ForwardReportProvider interface {
+	ReportForward(req *http.Request, err error)
+}
+
+// 后端连接池
+type ForwardPoolProvider interface {
+	ForwardPool() *ConnPool
+}
+
+type TargetHandler func(req *http.Request) (network, address string, timeout time.Duration)
 
 type BasicForwardHandler struct {
 	fp ForwardProvider
@@ -37,42 +45,80 @@ func (h *BasicForwardHandler) HandleRequest(w http.ResponseWriter, req *http.Req
 		return
 	}
 
-	network, address, timeout := h.fp.ForwardTarget()
-	rmt, err := net.DialTimeout(network, address, timeout)
+	rmt, resp, err := h.Forward(req)
 	if err != nil {
-		http.Error(w, "dial remote error: "+err.Error(), http.StatusBadGateway)
+		http.Error(w, err.Error(), err.Status)
 		return
 	}
 
-	defer rmt.Close()
+	h.WriteResponse(w, req, rmt, resp)
+}
 
-	if err := req.WriteProxy(rmt); err != nil {
-		http.Error(w, "write proxy error: "+err.Error(), http.StatusBadGateway)
-		return
+// 转发请求到目标并读取响应头
+//
+// 通过 RoundTripper 转发时返回的连接为 nil
+func (h *BasicForwardHandler) Forward(req *http.Request) (*PoolConn, *http.Response, *ForwardError) {
+	var rmt *PoolConn
+	var resp *http.Response
+	var ferr *ForwardError
+	if rt, scheme, address := h.forwardTransport(req); rt != nil {
+		resp, ferr = h.roundTripTransport(req, rt, scheme, address)
+	} else {
+		network, address, timeout := h.fp.ForwardTarget(req)
+		rmt, resp, ferr = h.roundTrip(req, network, address, timeout)
 	}
-
-	resp, err := http.ReadResponse(bufio.NewReader(rmt), req)
-	if err != nil {
-		http.Error(w, "read response error: "+err.Error(), http.StatusInternalServerError)
-		return
+	if ferr != nil {
+		if ferr.Err != ErrPoolTimeout {
+			h.report(req, ferr.Err)
+		}
+		return nil, nil, ferr
 	}
 
+	h.report(req, nil)
+
 	if err := h.rewriteResponse(resp); err != nil {
-		http.Error(w, "write response error: "+err.Error(), http.StatusBadGateway)
-		return
+		resp.Body.Close()
+		if rmt != nil {
+			rmt.Close()
+		}
+		return nil, nil, &ForwardError{ForwardStageResponse, "write response error", http.StatusBadGateway, err}
 	}
+	return rmt, resp, nil
+}
+
+// 写入后端响应，完成后释放后端连接
+func (h *BasicForwardHandler) WriteResponse(w http.ResponseWriter, req *http.Request, rmt *PoolConn, resp *http.Response) {
+	// 响应完整读取后连接放回连接池
+	reusable := false
+	defer func() {
+		// 未完整读取时先关闭连接，Body.Close 会读取剩余响应，后端持续输出时无法返回
+		if rmt != nil && !reusable {
+			rmt.Close()
+		}
+		resp.Body.Close()
+		if rmt != nil && reusable {
+			rmt.Release()
+		}
+	}()
 
 	// 是否升级到websocket
-	isWebsocket := h.isUpgradeToWebsocket(req) && resp.StatusCode == http.StatusSwitchingProtocols
+	isWebsocket := rmt != nil && h.isUpgradeToWebsocket(req) && resp.StatusCode == http.StatusSwitchingProtocols
 
 	// 普通http请求
 	if !isWebsocket {
 		h.copyHeader(w, resp.Header)
 		w.WriteHeader(resp.StatusCode)
-		if _, err := io.Copy(w, resp.Body); err != nil {
-			http.Error(w, "write response Error", http.StatusBadGateway)
+
+		var dst io.Writer = w
+		if f, ok := w.(http.Flusher); ok && resp.ContentLength == -1 {
+			dst = flushWriter{w, f}
+		}
+		// 响应头已写出，出错时不再写入错误信息
+		if _, err := io.Copy(dst, resp.Body); err != nil {
 			return
 		}
+		h.copyTrailer(w, resp.Trailer)
+		reusable = !resp.Close && !req.Close
 		return
 	}
 
@@ -83,25 +129,108 @@ func (h *BasicForwardHandler) HandleRequest(w http.ResponseWriter, req *http.Req
 		return
 	}
 
-	conn, _, err := hijacker.Hijack()
+	conn, brw, err := hijacker.Hijack()
 	if err != nil {
 		http.Error(w, "hijack response Error", http.StatusBadGateway)
 		return
 	}
 
+	// 劫持后响应由连接直接写入，出错时只能关闭连接
 	defer conn.Close()
+	defer trackTunnel(req, conn)()
 
 	if err := resp.Write(conn); err != nil {
-		http.Error(w, "write response error", http.StatusBadGateway)
+		printLog("write upgrade response error: %s\n", err.Error())
 		return
 	}
 
-	if _, _, err = h.transport(conn, rmt); err != nil {
-		http.Error(w, "transport error", http.StatusBadGateway)
-		return
+	// 读取缓冲中可能已有客户端或后端数据
+	client := newBufferedConn(conn, brw.Reader)
+	remote := newBufferedConn(rmt, rmt.Reader())
+
+	if _, _, err = newTunnel(req).Run(client, remote); err != nil {
+		printLog("websocket tunnel error: %s\n", err.Error())
 	}
 }
 
+const (
+	ForwardStageConnect = iota
+	ForwardStageRead
+	ForwardStageResponse
+)
+
+// 转发错误
+type ForwardError struct {
+	// 出错阶段
+	Stage int
+	// 错误描述
+	Message string
+	// 返回客户端的状态码
+	Status int
+	Err    error
+}
+
+func (e *ForwardError) Error() string {
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *ForwardError) Unwrap() error {
+	return e.Err
+}
+
+// 发送请求并读取响应头
+// 复用的空闲连接可能已被后端关闭，此时使用新连接重试一次
+func (h BasicForwardHandler) roundTrip(req *http.Request, network, address string, timeout time.Duration) (*PoolConn, *http.Response, *ForwardError) {
+	fresh := false
+	for {
+		rmt, err := h.dial(req, network, address, timeout, fresh)
+		if err != nil {
+			return nil, nil, &ForwardError{ForwardStageConnect, "dial remote error", http.StatusBadGateway, err}
+		}
+
+		// 单次尝试超时
+		if deadline, ok := req.Context().Deadline(); ok {
+			rmt.SetDeadline(deadline)
+		}
+
+		if err := req.WriteProxy(rmt); err != nil {
+			rmt.Close()
+			if rmt.Reused() && h.canRetry(req) {
+				fresh = true
+				continue
+			}
+			return nil, nil, &ForwardError{ForwardStageRead, "write proxy error", http.StatusBadGateway, err}
+		}
+
+		resp, err := http.ReadResponse(rmt.Reader(), req)
+		if err != nil {
+			rmt.Close()
+			if rmt.Reused() && h.canRetry(req) {
+				fresh = true
+				continue
+			}
+			return nil, nil, &ForwardError{ForwardStageRead, "read response error", http.StatusInternalServerError, err}
+		}
+
+		rmt.SetDeadline(time.Time{})
+		return rmt, resp, nil
+	}
+}
+
+func (h BasicForwardHandler) dial(req *http.Request, network, address string, timeout time.Duration, fresh bool) (*PoolConn, error) {
+	if v, ok := h.fp.(ForwardPoolProvider); ok && !fresh {
+		if pool := v.ForwardPool(); pool != nil {
+			return pool.Get(req.Context(), network, address, timeout)
+		}
+	}
+	return DialConn(network, address, timeout)
+}
+
+// 没有请求体的请求可安全重发
+func (h BasicForwardHandler) canRetry(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.ContentLength == 0
+}
+
 func (h BasicForwardHandler) rewriteRequest(req *http.Request) error {
 	if v, ok := h.fp.(ForwardRewriteRequestProvider); ok {
 		return v.RewriteRequest(req)
@@ -109,6 +238,12 @@ func (h BasicForwardHandler) rewriteRequest(req *http.Request) error {
 	return nil
 }
 
+func (h BasicForwardHandler) report(req *http.Request, err error) {
+	if v, ok := h.fp.(ForwardReportProvider); ok {
+		v.ReportForward(req, err)
+	}
+}
+
 func (h BasicForwardHandler) rewriteResponse(resp *http.Response) error {
 	if v, ok := h.fp.(ForwardRewriteResponseProvider); ok {
 		return v.RewriteResponse(resp)
@@ -134,34 +269,11 @@ func (h BasicForwardHandler) copyHeader(w http.ResponseWriter, header http.Heade
 	}
 }
 
-func (h BasicForwardHandler) transport(src, dst io.ReadWriter) (up, down int64, err error) {
-	var closeCh = make(chan struct{})
-	var errCh = make(chan error)
-
-	go func() {
-		// remote -> local
-		var _err error
-		if down, _err = io.Copy(src, dst); _err != nil && _err != io.EOF {
-			errCh <- _err
-			return
-		}
-		closeCh <- struct{}{}
-	}()
-
-	go func() {
-		// local -> remote
-		var _err error
-		if up, _err = io.Copy(dst, src); _err != nil && _err != io.EOF {
-			errCh <- _err
-			return
+// 响应体之后写入后端的 trailer，如 gRPC 的 grpc-status
+func (h BasicForwardHandler) copyTrailer(w http.ResponseWriter, trailer http.Header) {
+	for k, v := range trailer {
+		for _, vv := range v {
+			w.Header().Add(http.TrailerPrefix+k, vv)
 		}
-		closeCh <- struct{}{}
-	}()
-
-	select {
-	case err = <-errCh:
-		return
-	case <-closeCh:
-		return
 	}
 }
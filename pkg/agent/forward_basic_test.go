@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// 客户端断开时不再读取持续输出的后端响应
+func TestForwardClientDisconnect(t *testing.T) {
+	stop := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for {
+			select {
+			case <-stop:
+				return
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			if _, err := w.Write([]byte("data: ping\n\n")); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer backend.Close()
+	defer close(stop)
+
+	s, addr := serveGateway(t, &EndpointTarget{Network: "tcp", Address: backend.Listener.Addr().String()}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+"/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil || line != "data: ping\n" {
+		t.Fatalf("first event = %q, %v", line, err)
+	}
+	cancel()
+	resp.Body.Close()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer shutdownCancel()
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("shutdown: %v, handler still reading upstream", err)
+	}
+}
@@ -0,0 +1,284 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultConnectTimeout = 10 * time.Second
+
+// CONNECT 目标，host 支持 *.example.com、* 与 CIDR，port 为空匹配任意端口
+type connectAllow struct {
+	host string
+	port string
+	cidr *net.IPNet
+}
+
+func parseConnectAllow(v string) (*connectAllow, error) {
+	host, port, err := net.SplitHostPort(v)
+	if err != nil {
+		// 未指定端口
+		host, port = v, ""
+	}
+	if host == "" {
+		return nil, fmt.Errorf("invalid connect allow %q", v)
+	}
+	allow := &connectAllow{host: host, port: port}
+	if strings.IndexByte(host, '/') >= 0 {
+		_, cidr, err := net.ParseCIDR(host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid connect allow %q: %w", v, err)
+		}
+		allow.cidr = cidr
+	}
+	return allow, nil
+}
+
+func (a *connectAllow) match(host, port string) bool {
+	if a.port != "" && a.port != "*" && a.port != port {
+		return false
+	}
+	if a.cidr != nil {
+		ip := net.ParseIP(host)
+		return ip != nil && a.cidr.Contains(ip)
+	}
+	return MatchHost(host, []string{a.host})
+}
+
+// 匹配目标在允许列表中的 CONNECT 请求
+type connectMatcher struct {
+	allow []*connectAllow
+}
+
+// CONNECT 请求匹配，allow 为允许的目标 host:port
+func NewConnectMatcher(allow []string) (RequestPathMatcher, error) {
+	m := &connectMatcher{}
+	for _, v := range allow {
+		item, err := parseConnectAllow(v)
+		if err != nil {
+			return nil, err
+		}
+		m.allow = append(m.allow, item)
+	}
+	return m, nil
+}
+
+func (m *connectMatcher) MatchPathType() PathType {
+	return PathTypeNone
+}
+
+func (m *connectMatcher) MatchPathPriority() int {
+	return 0
+}
+
+func (m *connectMatcher) MatchRequest(req *http.Request) bool {
+	if req.Method != http.MethodConnect {
+		return false
+	}
+	host, port, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		return false
+	}
+	for _, v := range m.allow {
+		if v.match(host, port) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *connectMatcher) String() string {
+	allow := make([]string, 0, len(m.allow))
+	for _, v := range m.allow {
+		if v.port == "" {
+			allow = append(allow, v.host)
+		} else {
+			allow = append(allow, net.JoinHostPort(v.host, v.port))
+		}
+	}
+	return "CONNECT " + strings.Join(allow, ",")
+}
+
+// CONNECT 隧道，连接请求的目标并双向转发
+type ConnectForwardHandler struct {
+	// 连接目标超时
+	Timeout time.Duration
+}
+
+func NewConnectForwardHandler(timeout time.Duration) *ConnectForwardHandler {
+	if timeout <= 0 {
+		timeout = defaultConnectTimeout
+	}
+	return &ConnectForwardHandler{Timeout: timeout}
+}
+
+func (h *ConnectForwardHandler) HandleRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodConnect {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// HTTP/2 不支持劫持连接，通过请求与响应流转发
+	if req.ProtoMajor == 2 {
+		h.handleStream(w, req)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "attach hijack connection error", http.StatusBadGateway)
+		return
+	}
+
+	remote, err := net.DialTimeout("tcp", req.Host, h.Timeout)
+	if err != nil {
+		printLog("connect %s -> %s dial error: %s\n", RemoteIP(req), req.Host, err.Error())
+		http.Error(w, "dial remote error", http.StatusBadGateway)
+		return
+	}
+	defer remote.Close()
+
+	conn, brw, err := hijacker.Hijack()
+	if err != nil {
+		http.Error(w, "hijack response Error", http.StatusBadGateway)
+		return
+	}
+
+	// 劫持后响应由连接直接写入，出错时只能关闭连接
+	defer conn.Close()
+	defer trackTunnel(req, conn)()
+
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		return
+	}
+
+	runConnectTunnel(req, newBufferedConn(conn, brw.Reader), remote)
+}
+
+func (h *ConnectForwardHandler) handleStream(w http.ResponseWriter, req *http.Request) {
+	remote, err := net.DialTimeout("tcp", req.Host, h.Timeout)
+	if err != nil {
+		printLog("connect %s -> %s dial error: %s\n", RemoteIP(req), req.Host, err.Error())
+		http.Error(w, "dial remote error", http.StatusBadGateway)
+		return
+	}
+	defer remote.Close()
+
+	rc := http.NewResponseController(w)
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	conn := &streamConn{body: req.Body, w: w, rc: rc, remote: streamAddr(req.RemoteAddr)}
+	if addr, ok := req.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		conn.local = addr
+	} else {
+		conn.local = streamAddr("")
+	}
+	defer conn.Close()
+	defer trackTunnel(req, conn)()
+
+	runConnectTunnel(req, conn, remote)
+}
+
+// 转发隧道并记录审计日志
+func runConnectTunnel(req *http.Request, client, remote net.Conn) {
+	start := time.Now()
+	in, out, err := newTunnel(req).Run(client, remote)
+	if err != nil {
+		printLog("connect %s -> %s in=%d out=%d duration=%s error: %s\n", RemoteIP(req), req.Host, in, out, time.Since(start), err.Error())
+		return
+	}
+	printLog("connect %s -> %s in=%d out=%d duration=%s\n", RemoteIP(req), req.Host, in, out, time.Since(start))
+}
+
+// HTTP/2 CONNECT 的请求与响应流，读取请求体，写入响应后立即刷新
+//
+// 不支持半关闭，一端结束后关闭整个隧道；超时由 Tunnel 控制
+type streamConn struct {
+	body io.ReadCloser
+	w    http.ResponseWriter
+	rc   *http.ResponseController
+
+	local  net.Addr
+	remote net.Addr
+
+	mtx     sync.Mutex
+	closed  bool
+	writing bool
+}
+
+func (c *streamConn) Read(b []byte) (int, error) {
+	return c.body.Read(b)
+}
+
+func (c *streamConn) Write(b []byte) (int, error) {
+	c.mtx.Lock()
+	if c.closed {
+		c.mtx.Unlock()
+		return 0, net.ErrClosed
+	}
+	c.writing = true
+	c.mtx.Unlock()
+
+	n, err := c.w.Write(b)
+	if err == nil {
+		err = c.rc.Flush()
+	}
+
+	c.mtx.Lock()
+	c.writing = false
+	c.mtx.Unlock()
+	return n, err
+}
+
+// 关闭请求体使读取返回；写入因流控阻塞时重置流，
+// 否则由处理函数返回时正常结束响应
+func (c *streamConn) Close() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.writing {
+		c.rc.SetWriteDeadline(time.Now())
+	}
+	return c.body.Close()
+}
+
+func (c *streamConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *streamConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *streamConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *streamConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *streamConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+type streamAddr string
+
+func (a streamAddr) Network() string {
+	return "h2"
+}
+
+func (a streamAddr) String() string {
+	return string(a)
+}
@@ -0,0 +1,217 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"runtime"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+func TestConnectMatcher(t *testing.T) {
+	m, err := NewConnectMatcher([]string{"example.com:443", "*.github.com", "10.0.0.0/8:22", "*:8443"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method string
+		host   string
+		want   bool
+	}{
+		{http.MethodConnect, "example.com:443", true},
+		{http.MethodConnect, "EXAMPLE.com:443", true},
+		{http.MethodConnect, "example.com:80", false},
+		{http.MethodConnect, "api.github.com:443", true},
+		{http.MethodConnect, "api.github.com:22", true},
+		{http.MethodConnect, "github.com:443", false},
+		{http.MethodConnect, "10.1.2.3:22", true},
+		{http.MethodConnect, "10.1.2.3:80", false},
+		{http.MethodConnect, "192.168.1.1:22", false},
+		{http.MethodConnect, "any.host:8443", true},
+		{http.MethodConnect, "example.com", false},
+		{http.MethodGet, "example.com:443", false},
+	}
+	for _, tt := range tests {
+		req := &http.Request{Method: tt.method, Host: tt.host}
+		if got := m.MatchRequest(req); got != tt.want {
+			t.Errorf("MatchRequest(%s %s) = %v, want %v", tt.method, tt.host, got, tt.want)
+		}
+	}
+
+	if _, err := NewConnectMatcher([]string{"10.0.0.0/33:22"}); err == nil {
+		t.Error("NewConnectMatcher() invalid cidr error = nil")
+	}
+}
+
+// 要求 Proxy-Authorization 请求头
+type testProxyAuth struct{}
+
+func (testProxyAuth) HandleAuthorizeCheck(w http.ResponseWriter, req *http.Request) bool {
+	if req.Header.Get("Proxy-Authorization") != "Basic dGVzdDp0ZXN0" {
+		w.Header().Set("Proxy-Authenticate", "Basic")
+		w.WriteHeader(http.StatusProxyAuthRequired)
+		return false
+	}
+	return true
+}
+
+func connectRequest(t *testing.T, addr, target, auth string) (net.Conn, *bufio.Reader, int) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := "CONNECT " + target + " HTTP/1.1\r\nHost: " + target + "\r\n"
+	if auth != "" {
+		req += "Proxy-Authorization: " + auth + "\r\n"
+	}
+	conn.Write([]byte(req + "\r\n"))
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, br, resp.StatusCode
+}
+
+// 回显服务
+func serveEcho(t *testing.T) net.Listener {
+	t.Helper()
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { backend.Close() })
+	go func() {
+		for {
+			conn, err := backend.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return backend
+}
+
+// 只允许 CONNECT 到 backend 的网关
+func serveConnectGateway(t *testing.T, backend net.Listener) (*Server, net.Listener) {
+	t.Helper()
+	_, port, _ := net.SplitHostPort(backend.Addr().String())
+	matcher, err := NewConnectMatcher([]string{"127.0.0.1:" + port})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandler()
+	h.Add(NewForwardHandler(matcher, NewConnectForwardHandler(time.Second), testProxyAuth{}))
+	h.Sort()
+
+	s := New()
+	s.Use(h)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go s.Serve(l)
+	return s, l
+}
+
+func TestConnectForward(t *testing.T) {
+	backend := serveEcho(t)
+	s, l := serveConnectGateway(t, backend)
+
+	time.Sleep(20 * time.Millisecond)
+	before := runtime.NumGoroutine()
+
+	// 未鉴权
+	conn, _, status := connectRequest(t, l.Addr().String(), backend.Addr().String(), "")
+	conn.Close()
+	if status != http.StatusProxyAuthRequired {
+		t.Errorf("status = %d, want %d", status, http.StatusProxyAuthRequired)
+	}
+
+	// 目标不在允许列表
+	conn, _, status = connectRequest(t, l.Addr().String(), "127.0.0.1:1", "Basic dGVzdDp0ZXN0")
+	conn.Close()
+	if status != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", status, http.StatusForbidden)
+	}
+
+	for i := 0; i < 5; i++ {
+		conn, br, status := connectRequest(t, l.Addr().String(), backend.Addr().String(), "Basic dGVzdDp0ZXN0")
+		if status != http.StatusOK {
+			t.Fatalf("status = %d, want %d", status, http.StatusOK)
+		}
+		conn.Write([]byte("hello"))
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(br, buf); err != nil || string(buf) != "hello" {
+			t.Fatalf("read %q %v, want hello", buf, err)
+		}
+		conn.Close()
+	}
+
+	for i := 0; i < 100 && s.tunnelCount() > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := s.tunnelCount(); n != 0 {
+		t.Errorf("tunnelCount() = %d, want 0", n)
+	}
+	if stats := s.TunnelStats(); stats.Total() != 5 || stats.BytesIn() != 25 || stats.BytesOut() != 25 {
+		t.Errorf("stats total=%d in=%d out=%d, want 5 25 25", stats.Total(), stats.BytesIn(), stats.BytesOut())
+	}
+	checkGoroutines(t, before)
+}
+
+func TestConnectForwardH2C(t *testing.T) {
+	backend := serveEcho(t)
+	s, l := serveConnectGateway(t, backend)
+
+	tr := &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}
+	defer tr.CloseIdleConnections()
+
+	pr, pw := io.Pipe()
+	req, _ := http.NewRequest(http.MethodConnect, "http://"+l.Addr().String(), pr)
+	req.Host = backend.Addr().String()
+	req.Header.Set("Proxy-Authorization", "Basic dGVzdDp0ZXN0")
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	pw.Write([]byte("hello"))
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil || string(buf) != "hello" {
+		t.Fatalf("read %q %v, want hello", buf, err)
+	}
+
+	// 客户端结束请求流后隧道正常关闭
+	pw.Close()
+	if rest, err := io.ReadAll(resp.Body); err != nil || len(rest) != 0 {
+		t.Errorf("read rest %q %v, want EOF", rest, err)
+	}
+
+	if stats := s.TunnelStats(); stats.Total() != 1 || stats.BytesIn() != 5 || stats.BytesOut() != 5 {
+		t.Errorf("stats total=%d in=%d out=%d, want 1 5 5", stats.Total(), stats.BytesIn(), stats.BytesOut())
+	}
+}
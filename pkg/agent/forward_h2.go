@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	ProtocolHTTP1 = "http1"
+	// HTTP/2 over TLS
+	ProtocolH2 = "h2"
+	// 明文 HTTP/2
+	ProtocolH2C = "h2c"
+)
+
+// 使用 RoundTripper 转发的目标，如 HTTP/2 后端
+type ForwardTransportProvider interface {
+	// 返回 nil 时使用 HTTP/1.1 连接转发
+	ForwardTransport(req *http.Request) (rt http.RoundTripper, scheme, address string)
+}
+
+// 是否使用 HTTP/2 转发
+func (t *EndpointTarget) IsHTTP2() bool {
+	return t.Protocol == ProtocolH2 || t.Protocol == ProtocolH2C
+}
+
+// 目标的 HTTP/2 连接，多个请求复用同一连接
+func (t *EndpointTarget) transport(timeout time.Duration) *http2.Transport {
+	t.h2Once.Do(func() {
+		t.h2 = newHTTP2Transport(t, timeout)
+	})
+	return t.h2
+}
+
+// 关闭空闲的 HTTP/2 连接
+func (t *EndpointTarget) closeIdle() {
+	t.h2Once.Do(func() {})
+	if t.h2 != nil {
+		t.h2.CloseIdleConnections()
+	}
+}
+
+func (t *EndpointTarget) scheme() string {
+	if t.Protocol == ProtocolH2 {
+		return "https"
+	}
+	return "http"
+}
+
+func (t *EndpointTarget) host() string {
+	if t.Network == "unix" {
+		return "localhost"
+	}
+	return t.Address
+}
+
+func newHTTP2Transport(t *EndpointTarget, timeout time.Duration) *http2.Transport {
+	dialer := &net.Dialer{Timeout: timeout}
+	tr := &http2.Transport{}
+	if t.Protocol == ProtocolH2C {
+		tr.AllowHTTP = true
+		tr.DialTLSContext = func(ctx context.Context, _, _ string, _ *tls.Config) (net.Conn, error) {
+			return dialer.DialContext(ctx, t.Network, t.Address)
+		}
+		return tr
+	}
+
+	if t.TLSConfig != nil {
+		tr.TLSClientConfig = t.TLSConfig.Clone()
+	}
+	tr.DialTLSContext = func(ctx context.Context, _, _ string, cfg *tls.Config) (net.Conn, error) {
+		d := &tls.Dialer{NetDialer: dialer, Config: cfg}
+		return d.DialContext(ctx, t.Network, t.Address)
+	}
+	return tr
+}
+
+// 逐跳请求头，不转发到 HTTP/2 后端
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// 构造发往后端的请求
+func newTransportRequest(req *http.Request, scheme, address string) *http.Request {
+	out := req.Clone(req.Context())
+	out.RequestURI = ""
+	out.URL.Scheme = scheme
+	out.URL.Host = address
+	if req.ContentLength == 0 {
+		out.Body = nil
+	}
+
+	if c := out.Header.Get("Connection"); c != "" {
+		for _, v := range strings.Split(c, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				out.Header.Del(v)
+			}
+		}
+	}
+	for _, v := range hopHeaders {
+		out.Header.Del(v)
+	}
+	// HTTP/2 只允许 TE: trailers，gRPC 依赖该请求头
+	if te := out.Header.Get("Te"); te != "" && !strings.EqualFold(te, "trailers") {
+		out.Header.Del("Te")
+	}
+	return out
+}
+
+func (h BasicForwardHandler) forwardTransport(req *http.Request) (http.RoundTripper, string, string) {
+	if v, ok := h.fp.(ForwardTransportProvider); ok {
+		return v.ForwardTransport(req)
+	}
+	return nil, "", ""
+}
+
+// 使用 RoundTripper 发送请求并读取响应头
+func (h BasicForwardHandler) roundTripTransport(req *http.Request, rt http.RoundTripper, scheme, address string) (*http.Response, *ForwardError) {
+	resp, err := rt.RoundTrip(newTransportRequest(req, scheme, address))
+	if err != nil {
+		return nil, &ForwardError{ForwardStageRead, "round trip error", http.StatusBadGateway, err}
+	}
+	return resp, nil
+}
+
+// 流式响应每次写入后立即发送，如 gRPC 与 SSE
+type flushWriter struct {
+	w http.ResponseWriter
+	f http.Flusher
+}
+
+func (w flushWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	if n > 0 {
+		w.f.Flush()
+	}
+	return n, err
+}
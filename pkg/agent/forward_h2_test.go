@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// 返回请求协议与 trailer 的后端
+func trailerBackend() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Connection") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("X-Proto", r.Proto)
+		w.Header().Set("X-Te", r.Header.Get("Te"))
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+		w.(http.Flusher).Flush()
+		w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", "ok")
+	})
+}
+
+func serveGateway(t *testing.T, target *EndpointTarget, config *tls.Config) (*Server, string) {
+	t.Helper()
+	upstream := NewUpstream([]*EndpointTarget{target}, time.Second)
+	t.Cleanup(upstream.Stop)
+
+	h := NewHandler()
+	h.Add(NewForwardHandler(NewRequestPathMatcher("/"), NewStaticForwardHandler(upstream), nil))
+	h.Sort()
+
+	s := New()
+	s.Use(h)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config != nil {
+		go s.ServeTLS(l, config)
+	} else {
+		go s.Serve(l)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	})
+	return s, l.Addr().String()
+}
+
+func checkTrailerResponse(t *testing.T, resp *http.Response, wantProto string) {
+	t.Helper()
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if string(body) != "ping" {
+		t.Errorf("body = %q, want ping", body)
+	}
+	if got := resp.Header.Get("X-Proto"); got != wantProto {
+		t.Errorf("upstream proto = %s, want %s", got, wantProto)
+	}
+	if got := resp.Trailer.Get("Grpc-Status"); got != "0" {
+		t.Errorf("trailer grpc-status = %q, want 0", got)
+	}
+	if got := resp.Trailer.Get("Grpc-Message"); got != "ok" {
+		t.Errorf("trailer grpc-message = %q, want ok", got)
+	}
+}
+
+func TestForwardH2C(t *testing.T) {
+	backend := httptest.NewServer(h2c.NewHandler(trailerBackend(), &http2.Server{}))
+	defer backend.Close()
+
+	_, addr := serveGateway(t, &EndpointTarget{Network: "tcp", Address: backend.Listener.Addr().String(), Protocol: ProtocolH2C}, nil)
+
+	// 客户端使用 h2c 连接网关
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/pkg.Service/Method", strings.NewReader("ping"))
+	req.Header.Set("Te", "trailers")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ProtoMajor != 2 {
+		t.Errorf("client proto = %s, want HTTP/2.0", resp.Proto)
+	}
+	if got := resp.Header.Get("X-Te"); got != "trailers" {
+		t.Errorf("upstream te = %q, want trailers", got)
+	}
+	checkTrailerResponse(t, resp, "HTTP/2.0")
+
+	// HTTP/1.1 客户端同样收到 trailer
+	req, _ = http.NewRequest(http.MethodPost, "http://"+addr+"/pkg.Service/Method", strings.NewReader("ping"))
+	req.Header.Set("Connection", "keep-alive")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ProtoMajor != 1 {
+		t.Errorf("client proto = %s, want HTTP/1.1", resp.Proto)
+	}
+	checkTrailerResponse(t, resp, "HTTP/2.0")
+}
+
+func TestForwardH2(t *testing.T) {
+	backend := httptest.NewUnstartedServer(trailerBackend())
+	backend.EnableHTTP2 = true
+	backend.StartTLS()
+	defer backend.Close()
+
+	target := &EndpointTarget{
+		Network:   "tcp",
+		Address:   backend.Listener.Addr().String(),
+		Protocol:  ProtocolH2,
+		TLSConfig: &tls.Config{RootCAs: backend.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs},
+	}
+	_, addr := serveGateway(t, target, &tls.Config{Certificates: backend.TLS.Certificates})
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+	req, _ := http.NewRequest(http.MethodPost, "https://"+addr+"/pkg.Service/Method", strings.NewReader("ping"))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ProtoMajor != 2 {
+		t.Errorf("client proto = %s, want HTTP/2.0", resp.Proto)
+	}
+	checkTrailerResponse(t, resp, "HTTP/2.0")
+}
+
+func TestForwardHTTP1Trailer(t *testing.T) {
+	backend := httptest.NewServer(trailerBackend())
+	defer backend.Close()
+
+	_, addr := serveGateway(t, &EndpointTarget{Network: "tcp", Address: backend.Listener.Addr().String()}, nil)
+
+	req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/", strings.NewReader("ping"))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTrailerResponse(t, resp, "HTTP/1.1")
+}
+
+func TestServerShutdownH2C(t *testing.T) {
+	backend := httptest.NewServer(trailerBackend())
+	defer backend.Close()
+
+	s, addr := serveGateway(t, &EndpointTarget{Network: "tcp", Address: backend.Listener.Addr().String()}, nil)
+
+	tr := &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}
+	req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/", strings.NewReader("ping"))
+	resp, err := (&http.Client{Transport: tr}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTrailerResponse(t, resp, "HTTP/1.1")
+
+	if n := s.tunnelCount(); n != 1 {
+		t.Errorf("tunnelCount() = %d, want 1", n)
+	}
+
+	// 停止服务时通知客户端关闭空闲的 h2c 连接
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	tr.CloseIdleConnections()
+}
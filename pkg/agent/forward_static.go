@@ -1,39 +1,185 @@
 package agent
 
 import (
-	"math/rand"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
 	"time"
 )
 
 type StaticForwardHandler struct {
 	*BasicForwardHandler
-	targets []*EndpointTarget
-	timeout int
+	upstream *Upstream
+	rewrite  []ForwardRewriteRequestProvider
+	modify   []ForwardRewriteResponseProvider
 }
 
-type EndpointTarget struct {
-	Network string
-	Address string
-}
-
-func NewStaticForwardHandler(targets []*EndpointTarget, timeout int) *StaticForwardHandler {
+func NewStaticForwardHandler(upstream *Upstream) *StaticForwardHandler {
 	h := new(StaticForwardHandler)
 	h.BasicForwardHandler = NewBasicForwardHandler(h)
-	h.timeout = timeout
-	h.targets = targets
+	h.upstream = upstream
 	return h
 }
 
-func (h *StaticForwardHandler) ForwardTarget() (network, address string, timeout time.Duration) {
-	n := len(h.targets)
-	i := intn(n)
-	network = h.targets[i].Network
-	address = h.targets[i].Address
-	timeout = time.Duration(h.timeout) * time.Millisecond
+func (h *StaticForwardHandler) HandleRequest(w http.ResponseWriter, req *http.Request) {
+	if h.upstream.Retry.enabled(req) {
+		h.handleRetry(w, req)
+		return
+	}
+
+	target := h.upstream.Select(w, req)
+	if target == nil {
+		http.Error(w, "no available endpoint", http.StatusBadGateway)
+		return
+	}
+
+	atomic.AddInt64(&target.conns, 1)
+	defer atomic.AddInt64(&target.conns, -1)
+
+	h.BasicForwardHandler.HandleRequest(w, withTarget(req, target))
+}
+
+// 失败时切换目标重试
+func (h *StaticForwardHandler) handleRetry(w http.ResponseWriter, req *http.Request) {
+	policy := h.upstream.Retry
+
+	body, ok, err := bufferBody(req, policy.maxBodySize())
+	if err != nil {
+		http.Error(w, "read request error: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	maxAttempts := policy.MaxAttempts
+	// 请求体过大无法重放
+	if !ok {
+		maxAttempts = 1
+	}
+
+	if err := h.rewriteRequest(req); err != nil {
+		http.Error(w, "write request error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	tried := []*EndpointTarget{}
+	for attempt := 1; ; attempt++ {
+		target := h.upstream.SelectExclude(req, tried)
+		if target == nil {
+			http.Error(w, "no available endpoint", http.StatusBadGateway)
+			return
+		}
+		tried = append(tried, target)
+		last := attempt >= maxAttempts
+
+		if h.tryForward(w, req, target, body, ok, last) {
+			return
+		}
+	}
+}
+
+// 单次转发尝试，返回 false 表示需要重试
+func (h *StaticForwardHandler) tryForward(w http.ResponseWriter, req *http.Request, target *EndpointTarget, body []byte, replay, last bool) bool {
+	policy := h.upstream.Retry
+
+	atomic.AddInt64(&target.conns, 1)
+	defer atomic.AddInt64(&target.conns, -1)
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	// 单次尝试超时只限制到读取响应头，流式响应不受影响
+	stop := func() {}
+	if policy.PerTryTimeout > 0 {
+		ctx = newTryContext(ctx, policy.PerTryTimeout)
+		timer := time.AfterFunc(policy.PerTryTimeout, cancel)
+		stop = func() { timer.Stop() }
+	}
+
+	r := withTarget(req.Clone(ctx), target)
+	if replay && body != nil {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	rmt, resp, ferr := h.Forward(r)
+	stop()
+	if ferr != nil {
+		if !last && policy.retryError(ferr) {
+			return false
+		}
+		http.Error(w, ferr.Error(), ferr.Status)
+		return true
+	}
+
+	if !last && policy.retryStatus(resp.StatusCode) {
+		// 先关闭连接，Body.Close 会读取剩余响应，后端持续输出时无法返回
+		if rmt != nil {
+			rmt.Close()
+		}
+		resp.Body.Close()
+		return false
+	}
+
+	h.upstream.Stick(w, req, target)
+	h.WriteResponse(w, r, rmt, resp)
+	return true
+}
+
+func (h *StaticForwardHandler) ForwardTarget(req *http.Request) (network, address string, timeout time.Duration) {
+	target := TargetFrom(req)
+	if target == nil {
+		target = h.upstream.Balancer.Select(req, h.upstream.available())
+	}
+	if target == nil {
+		return
+	}
+	network = target.Network
+	address = target.Address
+	timeout = h.upstream.Timeout
 	return
 }
 
-func intn(v int) int {
-	rd := rand.New(rand.NewSource(time.Now().UnixNano()))
-	return rd.Intn(v)
+// HTTP/2 目标使用目标的连接转发
+func (h *StaticForwardHandler) ForwardTransport(req *http.Request) (http.RoundTripper, string, string) {
+	target := TargetFrom(req)
+	if target == nil || !target.IsHTTP2() {
+		return nil, "", ""
+	}
+	return target.transport(h.upstream.Timeout), target.scheme(), target.host()
+}
+
+func (h *StaticForwardHandler) ForwardPool() *ConnPool {
+	return h.upstream.Pool
+}
+
+func (h *StaticForwardHandler) ReportForward(req *http.Request, err error) {
+	h.upstream.Report(TargetFrom(req), err)
+}
+
+// 添加请求重写，按添加顺序执行
+func (h *StaticForwardHandler) AddRequestRewrite(rewrite ForwardRewriteRequestProvider) {
+	h.rewrite = append(h.rewrite, rewrite)
+}
+
+func (h *StaticForwardHandler) RewriteRequest(req *http.Request) error {
+	for _, v := range h.rewrite {
+		if err := v.RewriteRequest(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 添加响应重写，按添加顺序执行
+func (h *StaticForwardHandler) AddResponseRewrite(modify ForwardRewriteResponseProvider) {
+	h.modify = append(h.modify, modify)
+}
+
+func (h *StaticForwardHandler) RewriteResponse(resp *http.Response) error {
+	for _, v := range h.modify {
+		if err := v.RewriteResponse(resp); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,324 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	grpcContentType        = "application/grpc"
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text"
+)
+
+// gRPC 状态码
+const (
+	GRPCStatusOK               = 0
+	GRPCStatusUnknown          = 2
+	GRPCStatusPermissionDenied = 7
+	GRPCStatusUnimplemented    = 12
+	GRPCStatusInternal         = 13
+	GRPCStatusUnavailable      = 14
+	GRPCStatusUnauthenticated  = 16
+)
+
+// 是否为 gRPC 或 gRPC-Web 请求
+func IsGRPC(req *http.Request) bool {
+	return strings.HasPrefix(req.Header.Get("Content-Type"), grpcContentType)
+}
+
+func isGRPCWeb(req *http.Request) bool {
+	return strings.HasPrefix(req.Header.Get("Content-Type"), grpcWebContentType)
+}
+
+func isGRPCWebText(req *http.Request) bool {
+	return strings.HasPrefix(req.Header.Get("Content-Type"), grpcWebTextContentType)
+}
+
+// HTTP 状态码对应的 gRPC 状态码
+// https://github.com/grpc/grpc/blob/master/doc/http-grpc-status-mapping.md
+func GRPCStatusFromHTTP(status int) int {
+	switch status {
+	case http.StatusOK:
+		return GRPCStatusOK
+	case http.StatusBadRequest:
+		return GRPCStatusInternal
+	case http.StatusUnauthorized, http.StatusProxyAuthRequired:
+		return GRPCStatusUnauthenticated
+	case http.StatusForbidden:
+		return GRPCStatusPermissionDenied
+	case http.StatusNotFound:
+		return GRPCStatusUnimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return GRPCStatusUnavailable
+	}
+	return GRPCStatusUnknown
+}
+
+// gRPC 服务与方法匹配，pattern 为 pkg.Service/Method，
+// 省略方法或方法为 * 时匹配服务的全部方法
+type grpcMatcher struct {
+	pathMatcher
+}
+
+func NewGRPCMatcher(pattern string) (RequestPathMatcher, error) {
+	pattern = strings.TrimPrefix(pattern, "/")
+	service, method, _ := strings.Cut(pattern, "/")
+	if service == "" || strings.ContainsAny(service, "*{}") || strings.ContainsAny(method, "/{}") {
+		return nil, fmt.Errorf("invalid grpc pattern %q", pattern)
+	}
+	if method == "" || method == "*" {
+		return &grpcMatcher{pathMatcher{path: "/" + service + "/", typ: PathTypePrefix}}, nil
+	}
+	return &grpcMatcher{pathMatcher{path: "/" + service + "/" + method, typ: PathTypeFull}}, nil
+}
+
+func (m *grpcMatcher) MatchRequest(req *http.Request) bool {
+	return req.Method == http.MethodPost && IsGRPC(req) && m.pathMatcher.MatchRequest(req)
+}
+
+func (m *grpcMatcher) String() string {
+	return "grpc " + m.path
+}
+
+// gRPC 请求的错误响应转换为 grpc-status，客户端无法解析 HTTP 错误页
+type grpcErrorWriter struct {
+	http.ResponseWriter
+	contentType string
+	// 转换的错误状态，为 0 时直接写入
+	status  int
+	message bytes.Buffer
+	wrote   bool
+}
+
+func newGRPCErrorWriter(w http.ResponseWriter, req *http.Request) *grpcErrorWriter {
+	contentType := grpcContentType
+	if isGRPCWebText(req) {
+		contentType = grpcWebTextContentType
+	} else if isGRPCWeb(req) {
+		contentType = grpcWebContentType
+	}
+	return &grpcErrorWriter{ResponseWriter: w, contentType: contentType}
+}
+
+func (w *grpcErrorWriter) WriteHeader(status int) {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+	if status == http.StatusOK || strings.HasPrefix(w.Header().Get("Content-Type"), grpcContentType) {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+}
+
+func (w *grpcErrorWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.status != 0 {
+		// 错误信息作为 grpc-message
+		return w.message.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *grpcErrorWriter) Flush() {
+	if w.status != 0 {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// 写入转换的错误，只有响应头的 gRPC 响应
+func (w *grpcErrorWriter) finish() {
+	if w.status == 0 {
+		return
+	}
+	header := w.Header()
+	header.Del("Content-Length")
+	header.Del("X-Content-Type-Options")
+	header.Set("Content-Type", w.contentType)
+	header.Set("Grpc-Status", strconv.Itoa(GRPCStatusFromHTTP(w.status)))
+	if msg := strings.TrimSpace(w.message.String()); msg != "" {
+		header.Set("Grpc-Message", encodeGRPCMessage(msg))
+	}
+	w.ResponseWriter.WriteHeader(http.StatusOK)
+}
+
+// grpc-message 百分号编码
+func encodeGRPCMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// gRPC-Web 转换为 gRPC 转发，响应的 trailer 编码到响应体
+//
+// https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-WEB.md
+type GRPCWebHandler struct {
+	next RequestForwardHandler
+}
+
+func NewGRPCWebHandler(next RequestForwardHandler) *GRPCWebHandler {
+	return &GRPCWebHandler{next: next}
+}
+
+func (h *GRPCWebHandler) HandleRequest(w http.ResponseWriter, req *http.Request) {
+	if !isGRPCWeb(req) {
+		h.next.HandleRequest(w, req)
+		return
+	}
+
+	text := isGRPCWebText(req)
+	contentType := req.Header.Get("Content-Type")
+
+	r := req.Clone(req.Context())
+	r.Header.Set("Content-Type", grpcContentType+strings.TrimPrefix(strings.TrimPrefix(contentType, grpcWebTextContentType), grpcWebContentType))
+	r.Header.Set("Te", "trailers")
+	r.Header.Del("Content-Length")
+	if text {
+		r.Body = io.NopCloser(base64.NewDecoder(base64.StdEncoding, req.Body))
+		r.ContentLength = -1
+	}
+
+	gw := &grpcWebWriter{w: w, header: http.Header{}, contentType: contentType, text: text}
+	h.next.HandleRequest(gw, r)
+	gw.finish()
+}
+
+type grpcWebWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	contentType string
+	text        bool
+	wrote       bool
+	// 是否为 gRPC 响应，转发错误等非 gRPC 响应直接写入
+	grpc bool
+}
+
+func (w *grpcWebWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *grpcWebWriter) WriteHeader(status int) {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+	w.grpc = strings.HasPrefix(w.header.Get("Content-Type"), grpcContentType)
+
+	dst := w.w.Header()
+	for k, v := range w.header {
+		if strings.HasPrefix(k, http.TrailerPrefix) || k == "Trailer" {
+			continue
+		}
+		dst[k] = v
+	}
+	if w.grpc {
+		dst.Set("Content-Type", w.contentType)
+		dst.Del("Content-Length")
+	}
+	w.w.WriteHeader(status)
+}
+
+func (w *grpcWebWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.grpc || !w.text {
+		return w.w.Write(b)
+	}
+	if _, err := w.w.Write([]byte(base64.StdEncoding.EncodeToString(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *grpcWebWriter) Flush() {
+	if f, ok := w.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// 写入 trailer 帧
+func (w *grpcWebWriter) finish() {
+	if !w.wrote || !w.grpc {
+		return
+	}
+
+	trailer := http.Header{}
+	for k, v := range w.header {
+		if name, ok := strings.CutPrefix(k, http.TrailerPrefix); ok {
+			trailer[http.CanonicalHeaderKey(name)] = v
+		}
+	}
+	for _, k := range w.header.Values("Trailer") {
+		for _, name := range strings.Split(k, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if v, ok := w.header[name]; ok {
+				trailer[name] = v
+			}
+		}
+	}
+	if len(trailer) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(trailer))
+	for k := range trailer {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var body bytes.Buffer
+	for _, k := range keys {
+		for _, v := range trailer[k] {
+			body.WriteString(strings.ToLower(k) + ": " + v + "\r\n")
+		}
+	}
+
+	frame := make([]byte, 5, 5+body.Len())
+	frame[0] = 0x80
+	binary.BigEndian.PutUint32(frame[1:], uint32(body.Len()))
+	frame = append(frame, body.Bytes()...)
+	w.Write(frame)
+}
+
+// 解析 gRPC-Web 响应体中的 trailer 帧，用于测试与调试
+func parseGRPCWebTrailer(b []byte) (http.Header, bool) {
+	for len(b) >= 5 {
+		n := int(binary.BigEndian.Uint32(b[1:5]))
+		if len(b) < 5+n {
+			return nil, false
+		}
+		if b[0]&0x80 != 0 {
+			header := http.Header{}
+			for _, line := range strings.Split(string(b[5:5+n]), "\r\n") {
+				if k, v, ok := strings.Cut(line, ":"); ok {
+					header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+				}
+			}
+			return header, true
+		}
+		b = b[5+n:]
+	}
+	return nil, false
+}
@@ -0,0 +1,218 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestGRPCMatcher(t *testing.T) {
+	tests := []struct {
+		pattern     string
+		contentType string
+		path        string
+		want        bool
+	}{
+		{"pkg.Service/Method", "application/grpc", "/pkg.Service/Method", true},
+		{"/pkg.Service/Method", "application/grpc+proto", "/pkg.Service/Method", true},
+		{"pkg.Service/Method", "application/grpc", "/pkg.Service/Other", false},
+		{"pkg.Service/Method", "application/json", "/pkg.Service/Method", false},
+		{"pkg.Service", "application/grpc", "/pkg.Service/Other", true},
+		{"pkg.Service/*", "application/grpc-web", "/pkg.Service/Other", true},
+		{"pkg.Service", "application/grpc", "/pkg.ServiceV2/Other", false},
+	}
+	for _, tt := range tests {
+		m, err := NewGRPCMatcher(tt.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+		req.Header.Set("Content-Type", tt.contentType)
+		if got := m.MatchRequest(req); got != tt.want {
+			t.Errorf("%s MatchRequest(%s %s) = %v, want %v", tt.pattern, tt.contentType, tt.path, got, tt.want)
+		}
+	}
+
+	for _, v := range []string{"", "/", "pkg.*/Method", "pkg.Service/a/b"} {
+		if _, err := NewGRPCMatcher(v); err == nil {
+			t.Errorf("NewGRPCMatcher(%q) error = nil", v)
+		}
+	}
+}
+
+func TestGRPCRouteIndex(t *testing.T) {
+	h := NewHandler()
+	exact, _ := NewGRPCMatcher("pkg.Service/Method")
+	service, _ := NewGRPCMatcher("pkg.Service")
+	for _, m := range []RequestPathMatcher{exact, service} {
+		b := NewBasicMatcher()
+		b.Path = m
+		h.Add(NewForwardHandler(b, nil, nil))
+	}
+	h.Sort()
+
+	if len(h.router.always) != 0 {
+		t.Errorf("always = %v, want grpc routes indexed", h.router.always)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/pkg.Service/Method", nil)
+	req.Header.Set("Content-Type", "application/grpc")
+	if got := h.Match(req); got == nil || got.MatchPathType() != PathTypeFull {
+		t.Errorf("Match() = %v, want exact method route", got)
+	}
+}
+
+func TestGRPCErrorStatus(t *testing.T) {
+	// 后端不可用
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	m, _ := NewGRPCMatcher("pkg.Service")
+	upstream := NewUpstream([]*EndpointTarget{{Network: "tcp", Address: addr}}, time.Second)
+	defer upstream.Stop()
+
+	h := NewHandler()
+	h.Add(NewForwardHandler(m, NewStaticForwardHandler(upstream), nil))
+	h.Sort()
+
+	tests := []struct {
+		path        string
+		contentType string
+		status      string
+	}{
+		{"/pkg.Service/Method", "application/grpc", "14"},
+		{"/other.Service/Method", "application/grpc", "12"},
+		{"/other.Service/Method", "application/grpc-web-text", "12"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader("ping"))
+		req.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s status = %d, want 200", tt.path, w.Code)
+		}
+		if got := w.Header().Get("Content-Type"); got != tt.contentType {
+			t.Errorf("%s content-type = %q, want %q", tt.path, got, tt.contentType)
+		}
+		if got := w.Header().Get("Grpc-Status"); got != tt.status {
+			t.Errorf("%s grpc-status = %q, want %s", tt.path, got, tt.status)
+		}
+		if w.Header().Get("Grpc-Message") == "" {
+			t.Errorf("%s grpc-message is empty", tt.path)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("%s body = %q, want empty", tt.path, w.Body.String())
+		}
+	}
+
+	// 非 gRPC 请求保持原有错误响应
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/other", nil))
+	if w.Code != http.StatusNotFound || w.Header().Get("Grpc-Status") != "" {
+		t.Errorf("status = %d grpc-status = %q, want 404 without grpc-status", w.Code, w.Header().Get("Grpc-Status"))
+	}
+}
+
+func TestGRPCMessageEncode(t *testing.T) {
+	if got := encodeGRPCMessage("a 100% 错"); got != "a 100%25 %E9%94%99" {
+		t.Errorf("encodeGRPCMessage() = %q", got)
+	}
+}
+
+func TestGRPCWeb(t *testing.T) {
+	backend := httptest.NewServer(h2c.NewHandler(trailerBackend(), &http2.Server{}))
+	defer backend.Close()
+
+	upstream := NewUpstream([]*EndpointTarget{{Network: "tcp", Address: backend.Listener.Addr().String(), Protocol: ProtocolH2C}}, time.Second)
+	defer upstream.Stop()
+
+	m, _ := NewGRPCMatcher("pkg.Service/Method")
+	h := NewHandler()
+	h.Add(NewForwardHandler(m, NewGRPCWebHandler(NewStaticForwardHandler(upstream)), nil))
+	h.Sort()
+
+	s := New()
+	s.Use(h)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	}()
+
+	call := func(contentType, body string) (*http.Response, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, "http://"+l.Addr().String()+"/pkg.Service/Method", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, want 200", resp.StatusCode)
+		}
+		if got := resp.Header.Get("Content-Type"); got != contentType {
+			t.Errorf("content-type = %q, want %q", got, contentType)
+		}
+		if got := resp.Header.Get("X-Te"); got != "trailers" {
+			t.Errorf("upstream te = %q, want trailers", got)
+		}
+		return resp, b
+	}
+
+	checkTrailer := func(frame []byte) {
+		t.Helper()
+		trailer, ok := parseGRPCWebTrailer(frame)
+		if !ok {
+			t.Fatalf("trailer frame not found in %q", frame)
+		}
+		if got := trailer.Get("Grpc-Status"); got != "0" {
+			t.Errorf("trailer grpc-status = %q, want 0", got)
+		}
+		if got := trailer.Get("Grpc-Message"); got != "ok" {
+			t.Errorf("trailer grpc-message = %q, want ok", got)
+		}
+	}
+
+	// 二进制模式
+	_, body := call("application/grpc-web+proto", "ping")
+	if !strings.HasPrefix(string(body), "ping") {
+		t.Fatalf("body = %q, want ping prefix", body)
+	}
+	checkTrailer(body[len("ping"):])
+
+	// 文本模式，请求与响应均为 base64 编码，每次写入单独编码
+	encoded := base64.StdEncoding.EncodeToString([]byte("ping"))
+	_, body = call("application/grpc-web-text", encoded)
+	if !strings.HasPrefix(string(body), encoded) {
+		t.Fatalf("body = %q, want %q prefix", body, encoded)
+	}
+	frame, err := base64.StdEncoding.DecodeString(string(body[len(encoded):]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTrailer(frame)
+}
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"fmt"
 	"net/http"
 	"sort"
 )
@@ -15,6 +16,11 @@ type RequestPathMatcher interface {
 	MatchPathPriority() int
 }
 
+// 按域名匹配的规则，优先级高的先匹配
+type RequestHostMatcher interface {
+	MatchHostPriority() int
+}
+
 type RequestForwardHandler interface {
 	HandleRequest(w http.ResponseWriter, req *http.Request)
 }
@@ -29,7 +35,8 @@ type ForwardHandler interface {
 }
 
 type Handler struct {
-	items []ForwardHandler
+	items  []ForwardHandler
+	router *router
 }
 
 func NewHandler() *Handler {
@@ -40,44 +47,83 @@ func NewHandler() *Handler {
 
 func (h *Handler) Add(item ForwardHandler) {
 	h.items = append(h.items, item)
+	h.router = nil
 }
 
+// 按优先级排序并建立路由索引，需在添加全部规则后调用
 func (h *Handler) Sort() {
 	sort.Slice(h.items, func(i, j int) bool {
+		if hi, hj := matchHostPriority(h.items[i]), matchHostPriority(h.items[j]); hi != hj {
+			return hi > hj
+		}
 		if h.items[i].MatchPathType() == h.items[j].MatchPathType() {
 			return h.items[i].MatchPathPriority() > h.items[j].MatchPathPriority()
 		}
 		return h.items[i].MatchPathType() < h.items[j].MatchPathType()
 	})
-	for _, v := range h.items {
-		printLog("rule %v\n", v)
-	}
+	h.router = newRouter(h.items)
 }
 
-func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+// 查找匹配请求的规则，无匹配返回 nil
+func (h *Handler) Match(req *http.Request) ForwardHandler {
+	if h.router != nil {
+		for _, i := range h.router.candidates(req) {
+			if h.items[i].MatchRequest(req) {
+				return h.items[i]
+			}
+		}
+		return nil
+	}
+
 	for _, item := range h.items {
-		printLog("match test %v\n", item)
-		// 匹配请求
 		if item.MatchRequest(req) {
-			// 进行权限校验
-			if auth, ok := item.(AuthorizeHandler); ok {
-				if !auth.HandleAuthorizeCheck(w, req) {
-					return
-				}
-			}
-			// 校验通过
-			printLog("match %v\n", item)
-			item.HandleRequest(w, req)
+			return item
+		}
+	}
+	return nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// gRPC 客户端只识别 grpc-status
+	if IsGRPC(req) {
+		gw := newGRPCErrorWriter(w, req)
+		defer gw.finish()
+		w = gw
+	}
+
+	// 匹配请求
+	item := h.Match(req)
+	if item == nil {
+		// CONNECT 目标不在任何路由的允许列表中
+		if req.Method == http.MethodConnect {
+			printLog("connect %s -> %s denied\n", RemoteIP(req), req.Host)
+			http.Error(w, "connect destination not allowed", http.StatusForbidden)
 			return
 		}
+		// 无匹配路由
+		http.NotFound(w, req)
+		return
+	}
+
+	// 跳转在鉴权前执行
+	if r, ok := item.(RequestRedirectHandler); ok && r.HandleRedirect(w, req) {
+		return
 	}
 
-	// 无匹配路由
-	http.NotFound(w, req)
+	// 进行权限校验
+	if auth, ok := item.(AuthorizeHandler); ok {
+		if !auth.HandleAuthorizeCheck(w, req) {
+			return
+		}
+	}
+	// 校验通过
+	item.HandleRequest(w, req)
 }
 
 type forwardItem struct {
 	auth AuthorizeHandler
+	// 明文请求跳转到 HTTPS
+	httpsRedirect bool
 	RequestPathMatcher
 	RequestForwardHandler
 }
@@ -86,6 +132,25 @@ func NewForwardHandler(matcher RequestPathMatcher, forward RequestForwardHandler
 	return &forwardItem{RequestPathMatcher: matcher, RequestForwardHandler: forward, auth: auth}
 }
 
+func (item forwardItem) String() string {
+	return fmt.Sprint(item.RequestPathMatcher)
+}
+
+func (item forwardItem) MatchHostPriority() int {
+	return matchHostPriority(item.RequestPathMatcher)
+}
+
+func (item forwardItem) MatchRoute() ([]string, string, bool) {
+	return matchRoute(item.RequestPathMatcher)
+}
+
+func matchHostPriority(m interface{}) int {
+	if h, ok := m.(RequestHostMatcher); ok {
+		return h.MatchHostPriority()
+	}
+	return 0
+}
+
 func (item forwardItem) HandleAuthorizeCheck(w http.ResponseWriter, req *http.Request) bool {
 	if item.auth != nil {
 		return item.auth.HandleAuthorizeCheck(w, req)
@@ -0,0 +1,284 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	HealthCheckTCP  = "tcp"
+	HealthCheckHTTP = "http"
+)
+
+const (
+	TargetStatusHealthy   = "healthy"
+	TargetStatusUnhealthy = "unhealthy"
+	TargetStatusEjected   = "ejected"
+)
+
+// 主动健康检查
+type HealthCheck struct {
+	Type     string
+	Interval time.Duration
+	Timeout  time.Duration
+	// HTTP 检查路径
+	Path string
+	// HTTP 期望状态码，为空时 2xx/3xx 视为健康
+	ExpectStatus []int
+	// 连续成功次数达到后恢复
+	HealthyThreshold int
+	// 连续失败次数达到后标记不健康
+	UnhealthyThreshold int
+}
+
+// 被动健康检查
+type PassiveCheck struct {
+	// 连续失败次数达到后摘除
+	MaxFails int
+	// 摘除时长
+	FailTimeout time.Duration
+}
+
+type targetHealth struct {
+	mtx sync.Mutex
+	// 主动检查判定不健康
+	down      bool
+	successes int
+	failures  int
+	// 被动检查连续失败次数
+	fails      int
+	ejectUntil time.Time
+	lastError  string
+	checkedAt  time.Time
+}
+
+// 目标健康状态
+type TargetHealth struct {
+	Status    string
+	Fails     int
+	LastError string
+	CheckedAt time.Time
+}
+
+func (t *EndpointTarget) Health() *TargetHealth {
+	h := &t.health
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	status := TargetStatusHealthy
+	if h.down {
+		status = TargetStatusUnhealthy
+	} else if time.Now().Before(h.ejectUntil) {
+		status = TargetStatusEjected
+	}
+
+	return &TargetHealth{Status: status, Fails: h.fails, LastError: h.lastError, CheckedAt: h.checkedAt}
+}
+
+// 目标是否可用
+func (t *EndpointTarget) Available() bool {
+	h := &t.health
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return !h.down && !time.Now().Before(h.ejectUntil)
+}
+
+func (t *EndpointTarget) reportCheck(check *HealthCheck, err error) {
+	h := &t.health
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.checkedAt = time.Now()
+	if err != nil {
+		h.lastError = err.Error()
+		h.successes = 0
+		h.failures++
+		if h.failures >= threshold(check.UnhealthyThreshold) {
+			h.down = true
+		}
+		return
+	}
+
+	h.failures = 0
+	h.successes++
+	if h.successes >= threshold(check.HealthyThreshold) {
+		h.down = false
+	}
+}
+
+func (t *EndpointTarget) reportForward(check *PassiveCheck, err error) {
+	h := &t.health
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if err == nil {
+		h.fails = 0
+		return
+	}
+
+	h.lastError = err.Error()
+	h.fails++
+	if h.fails >= threshold(check.MaxFails) {
+		h.fails = 0
+		h.ejectUntil = time.Now().Add(check.FailTimeout)
+	}
+}
+
+func threshold(v int) int {
+	if v <= 0 {
+		return 1
+	}
+	return v
+}
+
+// 可用目标列表，全部不可用时返回全部目标
+func (u *Upstream) available() []*EndpointTarget {
+	if u.HealthCheck == nil && u.Passive == nil {
+		return u.Targets
+	}
+
+	targets := make([]*EndpointTarget, 0, len(u.Targets))
+	for _, t := range u.Targets {
+		if t.Available() {
+			targets = append(targets, t)
+		}
+	}
+
+	if len(targets) == 0 {
+		return u.Targets
+	}
+	return targets
+}
+
+// 上报转发结果，用于被动健康检查
+func (u *Upstream) Report(target *EndpointTarget, err error) {
+	if u.Passive == nil || target == nil {
+		return
+	}
+	target.reportForward(u.Passive, err)
+}
+
+// 启动主动健康检查
+func (u *Upstream) Start() {
+	if u.HealthCheck == nil || u.HealthCheck.Interval <= 0 {
+		return
+	}
+
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	if u.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	u.cancel = cancel
+
+	for _, t := range u.Targets {
+		go u.runCheck(ctx, t)
+	}
+}
+
+// 停止主动健康检查并关闭连接池
+func (u *Upstream) Stop() {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	if u.cancel != nil {
+		u.cancel()
+		u.cancel = nil
+	}
+	if u.Pool != nil {
+		u.Pool.Close()
+	}
+	for _, t := range u.Targets {
+		t.closeIdle()
+	}
+}
+
+func (u *Upstream) runCheck(ctx context.Context, target *EndpointTarget) {
+	ticker := time.NewTicker(u.HealthCheck.Interval)
+	defer ticker.Stop()
+
+	for {
+		target.reportCheck(u.HealthCheck, CheckTarget(ctx, u.HealthCheck, target))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// 检查目标是否健康
+func CheckTarget(ctx context.Context, check *HealthCheck, target *EndpointTarget) error {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = check.Interval
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dialer := &net.Dialer{}
+
+	switch check.Type {
+	case HealthCheckHTTP:
+		var transport http.RoundTripper = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, target.Network, target.Address)
+			},
+			DisableKeepAlives: true,
+		}
+		if target.IsHTTP2() {
+			tr := newHTTP2Transport(target, timeout)
+			defer tr.CloseIdleConnections()
+			transport = tr
+		}
+
+		client := &http.Client{
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.scheme()+"://"+target.host()+check.Path, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if !expectStatus(check.ExpectStatus, resp.StatusCode) {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	case HealthCheckTCP, "":
+		conn, err := dialer.DialContext(ctx, target.Network, target.Address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	return errors.New("unknown health check type " + check.Type)
+}
+
+func expectStatus(expect []int, status int) bool {
+	if len(expect) == 0 {
+		return status >= 200 && status < 400
+	}
+	for _, v := range expect {
+		if v == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUpstreamPassiveCheck(t *testing.T) {
+	targets := testTargets()
+	u := NewUpstream(targets, 0)
+	u.Passive = &PassiveCheck{MaxFails: 2, FailTimeout: time.Minute}
+
+	u.Report(targets[0], errors.New("dial error"))
+	if !targets[0].Available() {
+		t.Fatal("Available() = false, want true before max fails")
+	}
+
+	u.Report(targets[0], errors.New("dial error"))
+	if targets[0].Available() {
+		t.Fatal("Available() = true, want false after max fails")
+	}
+
+	if got := targets[0].Health().Status; got != TargetStatusEjected {
+		t.Errorf("Health().Status = %v, want %v", got, TargetStatusEjected)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for i := 0; i < 20; i++ {
+		if got := u.Select(httptest.NewRecorder(), req); got == targets[0] {
+			t.Fatalf("Select() = %v, want ejected target skipped", got)
+		}
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer svr.Close()
+
+	target := &EndpointTarget{Network: "tcp", Address: svr.Listener.Addr().String()}
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		check *HealthCheck
+		ok    bool
+	}{
+		{"tcp", &HealthCheck{Type: HealthCheckTCP, Interval: time.Second}, true},
+		{"http", &HealthCheck{Type: HealthCheckHTTP, Interval: time.Second, Path: "/health"}, true},
+		{"http status", &HealthCheck{Type: HealthCheckHTTP, Interval: time.Second, Path: "/"}, false},
+		{"http expect", &HealthCheck{Type: HealthCheckHTTP, Interval: time.Second, Path: "/", ExpectStatus: []int{503}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckTarget(ctx, tt.check, target)
+			if (err == nil) != tt.ok {
+				t.Errorf("CheckTarget() err = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+
+	// 关闭的端口
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := &EndpointTarget{Network: "tcp", Address: l.Addr().String()}
+	l.Close()
+
+	check := &HealthCheck{Type: HealthCheckTCP, Interval: time.Second, UnhealthyThreshold: 1}
+	closed.reportCheck(check, CheckTarget(ctx, check, closed))
+	if closed.Available() {
+		t.Error("Available() = true, want false for closed port")
+	}
+}
@@ -2,11 +2,15 @@ package agent
 
 import (
 	"fmt"
+	"net"
 	"net/http"
+	"regexp"
 	"strings"
 )
 
 var _ RequestPathMatcher = (*BasicMatcher)(nil)
+var _ RequestHostMatcher = (*BasicMatcher)(nil)
+var _ RequestRouteMatcher = (*BasicMatcher)(nil)
 
 type BasicMatcher struct {
 	Host   []string
@@ -34,8 +38,28 @@ func (b *BasicMatcher) MatchPathPriority() int {
 	return b.Path.MatchPathPriority()
 }
 
+// 绑定具体域名优先于通配符域名，未绑定域名的最后匹配
+func (b *BasicMatcher) MatchHostPriority() int {
+	priority := 0
+	for _, v := range b.Host {
+		if strings.HasPrefix(v, "*") {
+			priority = 1
+			continue
+		}
+		return 2
+	}
+	return priority
+}
+
+func (b *BasicMatcher) MatchRoute() ([]string, string, bool) {
+	if p, ok := b.Path.(interface{ routePath() string }); ok {
+		return b.Host, p.routePath(), true
+	}
+	return nil, "", false
+}
+
 func (b *BasicMatcher) MatchRequest(req *http.Request) bool {
-	if len(b.Host) > 0 && !InStringSlice(req.Host, b.Host) {
+	if len(b.Host) > 0 && !MatchHost(req.Host, b.Host) {
 		return false
 	}
 
@@ -63,9 +87,35 @@ func (b *BasicMatcher) MatchRequest(req *http.Request) bool {
 }
 
 func (b *BasicMatcher) String() string {
+	if len(b.Host) > 0 {
+		return fmt.Sprintf("%v %v %v", b.Host, b.Method, b.Path)
+	}
 	return fmt.Sprintf("%v %v", b.Method, b.Path)
 }
 
+// 匹配域名，忽略端口与大小写，*.example.com 匹配 example.com 的任意子域名
+func MatchHost(host string, patterns []string) bool {
+	host = normalizeHost(host)
+	for _, v := range patterns {
+		v = normalizeHost(v)
+		if v == "*" || v == host {
+			return true
+		}
+		if strings.HasPrefix(v, "*.") && len(host) > len(v)-1 && strings.HasSuffix(host, v[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
 type PathType int
 
 const (
@@ -73,6 +123,7 @@ const (
 	PathTypeFull
 	PathTypePrefix
 	PathTypeParam
+	PathTypeRegex
 )
 
 type pathMatcher struct {
@@ -92,6 +143,14 @@ func (m *pathMatcher) MatchPathPriority() int {
 	return 0
 }
 
+func (m *pathMatcher) String() string {
+	return m.path
+}
+
+func (m *pathMatcher) routePath() string {
+	return m.path
+}
+
 func (m *pathMatcher) MatchRequest(req *http.Request) bool {
 	path := req.URL.Path
 	switch m.typ {
@@ -118,3 +177,32 @@ func NewRequestPathMatcher(path string) RequestPathMatcher {
 func NewRequestPathMatcherWithType(typ PathType, path string) RequestPathMatcher {
 	return &pathMatcher{path: path, typ: typ}
 }
+
+type regexPathMatcher struct {
+	regex *regexp.Regexp
+}
+
+// 正则匹配路径，在其他类型之后匹配
+func NewRegexPathMatcher(pattern string) (RequestPathMatcher, error) {
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &regexPathMatcher{regex: regex}, nil
+}
+
+func (m *regexPathMatcher) MatchPathType() PathType {
+	return PathTypeRegex
+}
+
+func (m *regexPathMatcher) MatchPathPriority() int {
+	return len(m.regex.String())
+}
+
+func (m *regexPathMatcher) MatchRequest(req *http.Request) bool {
+	return m.regex.MatchString(req.URL.Path)
+}
+
+func (m *regexPathMatcher) String() string {
+	return m.regex.String()
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchHost(t *testing.T) {
+	tests := []struct {
+		host     string
+		patterns []string
+		want     bool
+	}{
+		{"example.com", []string{"example.com"}, true},
+		{"example.com:8080", []string{"example.com"}, true},
+		{"Example.COM", []string{"example.com:80"}, true},
+		{"example.com.", []string{"example.com"}, true},
+		{"www.example.com", []string{"example.com"}, false},
+		{"www.example.com", []string{"*.example.com"}, true},
+		{"a.b.example.com:443", []string{"*.example.com"}, true},
+		{"example.com", []string{"*.example.com"}, false},
+		{"badexample.com", []string{"*.example.com"}, false},
+		{"[::1]:8080", []string{"::1"}, true},
+		{"other.com", []string{"*"}, true},
+	}
+	for _, tt := range tests {
+		if got := MatchHost(tt.host, tt.patterns); got != tt.want {
+			t.Errorf("MatchHost(%q, %v) = %v, want %v", tt.host, tt.patterns, got, tt.want)
+		}
+	}
+}
+
+type testNameHandler string
+
+func (h testNameHandler) HandleRequest(w http.ResponseWriter, req *http.Request) {
+	w.Write([]byte(h))
+}
+
+func TestHandlerSortHost(t *testing.T) {
+	newItem := func(name string, host ...string) ForwardHandler {
+		m := NewBasicMatcher()
+		m.Host = host
+		m.Path = NewRequestPathMatcher("/")
+		return NewForwardHandler(m, testNameHandler(name), nil)
+	}
+
+	h := NewHandler()
+	h.Add(newItem("none"))
+	h.Add(newItem("wildcard", "*.example.com"))
+	h.Add(newItem("exact", "www.example.com"))
+	h.Sort()
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"www.example.com", "exact"},
+		{"www.example.com:8080", "exact"},
+		{"api.example.com", "wildcard"},
+		{"other.com", "none"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = tt.host
+		h.ServeHTTP(w, req)
+		if w.Body.String() != tt.want {
+			t.Errorf("ServeHTTP(%q) = %q, want %q", tt.host, w.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestPathMatcherType(t *testing.T) {
+	regex, err := NewRegexPathMatcher(`^/api/v[0-9]+/`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		matcher RequestPathMatcher
+		path    string
+		want    bool
+	}{
+		{NewRequestPathMatcherWithType(PathTypeFull, "/api"), "/api", true},
+		{NewRequestPathMatcherWithType(PathTypeFull, "/api"), "/api/users", false},
+		{NewRequestPathMatcherWithType(PathTypePrefix, "/api"), "/api/users", true},
+		{NewRequestPathMatcherWithType(PathTypeParam, "/users/{id}"), "/users/1", true},
+		{regex, "/api/v2/users", true},
+		{regex, "/api/users", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if got := tt.matcher.MatchRequest(req); got != tt.want {
+			t.Errorf("%v MatchRequest(%q) = %v, want %v", tt.matcher, tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := NewRegexPathMatcher(`(`); err == nil {
+		t.Error("NewRegexPathMatcher() want error for invalid pattern")
+	}
+}
@@ -0,0 +1,222 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+var ErrPoolTimeout = errors.New("wait for connection timeout")
+
+// 后端连接池，按目标地址保存空闲连接
+type ConnPool struct {
+	// 每个目标最大空闲连接数，0 不保留空闲连接
+	MaxIdle int
+	// 空闲超时，0 不超时
+	IdleTimeout time.Duration
+	// 每个目标最大连接数，0 不限制
+	MaxConns int
+
+	mtx     sync.Mutex
+	closed  bool
+	idle    map[string][]*idleConn
+	total   map[string]int
+	waiters map[string][]chan struct{}
+}
+
+type idleConn struct {
+	conn   *PoolConn
+	idleAt time.Time
+}
+
+func NewConnPool(maxIdle int, idleTimeout time.Duration, maxConns int) *ConnPool {
+	return &ConnPool{
+		MaxIdle:     maxIdle,
+		IdleTimeout: idleTimeout,
+		MaxConns:    maxConns,
+		idle:        map[string][]*idleConn{},
+		total:       map[string]int{},
+		waiters:     map[string][]chan struct{}{},
+	}
+}
+
+// 获取连接，优先复用空闲连接，达到最大连接数时等待
+func (p *ConnPool) Get(ctx context.Context, network, address string, timeout time.Duration) (*PoolConn, error) {
+	key := network + "://" + address
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		p.mtx.Lock()
+		if c := p.popIdle(key); c != nil {
+			p.mtx.Unlock()
+			return c, nil
+		}
+
+		if p.MaxConns <= 0 || p.total[key] < p.MaxConns {
+			p.total[key]++
+			p.mtx.Unlock()
+
+			conn, err := net.DialTimeout(network, address, timeout)
+			if err != nil {
+				p.release(key)
+				return nil, err
+			}
+			return &PoolConn{Conn: conn, br: bufio.NewReader(conn), pool: p, key: key}, nil
+		}
+
+		wait := make(chan struct{})
+		p.waiters[key] = append(p.waiters[key], wait)
+		p.mtx.Unlock()
+
+		select {
+		case <-wait:
+		case <-deadline:
+			return nil, ErrPoolTimeout
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// 关闭连接池，关闭全部空闲连接，使用中的连接释放时直接关闭
+func (p *ConnPool) Close() {
+	p.mtx.Lock()
+	p.closed = true
+	idle := p.idle
+	p.idle = map[string][]*idleConn{}
+	p.mtx.Unlock()
+
+	for _, list := range idle {
+		for _, v := range list {
+			v.conn.Close()
+		}
+	}
+}
+
+// 空闲连接数
+func (p *ConnPool) IdleCount(network, address string) int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return len(p.idle[network+"://"+address])
+}
+
+func (p *ConnPool) popIdle(key string) *PoolConn {
+	p.pruneIdle(key)
+	list := p.idle[key]
+	if len(list) == 0 {
+		return nil
+	}
+	c := list[len(list)-1]
+	p.idle[key] = list[:len(list)-1]
+	c.conn.reused = true
+	return c.conn
+}
+
+// 清理过期空闲连接，需持有锁
+func (p *ConnPool) pruneIdle(key string) {
+	if p.IdleTimeout <= 0 {
+		return
+	}
+	list := p.idle[key]
+	expire := time.Now().Add(-p.IdleTimeout)
+	n := 0
+	for n < len(list) && list[n].idleAt.Before(expire) {
+		// 关闭连接需要加锁，这里异步关闭避免死锁
+		go list[n].conn.Close()
+		n++
+	}
+	if n > 0 {
+		p.idle[key] = list[n:]
+	}
+}
+
+func (p *ConnPool) put(c *PoolConn) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.pruneIdle(c.key)
+	if p.closed || len(p.idle[c.key]) >= p.MaxIdle {
+		return false
+	}
+
+	p.idle[c.key] = append(p.idle[c.key], &idleConn{conn: c, idleAt: time.Now()})
+	p.notify(c.key)
+	return true
+}
+
+func (p *ConnPool) release(key string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.total[key]--
+	p.notify(key)
+}
+
+// 唤醒等待连接的请求，需持有锁
+func (p *ConnPool) notify(key string) {
+	for _, w := range p.waiters[key] {
+		close(w)
+	}
+	delete(p.waiters, key)
+}
+
+// 连接池中的连接
+type PoolConn struct {
+	net.Conn
+	br     *bufio.Reader
+	pool   *ConnPool
+	key    string
+	reused bool
+	once   sync.Once
+}
+
+// 不使用连接池的连接
+func DialConn(network, address string, timeout time.Duration) (*PoolConn, error) {
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &PoolConn{Conn: conn, br: bufio.NewReader(conn)}, nil
+}
+
+// 读取缓冲，包含已从连接读取但未消费的数据
+func (c *PoolConn) Reader() *bufio.Reader {
+	return c.br
+}
+
+// 底层连接
+func (c *PoolConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// 是否为复用的空闲连接
+func (c *PoolConn) Reused() bool {
+	return c.reused
+}
+
+// 放回连接池，无法放回时关闭
+func (c *PoolConn) Release() {
+	if c.pool != nil && c.br.Buffered() == 0 && c.pool.put(c) {
+		return
+	}
+	c.Close()
+}
+
+func (c *PoolConn) Close() error {
+	var err error
+	c.once.Do(func() {
+		err = c.Conn.Close()
+		if c.pool != nil {
+			c.pool.release(c.key)
+		}
+	})
+	return err
+}
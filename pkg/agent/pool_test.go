@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestUpstreamServer(conns *int64) *httptest.Server {
+	svr := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	svr.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew && conns != nil {
+			atomic.AddInt64(conns, 1)
+		}
+	}
+	svr.Start()
+	return svr
+}
+
+func newTestForwardHandler(svr *httptest.Server, pool *ConnPool) *StaticForwardHandler {
+	u := NewUpstream([]*EndpointTarget{{Network: "tcp", Address: svr.Listener.Addr().String()}}, time.Second)
+	u.Pool = pool
+	return NewStaticForwardHandler(u)
+}
+
+func forwardTestRequest(t testing.TB, h RequestForwardHandler) {
+	w := httptest.NewRecorder()
+	h.HandleRequest(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Fatalf("HandleRequest() = %d %q, want 200 ok", w.Code, w.Body.String())
+	}
+}
+
+func TestConnPoolReuse(t *testing.T) {
+	var conns int64
+	svr := newTestUpstreamServer(&conns)
+	defer svr.Close()
+
+	h := newTestForwardHandler(svr, NewConnPool(4, time.Minute, 0))
+	for i := 0; i < 5; i++ {
+		forwardTestRequest(t, h)
+	}
+
+	if got := atomic.LoadInt64(&conns); got != 1 {
+		t.Errorf("upstream connections = %d, want 1", got)
+	}
+
+	// 后端关闭空闲连接后使用新连接重试
+	svr.CloseClientConnections()
+	forwardTestRequest(t, h)
+}
+
+func TestConnPoolMaxConns(t *testing.T) {
+	svr := newTestUpstreamServer(nil)
+	defer svr.Close()
+
+	addr := svr.Listener.Addr().String()
+	pool := NewConnPool(1, time.Minute, 1)
+	ctx := context.Background()
+
+	c, err := pool.Get(ctx, "tcp", addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pool.Get(ctx, "tcp", addr, 50*time.Millisecond); err != ErrPoolTimeout {
+		t.Fatalf("Get() err = %v, want %v", err, ErrPoolTimeout)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		c.Release()
+	}()
+
+	c2, err := pool.Get(ctx, "tcp", addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c2 != c || !c2.Reused() {
+		t.Error("Get() want reused idle connection")
+	}
+	c2.Close()
+
+	if got := pool.IdleCount("tcp", addr); got != 0 {
+		t.Errorf("IdleCount() = %d, want 0", got)
+	}
+}
+
+func BenchmarkForwardPooled(b *testing.B) {
+	svr := newTestUpstreamServer(nil)
+	defer svr.Close()
+
+	h := newTestForwardHandler(svr, NewConnPool(64, time.Minute, 0))
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			forwardTestRequest(b, h)
+		}
+	})
+}
+
+func BenchmarkForwardUnpooled(b *testing.B) {
+	svr := newTestUpstreamServer(nil)
+	defer svr.Close()
+
+	h := newTestForwardHandler(svr, nil)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			forwardTestRequest(b, h)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 在鉴权前处理跳转，如明文请求跳转到 HTTPS
+type RequestRedirectHandler interface {
+	// 已写入跳转响应时返回 true
+	HandleRedirect(w http.ResponseWriter, req *http.Request) bool
+}
+
+// 明文请求在鉴权前跳转到 HTTPS 的规则
+func NewHTTPSForwardHandler(matcher RequestPathMatcher, forward RequestForwardHandler, auth AuthorizeHandler) ForwardHandler {
+	return &forwardItem{RequestPathMatcher: matcher, RequestForwardHandler: forward, auth: auth, httpsRedirect: true}
+}
+
+func (item forwardItem) HandleRedirect(w http.ResponseWriter, req *http.Request) bool {
+	if !item.httpsRedirect || req.TLS != nil {
+		return false
+	}
+	http.Redirect(w, req, "https://"+httpsHost(req)+req.URL.RequestURI(), http.StatusPermanentRedirect)
+	return true
+}
+
+// 跳转地址的端口使用 Server 设置的 HTTPS 端口，未设置时保留请求中非 80 的端口
+func httpsHost(req *http.Request) string {
+	host, port := req.Host, ""
+	if h, p, err := net.SplitHostPort(req.Host); err == nil {
+		host, port = h, p
+	}
+
+	if s, ok := req.Context().Value(tunnelTrackerKey{}).(*Server); ok {
+		if v := s.httpsPort.Load(); v > 0 {
+			port = strconv.Itoa(int(v))
+		}
+	}
+
+	if port == "" || port == "80" || port == "443" {
+		if strings.IndexByte(host, ':') >= 0 {
+			return "[" + host + "]"
+		}
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
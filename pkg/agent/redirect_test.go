@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPSRedirect(t *testing.T) {
+	deny := authorizeFunc(func(w http.ResponseWriter, req *http.Request) bool {
+		http.Error(w, "denied", http.StatusUnauthorized)
+		return false
+	})
+	h := NewHandler()
+	h.Add(NewHTTPSForwardHandler(NewRequestPathMatcher("/"), testNameHandler("route"), deny))
+	h.Sort()
+
+	s := New()
+	s.Use(h)
+
+	tests := []struct {
+		target string
+		port   int
+		want   string
+	}{
+		{"http://example.com/a/b?c=d", 0, "https://example.com/a/b?c=d"},
+		{"http://example.com:80/a", 0, "https://example.com/a"},
+		{"http://example.com:8080/a", 0, "https://example.com:8080/a"},
+		{"http://example.com:8080/a", 8443, "https://example.com:8443/a"},
+		{"http://example.com/a", 443, "https://example.com/a"},
+		{"http://[::1]:80/a", 0, "https://[::1]/a"},
+		{"http://[::1]:8080/a", 0, "https://[::1]:8080/a"},
+	}
+	// 明文请求在鉴权前跳转
+	for _, tt := range tests {
+		s.SetHTTPSPort(tt.port)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != tt.want {
+			t.Errorf("%s port %d: redirect = %d %q, want %q", tt.target, tt.port, w.Code, w.Header().Get("Location"), tt.want)
+		}
+	}
+
+	// HTTPS 请求继续执行鉴权
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/a", nil)
+	req.TLS = &tls.ConnectionState{}
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("https request status = %d, want 401", w.Code)
+	}
+}
+
+type authorizeFunc func(w http.ResponseWriter, req *http.Request) bool
+
+func (f authorizeFunc) HandleAuthorizeCheck(w http.ResponseWriter, req *http.Request) bool {
+	return f(w, req)
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	RetryOnConnectError = "connect_error"
+	RetryOnReadError    = "read_error"
+)
+
+const defaultRetryMaxBodySize = 1 << 20
+
+// 重试策略
+type RetryPolicy struct {
+	// 最大尝试次数，包括首次请求
+	MaxAttempts int
+	// 重试的错误类型
+	RetryOn []string
+	// 重试的响应状态码
+	RetryStatus []int
+	// 允许重试非幂等请求
+	NonIdempotent bool
+	// 单次尝试超时，0 不限制
+	PerTryTimeout time.Duration
+	// 缓存请求体的最大长度，超过时不重试
+	MaxBodySize int64
+}
+
+func (p *RetryPolicy) enabled(req *http.Request) bool {
+	if p == nil || p.MaxAttempts <= 1 {
+		return false
+	}
+	return p.NonIdempotent || isIdempotent(req.Method)
+}
+
+func (p *RetryPolicy) retryError(err *ForwardError) bool {
+	switch err.Stage {
+	case ForwardStageConnect:
+		return InStringSlice(RetryOnConnectError, p.RetryOn)
+	case ForwardStageRead:
+		return InStringSlice(RetryOnReadError, p.RetryOn)
+	}
+	return false
+}
+
+func (p *RetryPolicy) retryStatus(status int) bool {
+	for _, v := range p.RetryStatus {
+		if v == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) maxBodySize() int64 {
+	if p.MaxBodySize <= 0 {
+		return defaultRetryMaxBodySize
+	}
+	return p.MaxBodySize
+}
+
+// 单次尝试的上下文，HTTP/1.1 转发据此设置连接读写超时
+//
+// 超时由计时器取消，响应头返回后停止计时
+type tryContext struct {
+	context.Context
+	deadline time.Time
+}
+
+func newTryContext(ctx context.Context, timeout time.Duration) *tryContext {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	return &tryContext{Context: ctx, deadline: deadline}
+}
+
+func (c *tryContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// 缓存请求体，超过限制时恢复请求体并返回 false
+func bufferBody(req *http.Request, limit int64) ([]byte, bool, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, true, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+
+	if int64(len(body)) > limit {
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+		return nil, false, nil
+	}
+
+	req.Body.Close()
+	return body, true, nil
+}
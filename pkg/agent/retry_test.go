@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func closedAddress(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func TestRetryConnectError(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer svr.Close()
+
+	u := NewUpstream([]*EndpointTarget{
+		{Network: "tcp", Address: closedAddress(t)},
+		{Network: "tcp", Address: svr.Listener.Addr().String()},
+	}, time.Second)
+	u.Balancer = NewRoundRobinBalancer()
+	u.Retry = &RetryPolicy{MaxAttempts: 2, RetryOn: []string{RetryOnConnectError}}
+	h := NewStaticForwardHandler(u)
+
+	for i := 0; i < 4; i++ {
+		w := httptest.NewRecorder()
+		h.HandleRequest(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader("body")))
+		if w.Code != http.StatusOK || w.Body.String() != "body" {
+			t.Fatalf("HandleRequest() = %d %q, want 200 body", w.Code, w.Body.String())
+		}
+	}
+
+	// 非幂等请求不重试
+	u.Balancer = NewRoundRobinBalancer()
+	w := httptest.NewRecorder()
+	h.HandleRequest(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("body")))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("HandleRequest() = %d, want %d", w.Code, http.StatusBadGateway)
+	}
+}
+
+func TestRetryStatus(t *testing.T) {
+	var count int64
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&count, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer svr.Close()
+
+	u := NewUpstream([]*EndpointTarget{{Network: "tcp", Address: svr.Listener.Addr().String()}}, time.Second)
+	u.Retry = &RetryPolicy{MaxAttempts: 3, RetryStatus: []int{http.StatusServiceUnavailable}}
+	h := NewStaticForwardHandler(u)
+
+	w := httptest.NewRecorder()
+	h.HandleRequest(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK || atomic.LoadInt64(&count) != 3 {
+		t.Errorf("HandleRequest() = %d after %d attempts, want 200 after 3", w.Code, count)
+	}
+}
+
+// 重试状态码的响应持续输出时不等待响应结束
+func TestRetryStatusStreaming(t *testing.T) {
+	var count int64
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&count, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			for {
+				if _, err := io.WriteString(w, "busy\n"); err != nil {
+					return
+				}
+				w.(http.Flusher).Flush()
+				select {
+				case <-r.Context().Done():
+					return
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer svr.Close()
+
+	u := NewUpstream([]*EndpointTarget{{Network: "tcp", Address: svr.Listener.Addr().String()}}, time.Second)
+	defer u.Stop()
+	u.Retry = &RetryPolicy{MaxAttempts: 2, RetryStatus: []int{http.StatusServiceUnavailable}}
+	h := NewStaticForwardHandler(u)
+
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		w := httptest.NewRecorder()
+		h.HandleRequest(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		done <- w
+	}()
+	select {
+	case w := <-done:
+		if w.Code != http.StatusOK || w.Body.String() != "ok" {
+			t.Errorf("HandleRequest() = %d %q, want 200 ok", w.Code, w.Body.String())
+		}
+	case <-time.After(2 * time.Second):
+		svr.CloseClientConnections()
+		t.Fatal("retry blocked by streaming response")
+	}
+}
+
+func TestRetryPerTryTimeout(t *testing.T) {
+	var count int64
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&count, 1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer svr.Close()
+
+	u := NewUpstream([]*EndpointTarget{{Network: "tcp", Address: svr.Listener.Addr().String()}}, time.Second)
+	u.Retry = &RetryPolicy{MaxAttempts: 2, RetryOn: []string{RetryOnReadError}, PerTryTimeout: 50 * time.Millisecond}
+	h := NewStaticForwardHandler(u)
+
+	w := httptest.NewRecorder()
+	h.HandleRequest(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("HandleRequest() = %d %q, want 200 ok", w.Code, w.Body.String())
+	}
+}
+
+func TestRetryBodyTooLarge(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader("0123456789"))
+	body, ok, err := bufferBody(req, 4)
+	if err != nil || ok || body != nil {
+		t.Fatalf("bufferBody() = %q %v %v, want not buffered", body, ok, err)
+	}
+
+	rest, _ := io.ReadAll(req.Body)
+	if string(rest) != "0123456789" {
+		t.Errorf("request body = %q, want restored", rest)
+	}
+}
+
+// 单次尝试超时只限制响应头，HTTP/2 流式响应可超过该时间
+func TestRetryPerTryTimeoutH2Stream(t *testing.T) {
+	backend := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			io.WriteString(w, "data\n")
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}), &http2.Server{}))
+	defer backend.Close()
+
+	u := NewUpstream([]*EndpointTarget{{Network: "tcp", Address: backend.Listener.Addr().String(), Protocol: ProtocolH2C}}, time.Second)
+	defer u.Stop()
+	u.Retry = &RetryPolicy{MaxAttempts: 2, RetryOn: []string{RetryOnReadError}, PerTryTimeout: 80 * time.Millisecond}
+	h := NewStaticForwardHandler(u)
+
+	w := httptest.NewRecorder()
+	h.HandleRequest(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "data\ndata\ndata\n" {
+		t.Errorf("HandleRequest() = %d %q, want 200 with full stream", w.Code, w.Body.String())
+	}
+}
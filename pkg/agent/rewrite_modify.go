@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var _ ForwardRewriteRequestProvider = (*Modifier)(nil)
+var _ ForwardRewriteResponseProvider = (*Modifier)(nil)
+
+const (
+	ModifyTypeAdd    = "add"
+	ModifyTypeSet    = "set"
+	ModifyTypeDelete = "delete"
+)
+
+const (
+	ModifySourceHeader         = "header"
+	ModifySourceQuery          = "query"
+	ModifySourceCookie         = "cookie"
+	ModifySourceResponseHeader = "response_header"
+)
+
+type ModifyOption struct {
+	Type   string
+	Source string
+	Name   string
+	Value  string
+}
+
+// 请求/响应数据编辑
+//
+// 值支持模板变量 {source.name}：
+//   - {path.name} 路由路径参数
+//   - {header.name} {query.name} {cookie.name} 请求数据，包括鉴权写入的请求头
+//   - {ip} 客户端地址
+type Modifier struct {
+	pattern  string
+	request  []*ModifyOption
+	response []*ModifyOption
+}
+
+func NewModifier(pattern string, options []*ModifyOption) (*Modifier, error) {
+	m := &Modifier{pattern: pattern}
+	for _, v := range options {
+		switch v.Type {
+		case ModifyTypeAdd, ModifyTypeSet, ModifyTypeDelete:
+		default:
+			return nil, fmt.Errorf("unknown modify type %s", v.Type)
+		}
+
+		switch v.Source {
+		case ModifySourceHeader, ModifySourceQuery, ModifySourceCookie:
+			m.request = append(m.request, v)
+		case ModifySourceResponseHeader:
+			m.response = append(m.response, v)
+		default:
+			return nil, fmt.Errorf("unknown modify source %s", v.Source)
+		}
+	}
+	return m, nil
+}
+
+func (m *Modifier) RewriteRequest(req *http.Request) error {
+	if len(m.request) == 0 {
+		return nil
+	}
+
+	query := req.URL.Query()
+	queryModified := false
+
+	for _, v := range m.request {
+		value := m.expand(req, v.Value)
+		switch v.Source {
+		case ModifySourceHeader:
+			modifyHeader(req.Header, v.Type, v.Name, value)
+			if http.CanonicalHeaderKey(v.Name) == "Host" && v.Type != ModifyTypeDelete {
+				req.Host = value
+			}
+		case ModifySourceQuery:
+			modifyValues(query, v.Type, v.Name, value)
+			queryModified = true
+		case ModifySourceCookie:
+			modifyCookie(req, v.Type, v.Name, value)
+		}
+	}
+
+	if queryModified {
+		req.URL.RawQuery = query.Encode()
+	}
+	return nil
+}
+
+func (m *Modifier) RewriteResponse(resp *http.Response) error {
+	for _, v := range m.response {
+		value := v.Value
+		if resp.Request != nil {
+			value = m.expand(resp.Request, v.Value)
+		}
+		modifyHeader(resp.Header, v.Type, v.Name, value)
+	}
+	return nil
+}
+
+// 展开模板变量，未知变量保持原样
+func (m *Modifier) expand(req *http.Request, tpl string) string {
+	if strings.IndexByte(tpl, '{') < 0 {
+		return tpl
+	}
+
+	var params url.Values
+	var b strings.Builder
+	for i := 0; i < len(tpl); i++ {
+		if tpl[i] == '{' {
+			if end := strings.IndexByte(tpl[i:], '}'); end > 0 {
+				source, name, _ := strings.Cut(tpl[i+1:i+end], ".")
+				if source == "path" {
+					if params == nil {
+						params = m.pathParams(req)
+					}
+					b.WriteString(params.Get(name))
+					i += end
+					continue
+				}
+				if InStringSlice(source, []string{"header", "query", "cookie", "ip"}) {
+					b.WriteString(VarFrom(req, source, name))
+					i += end
+					continue
+				}
+			}
+		}
+		b.WriteByte(tpl[i])
+	}
+	return b.String()
+}
+
+// 路径参数以原始请求路径为准，不受路径重写影响
+func (m *Modifier) pathParams(req *http.Request) url.Values {
+	path := req.URL.Path
+	if req.RequestURI != "" {
+		if u, err := url.ParseRequestURI(req.RequestURI); err == nil {
+			path = u.Path
+		}
+	}
+	_, params, _ := TestPath(m.pattern, path)
+	if params == nil {
+		params = url.Values{}
+	}
+	return params
+}
+
+func modifyHeader(header http.Header, typ, name, value string) {
+	switch typ {
+	case ModifyTypeAdd:
+		header.Add(name, value)
+	case ModifyTypeSet:
+		header.Set(name, value)
+	case ModifyTypeDelete:
+		header.Del(name)
+	}
+}
+
+func modifyValues(values url.Values, typ, name, value string) {
+	switch typ {
+	case ModifyTypeAdd:
+		values.Add(name, value)
+	case ModifyTypeSet:
+		values.Set(name, value)
+	case ModifyTypeDelete:
+		values.Del(name)
+	}
+}
+
+func modifyCookie(req *http.Request, typ, name, value string) {
+	cookies := req.Cookies()
+	req.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name == name && typ != ModifyTypeAdd {
+			continue
+		}
+		req.AddCookie(c)
+	}
+	if typ != ModifyTypeDelete {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestModifierRequest(t *testing.T) {
+	m, err := NewModifier("/users/{id}", []*ModifyOption{
+		{Type: "set", Source: "header", Name: "X-User", Value: "{path.id}"},
+		{Type: "set", Source: "header", Name: "X-Auth", Value: "auth-{header.X-Auth-Id}"},
+		{Type: "add", Source: "header", Name: "X-Real-Ip", Value: "{ip}"},
+		{Type: "delete", Source: "header", Name: "X-Remove"},
+		{Type: "set", Source: "query", Name: "page", Value: "2"},
+		{Type: "delete", Source: "query", Name: "debug"},
+		{Type: "set", Source: "cookie", Name: "session", Value: "{cookie.token}"},
+		{Type: "delete", Source: "cookie", Name: "token"},
+		{Type: "set", Source: "header", Name: "X-Literal", Value: "{unknown}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42?page=1&debug=1", nil)
+	req.RemoteAddr = "10.0.0.1:5678"
+	req.Header.Set("X-Auth-Id", "7")
+	req.Header.Set("X-Remove", "1")
+	req.AddCookie(&http.Cookie{Name: "token", Value: "abc"})
+
+	// 路径重写后参数仍按原始路径获取
+	req.URL.Path = "/rewritten"
+
+	if err := m.RewriteRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	headers := map[string]string{
+		"X-User":    "42",
+		"X-Auth":    "auth-7",
+		"X-Real-Ip": "10.0.0.1",
+		"X-Remove":  "",
+		"X-Literal": "{unknown}",
+	}
+	for k, v := range headers {
+		if got := req.Header.Get(k); got != v {
+			t.Errorf("header %s = %q, want %q", k, got, v)
+		}
+	}
+
+	if got := req.URL.RawQuery; got != "page=2" {
+		t.Errorf("query = %q, want %q", got, "page=2")
+	}
+
+	if got := req.Header.Get("Cookie"); got != "session=abc" {
+		t.Errorf("cookie = %q, want %q", got, "session=abc")
+	}
+}
+
+func TestModifierResponse(t *testing.T) {
+	m, err := NewModifier("/{name}", []*ModifyOption{
+		{Type: "set", Source: "response_header", Name: "X-Name", Value: "{path.name}"},
+		{Type: "delete", Source: "response_header", Name: "Server"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/foo", nil)
+	resp := &http.Response{Header: http.Header{"Server": []string{"upstream"}}, Request: req}
+	if err := m.RewriteResponse(resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := resp.Header.Get("X-Name"); got != "foo" {
+		t.Errorf("X-Name = %q, want %q", got, "foo")
+	}
+	if got := resp.Header.Get("Server"); got != "" {
+		t.Errorf("Server = %q, want empty", got)
+	}
+}
+
+func TestModifierInvalid(t *testing.T) {
+	if _, err := NewModifier("/", []*ModifyOption{{Type: "replace", Source: "header", Name: "a"}}); err == nil {
+		t.Error("NewModifier() want error for unknown type")
+	}
+	if _, err := NewModifier("/", []*ModifyOption{{Type: "set", Source: "body", Name: "a"}}); err == nil {
+		t.Error("NewModifier() want error for unknown source")
+	}
+}
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var _ ForwardRewriteRequestProvider = (*PathRewrite)(nil)
+
+// 路径重写
+// 替换内容支持正则分组 $1 ${name} 以及路径参数 {name}
+type PathRewrite struct {
+	pattern string
+	regex   *regexp.Regexp
+	replace string
+}
+
+func NewPathRewrite(pattern, regex, replace string) (*PathRewrite, error) {
+	if regex == "" {
+		regex = "^.*$"
+	}
+
+	exp, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PathRewrite{pattern: pattern, regex: exp, replace: replace}, nil
+}
+
+func (r *PathRewrite) RewriteRequest(req *http.Request) error {
+	path := req.URL.Path
+	if !r.regex.MatchString(path) {
+		return nil
+	}
+
+	replace := r.replace
+	if strings.IndexByte(r.pattern, '{') >= 0 && strings.IndexByte(replace, '{') >= 0 {
+		_, params, err := TestPath(r.pattern, path)
+		if err != nil {
+			return err
+		}
+		replace = expandPathParams(replace, params)
+	}
+
+	req.URL.Path = r.regex.ReplaceAllString(path, replace)
+	// 由 Path 重新生成转义路径
+	req.URL.RawPath = ""
+	return nil
+}
+
+// 替换 {name} 为路径参数值，未定义的参数保持原样
+func expandPathParams(tpl string, params map[string][]string) string {
+	var b strings.Builder
+	for i := 0; i < len(tpl); i++ {
+		// 跳过正则的 ${name} 形式
+		if tpl[i] == '{' && (i == 0 || tpl[i-1] != '$') {
+			end := strings.IndexByte(tpl[i:], '}')
+			if end > 0 {
+				name := tpl[i+1 : i+end]
+				if v, ok := params[name]; ok && len(v) > 0 {
+					b.WriteString(strings.ReplaceAll(v[0], "$", "$$"))
+					i += end
+					continue
+				}
+			}
+		}
+		b.WriteByte(tpl[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPathRewrite(t *testing.T) {
+	tests := []struct {
+		pattern string
+		regex   string
+		replace string
+		path    string
+		want    string
+	}{
+		{"/api", "^/api", "", "/api/users", "/users"},
+		{"/api", "^/api/(.*)$", "/v2/$1", "/api/users", "/v2/users"},
+		{"/api", "^/api/(?P<rest>.*)$", "/v2/${rest}", "/api/users", "/v2/users"},
+		{"/api", "^/other", "/v2", "/api/users", "/api/users"},
+		{"/{name}/info", "", "/users/{name}", "/foo/info", "/users/foo"},
+		{"/{name}/{id}", "^/.*$", "/{id}/{name}/{missing}", "/foo/1", "/1/foo/{missing}"},
+		{"/{name}/info", "", "/cost/{name}", "/$1/info", "/cost/$1"},
+		{"/files", "^/files", "/static", "/files/a%20b", "/static/a b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.regex+tt.replace, func(t *testing.T) {
+			rw, err := NewPathRewrite(tt.pattern, tt.regex, tt.replace)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := http.NewRequest(http.MethodGet, "http://localhost"+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := rw.RewriteRequest(req); err != nil {
+				t.Fatal(err)
+			}
+			if req.URL.Path != tt.want {
+				t.Errorf("RewriteRequest() path = %v, want %v", req.URL.Path, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package agent
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// 可建立路由索引的规则
+type RequestRouteMatcher interface {
+	// 匹配的域名与路径模式，域名为空匹配全部域名，无法建立索引时 ok 为 false
+	MatchRoute() (hosts []string, path string, ok bool)
+}
+
+// 路由表，按域名与路径建立基数树索引
+//
+// 索引只用于筛选可能匹配的规则，候选规则按 Handler.Sort 的顺序
+// 逐个调用 MatchRequest 确认，匹配结果与顺序扫描一致
+type router struct {
+	// 域名模式到路径树，空字符串为未绑定域名
+	hosts map[string]*radixNode
+	// 无法建立索引的规则，如正则路径，总是作为候选
+	always []int
+}
+
+func newRouter(items []ForwardHandler) *router {
+	r := &router{hosts: map[string]*radixNode{}}
+	for i, item := range items {
+		hosts, path, ok := matchRoute(item)
+		if !ok {
+			r.always = append(r.always, i)
+			continue
+		}
+
+		exact := false
+		switch item.MatchPathType() {
+		case PathTypeFull:
+			exact = true
+		case PathTypePrefix, PathTypeNone:
+		case PathTypeParam:
+			path = staticPrefix(path)
+		default:
+			r.always = append(r.always, i)
+			continue
+		}
+
+		if len(hosts) == 0 {
+			hosts = []string{""}
+		}
+
+		for _, host := range hosts {
+			host = normalizeHost(host)
+			node, ok := r.hosts[host]
+			if !ok {
+				node = &radixNode{}
+				r.hosts[host] = node
+			}
+			node.insert(path, i, exact)
+		}
+	}
+	return r
+}
+
+func matchRoute(m interface{}) ([]string, string, bool) {
+	if r, ok := m.(RequestRouteMatcher); ok {
+		return r.MatchRoute()
+	}
+	return nil, "", false
+}
+
+// 可能匹配请求的规则下标，按顺序返回
+func (r *router) candidates(req *http.Request) []int {
+	host := normalizeHost(req.Host)
+	path := req.URL.Path
+
+	list := append([]int{}, r.always...)
+	for _, key := range hostKeys(host) {
+		if node, ok := r.hosts[key]; ok {
+			list = node.lookup(path, list)
+		}
+	}
+
+	sort.Ints(list)
+	n := 0
+	for i, v := range list {
+		if i > 0 && v == list[n-1] {
+			continue
+		}
+		list[n] = v
+		n++
+	}
+	return list[:n]
+}
+
+// 请求域名可能匹配的域名模式
+func hostKeys(host string) []string {
+	keys := []string{"", "*"}
+	if host == "" {
+		return keys
+	}
+
+	keys = append(keys, host)
+	for i := strings.IndexByte(host, '.'); i >= 0; {
+		keys = append(keys, "*"+host[i:])
+		next := strings.IndexByte(host[i+1:], '.')
+		if next < 0 {
+			break
+		}
+		i = i + 1 + next
+	}
+	return keys
+}
+
+// 参数路径第一个参数前的静态部分
+func staticPrefix(path string) string {
+	if i := strings.IndexByte(path, '{'); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+type radixNode struct {
+	// 节点路径片段
+	label    string
+	children []*radixNode
+	// 以该节点为前缀的规则
+	prefix []int
+	// 完全匹配该节点的规则
+	exact []int
+}
+
+func (n *radixNode) insert(path string, index int, exact bool) {
+	for {
+		if path == "" {
+			if exact {
+				n.exact = append(n.exact, index)
+			} else {
+				n.prefix = append(n.prefix, index)
+			}
+			return
+		}
+
+		child := n.child(path[0])
+		if child == nil {
+			child = &radixNode{label: path}
+			n.children = append(n.children, child)
+			n = child
+			path = ""
+			continue
+		}
+
+		common := commonPrefix(child.label, path)
+		if common < len(child.label) {
+			// 拆分节点
+			split := &radixNode{label: child.label[common:], children: child.children, prefix: child.prefix, exact: child.exact}
+			child.label = child.label[:common]
+			child.children = []*radixNode{split}
+			child.prefix = nil
+			child.exact = nil
+		}
+
+		n = child
+		path = path[common:]
+	}
+}
+
+func (n *radixNode) lookup(path string, list []int) []int {
+	for {
+		list = append(list, n.prefix...)
+		if path == "" {
+			return append(list, n.exact...)
+		}
+
+		child := n.child(path[0])
+		if child == nil || !strings.HasPrefix(path, child.label) {
+			return list
+		}
+
+		n = child
+		path = path[len(child.label):]
+	}
+}
+
+func (n *radixNode) child(c byte) *radixNode {
+	for _, v := range n.children {
+		if v.label[0] == c {
+			return v
+		}
+	}
+	return nil
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestRouteHandler(n int) *Handler {
+	r := rand.New(rand.NewSource(1))
+	h := NewHandler()
+	for i := 0; i < n; i++ {
+		m := NewBasicMatcher()
+		switch i % 4 {
+		case 0:
+			m.Path = NewRequestPathMatcherWithType(PathTypeFull, fmt.Sprintf("/api/v%d/full/%d", i%7, i))
+		case 1:
+			m.Path = NewRequestPathMatcherWithType(PathTypePrefix, fmt.Sprintf("/api/v%d/prefix/%d", i%7, i))
+		case 2:
+			m.Path = NewRequestPathMatcherWithType(PathTypeParam, fmt.Sprintf("/api/v%d/users/{id}/%d", i%7, i))
+		case 3:
+			m.Path = NewRequestPathMatcherWithType(PathTypePrefix, fmt.Sprintf("/static/%d", i))
+		}
+
+		switch r.Intn(4) {
+		case 0:
+			m.Host = []string{fmt.Sprintf("host%d.example.com", i%13)}
+		case 1:
+			m.Host = []string{"*.example.com"}
+		}
+
+		if i%5 == 0 {
+			m.Method = []string{http.MethodPost}
+		}
+		h.Add(NewForwardHandler(m, testNameHandler(fmt.Sprint(i)), nil))
+	}
+
+	regex, _ := NewRegexPathMatcher(`^/regex/[0-9]+$`)
+	m := NewBasicMatcher()
+	m.Path = regex
+	h.Add(NewForwardHandler(m, testNameHandler("regex"), nil))
+
+	root := NewBasicMatcher()
+	root.Path = NewRequestPathMatcher("/")
+	h.Add(NewForwardHandler(root, testNameHandler("root"), nil))
+
+	h.Sort()
+	return h
+}
+
+func testRouteRequests(n int) []*http.Request {
+	r := rand.New(rand.NewSource(2))
+	hosts := []string{"", "host1.example.com", "host7.example.com:8080", "a.b.example.com", "other.com"}
+	reqs := []*http.Request{}
+	for i := 0; i < 1000; i++ {
+		id := r.Intn(n)
+		var path string
+		switch r.Intn(6) {
+		case 0:
+			path = fmt.Sprintf("/api/v%d/full/%d", id%7, id)
+		case 1:
+			path = fmt.Sprintf("/api/v%d/prefix/%d/more", id%7, id)
+		case 2:
+			path = fmt.Sprintf("/api/v%d/users/abc/%d", id%7, id)
+		case 3:
+			path = fmt.Sprintf("/static/%d/file.js", id)
+		case 4:
+			path = fmt.Sprintf("/regex/%d", id)
+		default:
+			path = "/not/found"
+		}
+
+		method := http.MethodGet
+		if r.Intn(2) == 0 {
+			method = http.MethodPost
+		}
+
+		req := httptest.NewRequest(method, path, nil)
+		req.Host = hosts[r.Intn(len(hosts))]
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+func matchLinear(h *Handler, req *http.Request) ForwardHandler {
+	for _, item := range h.items {
+		if item.MatchRequest(req) {
+			return item
+		}
+	}
+	return nil
+}
+
+func TestRouterMatchLinear(t *testing.T) {
+	h := newTestRouteHandler(2000)
+	for _, req := range testRouteRequests(2000) {
+		if got, want := h.Match(req), matchLinear(h, req); got != want {
+			t.Fatalf("Match(%s %s %s) = %v, want %v", req.Method, req.Host, req.URL.Path, got, want)
+		}
+	}
+}
+
+func TestRadixNode(t *testing.T) {
+	root := &radixNode{}
+	root.insert("/api/users", 0, false)
+	root.insert("/api/user", 1, true)
+	root.insert("/api", 2, false)
+	root.insert("/apple", 3, false)
+	root.insert("", 4, false)
+
+	tests := []struct {
+		path string
+		want []int
+	}{
+		{"/api/users/1", []int{4, 2, 0}},
+		{"/api/user", []int{4, 2, 1}},
+		{"/apple/1", []int{4, 3}},
+		{"/ap", []int{4}},
+	}
+	for _, tt := range tests {
+		got := root.lookup(tt.path, nil)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("lookup(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkRouterMatch10k(b *testing.B) {
+	h := newTestRouteHandler(10000)
+	reqs := testRouteRequests(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Match(reqs[i%len(reqs)])
+	}
+}
+
+func BenchmarkLinearMatch10k(b *testing.B) {
+	h := newTestRouteHandler(10000)
+	reqs := testRouteRequests(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		matchLinear(h, reqs[i%len(reqs)])
+	}
+}
@@ -1,29 +1,291 @@
 package agent
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
+// 证书验证请求处理，如 ACME HTTP-01
+type ChallengeHandler interface {
+	ServeChallenge(w http.ResponseWriter, req *http.Request) bool
+}
+
 type Server struct {
-	hd  http.Handler
-	mtx *sync.Mutex
+	hd        atomic.Pointer[serverHandler]
+	challenge ChallengeHandler
+
+	mtx     sync.Mutex
+	closing bool
+	servers map[net.Listener]*http.Server
+	// 已劫持的长连接，如 websocket
+	tunnels map[net.Conn]struct{}
+	// 长连接超时配置
+	tunnel      atomic.Pointer[Tunnel]
+	tunnelStats TunnelStats
+	// 跳转 HTTPS 使用的端口
+	httpsPort atomic.Int32
+}
+
+const shutdownPollInterval = 50 * time.Millisecond
+
+type serverHandler struct {
+	http.Handler
 }
 
+type tunnelTrackerKey struct{}
+
 func New() *Server {
-	return &Server{mtx: &sync.Mutex{}}
+	s := &Server{
+		servers: map[net.Listener]*http.Server{},
+		tunnels: map[net.Conn]struct{}{},
+	}
+	s.tunnel.Store(&Tunnel{Stats: &s.tunnelStats})
+	return s
+}
+
+// 设置长连接空闲超时与最长存活时间，对之后建立的连接生效
+func (s *Server) SetTunnelTimeout(idle, lifetime time.Duration) {
+	s.tunnel.Store(&Tunnel{IdleTimeout: idle, MaxLifetime: lifetime, Stats: &s.tunnelStats})
+}
+
+// 设置明文请求跳转 HTTPS 使用的端口，为 0 时保留请求中非 80 的端口
+func (s *Server) SetHTTPSPort(port int) {
+	s.httpsPort.Store(int32(port))
+}
+
+// 长连接统计
+func (s *Server) TunnelStats() *TunnelStats {
+	return &s.tunnelStats
 }
 
+// 替换请求处理，正在处理的请求不受影响
 func (s *Server) Use(h http.Handler) {
+	s.hd.Store(&serverHandler{h})
+}
+
+// 设置证书验证处理，需在服务启动前设置
+func (s *Server) UseChallenge(h ChallengeHandler) {
+	s.challenge = h
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if s.challenge != nil && s.challenge.ServeChallenge(w, req) {
+		return
+	}
+	hd := s.hd.Load()
+	if hd == nil {
+		http.NotFound(w, req)
+		return
+	}
+	req = req.WithContext(context.WithValue(req.Context(), tunnelTrackerKey{}, s))
+	hd.ServeHTTP(w, req)
+}
+
+// 启动 HTTP 服务，Shutdown 后返回 nil
+func (s *Server) Run(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// 启动 HTTPS 服务，证书由 config.GetCertificate 提供
+func (s *Server) RunTLS(addr string, config *tls.Config) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.ServeTLS(l, config)
+}
+
+// 在指定监听上提供 HTTP 服务，支持 h2c
+func (s *Server) Serve(l net.Listener) error {
+	h2s := &http2.Server{}
+	svr := &http.Server{Handler: s.h2cHandler(h2s), ConnContext: withConn}
+	if err := http2.ConfigureServer(svr, h2s); err != nil {
+		return err
+	}
+	return s.serve(svr, l, func() error {
+		return svr.Serve(l)
+	})
+}
+
+// 在指定监听上提供 HTTPS 服务，支持 HTTP/2
+func (s *Server) ServeTLS(l net.Listener, config *tls.Config) error {
+	svr := &http.Server{Handler: s, TLSConfig: config.Clone()}
+	if err := http2.ConfigureServer(svr, &http2.Server{}); err != nil {
+		return err
+	}
+	return s.serve(svr, l, func() error {
+		return svr.ServeTLS(l, "", "")
+	})
+}
+
+type connContextKey struct{}
+
+func withConn(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connContextKey{}, c)
+}
+
+// 明文 HTTP/2 请求由 h2s 处理
+//
+// h2c 连接被劫持后不再由 http.Server 管理，作为长连接登记，
+// 停止服务时 http2.Server 通知客户端不再发起新请求
+func (s *Server) h2cHandler(h2s *http2.Server) http.Handler {
+	h := h2c.NewHandler(s, h2s)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if isH2C(req) {
+			if conn, ok := req.Context().Value(connContextKey{}).(net.Conn); ok {
+				defer s.trackTunnel(conn)()
+			}
+		}
+		h.ServeHTTP(w, req)
+	})
+}
+
+// 明文 HTTP/2 连接前言或升级请求
+func isH2C(req *http.Request) bool {
+	if req.Method == "PRI" && req.URL.Path == "*" && req.Proto == "HTTP/2.0" {
+		return true
+	}
+	return strings.EqualFold(req.Header.Get("Upgrade"), "h2c") && req.Header.Get("HTTP2-Settings") != ""
+}
+
+func (s *Server) serve(svr *http.Server, l net.Listener, run func() error) error {
+	s.mtx.Lock()
+	if s.closing {
+		s.mtx.Unlock()
+		l.Close()
+		return nil
+	}
+	s.servers[l] = svr
+	s.mtx.Unlock()
+
+	defer func() {
+		s.mtx.Lock()
+		delete(s.servers, l)
+		s.mtx.Unlock()
+	}()
+
+	if err := run(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// 停止服务
+//
+// 停止监听后等待正在处理的请求与长连接结束，ctx 超时后强制关闭剩余连接
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mtx.Lock()
+	s.closing = true
+	servers := make([]*http.Server, 0, len(s.servers))
+	for _, svr := range s.servers {
+		servers = append(servers, svr)
+	}
+	s.mtx.Unlock()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(servers))
+	for i, svr := range servers {
+		wg.Add(1)
+		go func(i int, svr *http.Server) {
+			defer wg.Done()
+			if err := svr.Shutdown(ctx); err != nil {
+				svr.Close()
+				errs[i] = err
+			}
+		}(i, svr)
+	}
+	wg.Wait()
+
+	// 与 http.Server.Shutdown 相同，轮询等待长连接结束
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for s.tunnelCount() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			s.mtx.Lock()
+			for conn := range s.tunnels {
+				conn.Close()
+			}
+			s.mtx.Unlock()
+			return ctx.Err()
+		}
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 停止指定监听上的服务，等待其正在处理的请求结束，用于切换监听地址
+//
+// 已劫持的长连接不受影响，由 Shutdown 统一处理
+func (s *Server) ShutdownListener(ctx context.Context, l net.Listener) error {
+	s.mtx.Lock()
+	svr, ok := s.servers[l]
+	s.mtx.Unlock()
+	if !ok {
+		return l.Close()
+	}
+	if err := svr.Shutdown(ctx); err != nil {
+		svr.Close()
+		return err
+	}
+	return nil
+}
+
+func (s *Server) tunnelCount() int {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
-	s.hd = h
+	return len(s.tunnels)
 }
 
-func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	s.hd.ServeHTTP(w, req)
+// 登记劫持的连接，返回连接结束时调用的函数
+func (s *Server) trackTunnel(conn net.Conn) func() {
+	s.mtx.Lock()
+	s.tunnels[conn] = struct{}{}
+	s.mtx.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mtx.Lock()
+			delete(s.tunnels, conn)
+			s.mtx.Unlock()
+		})
+	}
+}
+
+// 请求使用的长连接配置，请求不由 Server 处理时不限制超时
+func newTunnel(req *http.Request) *Tunnel {
+	if s, ok := req.Context().Value(tunnelTrackerKey{}).(*Server); ok {
+		t := *s.tunnel.Load()
+		return &t
+	}
+	return &Tunnel{}
 }
 
-func (s *Server) Run(addr string) {
-	http.ListenAndServe(addr, s)
+// 登记请求中劫持的连接，请求不由 Server 处理时不做记录
+func trackTunnel(req *http.Request, conn net.Conn) func() {
+	if s, ok := req.Context().Value(tunnelTrackerKey{}).(*Server); ok {
+		return s.trackTunnel(conn)
+	}
+	return func() {}
 }
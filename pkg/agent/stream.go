@@ -0,0 +1,445 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StreamNetworkTCP = "tcp"
+	StreamNetworkUDP = "udp"
+)
+
+const (
+	// 读取 TLS ClientHello 超时
+	defaultStreamPeekTimeout = 5 * time.Second
+	// UDP 会话空闲超时
+	defaultStreamIdleTimeout = 60 * time.Second
+	udpBufferSize            = 64 * 1024
+)
+
+var ErrNoStreamTarget = errors.New("no available stream target")
+
+// 四层转发规则
+type StreamRoute struct {
+	// TLS SNI 匹配的域名，支持通配符，为空时作为默认规则
+	//
+	// 同一监听地址任一规则设置域名后，所有连接先读取 ClientHello 再选择规则，
+	// 最长等待 PeekTimeout，服务端先发送数据的协议会因此延迟
+	ServerNames []string
+	Upstream    *Upstream
+	Stats       *TunnelStats
+}
+
+// 四层代理服务，同一监听地址的规则按 TLS SNI 选择
+type StreamServer struct {
+	Network string
+	Address string
+	// 读取 TLS ClientHello 超时
+	PeekTimeout time.Duration
+	// UDP 会话空闲超时
+	IdleTimeout time.Duration
+
+	routes atomic.Pointer[[]*StreamRoute]
+
+	mtx      sync.Mutex
+	closed   bool
+	ln       net.Listener
+	pc       net.PacketConn
+	conns    map[net.Conn]struct{}
+	sessions map[string]*udpSession
+}
+
+func NewStreamServer(network, address string) *StreamServer {
+	return &StreamServer{
+		Network:     network,
+		Address:     address,
+		PeekTimeout: defaultStreamPeekTimeout,
+		IdleTimeout: defaultStreamIdleTimeout,
+		conns:       map[net.Conn]struct{}{},
+		sessions:    map[string]*udpSession{},
+	}
+}
+
+// 替换转发规则，已建立的连接不受影响
+func (s *StreamServer) Update(routes []*StreamRoute) {
+	for _, v := range routes {
+		if v.Stats == nil {
+			v.Stats = &TunnelStats{}
+		}
+	}
+	s.routes.Store(&routes)
+}
+
+// 开始监听并在后台处理连接
+func (s *StreamServer) Start() error {
+	switch s.Network {
+	case StreamNetworkTCP:
+		ln, err := net.Listen("tcp", s.Address)
+		if err != nil {
+			return err
+		}
+		s.mtx.Lock()
+		s.ln = ln
+		s.mtx.Unlock()
+		go s.serveTCP(ln)
+	case StreamNetworkUDP:
+		pc, err := net.ListenPacket("udp", s.Address)
+		if err != nil {
+			return err
+		}
+		s.mtx.Lock()
+		s.pc = pc
+		s.mtx.Unlock()
+		go s.serveUDP(pc)
+	default:
+		return errors.New("unsupported stream network " + s.Network)
+	}
+	return nil
+}
+
+// 实际监听地址
+func (s *StreamServer) Addr() net.Addr {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.ln != nil {
+		return s.ln.Addr()
+	}
+	if s.pc != nil {
+		return s.pc.LocalAddr()
+	}
+	return nil
+}
+
+// 停止监听，等待 TCP 连接结束，ctx 超时后强制关闭；UDP 会话直接关闭
+func (s *StreamServer) Shutdown(ctx context.Context) error {
+	s.mtx.Lock()
+	s.closed = true
+	if s.ln != nil {
+		s.ln.Close()
+	}
+	if s.pc != nil {
+		s.pc.Close()
+	}
+	for _, v := range s.sessions {
+		v.conn.Close()
+	}
+	s.mtx.Unlock()
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for s.connCount() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			s.mtx.Lock()
+			for conn := range s.conns {
+				conn.Close()
+			}
+			s.mtx.Unlock()
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (s *StreamServer) connCount() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.conns)
+}
+
+func (s *StreamServer) loadRoutes() []*StreamRoute {
+	if v := s.routes.Load(); v != nil {
+		return *v
+	}
+	return nil
+}
+
+func (s *StreamServer) serveTCP(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return
+		}
+
+		s.mtx.Lock()
+		if s.closed {
+			s.mtx.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mtx.Unlock()
+
+		go func() {
+			defer func() {
+				s.mtx.Lock()
+				delete(s.conns, conn)
+				s.mtx.Unlock()
+			}()
+			s.handleTCP(conn)
+		}()
+	}
+}
+
+func (s *StreamServer) handleTCP(conn net.Conn) {
+	defer conn.Close()
+
+	routes := s.loadRoutes()
+
+	var client net.Conn = conn
+	serverName := ""
+	if hasServerNames(routes) {
+		conn.SetReadDeadline(time.Now().Add(s.PeekTimeout))
+		name, peeked := peekServerName(conn)
+		conn.SetReadDeadline(time.Time{})
+		serverName = name
+		client = &bufferedConn{Conn: conn, r: io.MultiReader(bytes.NewReader(peeked), conn)}
+	}
+
+	route := matchStreamRoute(routes, serverName)
+	if route == nil {
+		return
+	}
+
+	target, rmt, err := dialStream(route.Upstream, s.Network, conn.RemoteAddr(), serverName)
+	if err != nil {
+		printLog("stream %s dial error: %s\n", s.Address, err.Error())
+		return
+	}
+	defer rmt.Close()
+
+	atomic.AddInt64(&target.conns, 1)
+	defer atomic.AddInt64(&target.conns, -1)
+
+	tunnel := &Tunnel{Stats: route.Stats}
+	if _, _, err := tunnel.Run(client, rmt); err != nil {
+		printLog("stream %s tunnel error: %s\n", s.Address, err.Error())
+	}
+}
+
+// UDP 会话，按客户端地址区分
+type udpSession struct {
+	route  *StreamRoute
+	target *EndpointTarget
+	conn   net.Conn
+	// 最近收到客户端数据的时间
+	active atomic.Int64
+}
+
+func (s *StreamServer) serveUDP(pc net.PacketConn) {
+	buf := make([]byte, udpBufferSize)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return
+		}
+
+		sess, err := s.udpSession(pc, addr)
+		if err != nil {
+			printLog("stream %s dial error: %s\n", s.Address, err.Error())
+			continue
+		}
+		if sess == nil {
+			continue
+		}
+
+		sess.active.Store(time.Now().UnixNano())
+		if n, err := sess.conn.Write(buf[:n]); err == nil {
+			atomic.AddInt64(&sess.route.Stats.bytesIn, int64(n))
+		}
+	}
+}
+
+func (s *StreamServer) udpSession(pc net.PacketConn, addr net.Addr) (*udpSession, error) {
+	key := addr.String()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if sess, ok := s.sessions[key]; ok {
+		return sess, nil
+	}
+	if s.closed {
+		return nil, nil
+	}
+
+	route := matchStreamRoute(s.loadRoutes(), "")
+	if route == nil {
+		return nil, nil
+	}
+
+	target, conn, err := dialStream(route.Upstream, s.Network, addr, "")
+	if err != nil {
+		return nil, err
+	}
+
+	sess := &udpSession{route: route, target: target, conn: conn}
+	sess.active.Store(time.Now().UnixNano())
+	s.sessions[key] = sess
+
+	route.Stats.connect()
+	atomic.AddInt64(&target.conns, 1)
+	go s.replyUDP(pc, addr, sess)
+	return sess, nil
+}
+
+// 后端响应写回客户端，会话空闲超时后关闭
+func (s *StreamServer) replyUDP(pc net.PacketConn, addr net.Addr, sess *udpSession) {
+	defer func() {
+		s.mtx.Lock()
+		delete(s.sessions, addr.String())
+		s.mtx.Unlock()
+		sess.conn.Close()
+		sess.route.Stats.disconnect()
+		atomic.AddInt64(&sess.target.conns, -1)
+	}()
+
+	buf := make([]byte, udpBufferSize)
+	for {
+		sess.conn.SetReadDeadline(time.Now().Add(s.IdleTimeout))
+		n, err := sess.conn.Read(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				if time.Since(time.Unix(0, sess.active.Load())) < s.IdleTimeout {
+					continue
+				}
+			}
+			return
+		}
+		if n, err := pc.WriteTo(buf[:n], addr); err == nil {
+			atomic.AddInt64(&sess.route.Stats.bytesOut, int64(n))
+		}
+	}
+}
+
+func hasServerNames(routes []*StreamRoute) bool {
+	for _, v := range routes {
+		if len(v.ServerNames) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// 选择 SNI 匹配的规则，完全匹配优先于通配符，均不匹配时使用默认规则
+func matchStreamRoute(routes []*StreamRoute, serverName string) *StreamRoute {
+	var matched, fallback *StreamRoute
+	priority := 0
+	for _, v := range routes {
+		if len(v.ServerNames) == 0 {
+			if fallback == nil {
+				fallback = v
+			}
+			continue
+		}
+		if serverName == "" {
+			continue
+		}
+		if p := serverNamePriority(serverName, v.ServerNames); p > priority {
+			matched, priority = v, p
+		}
+	}
+	if matched != nil {
+		return matched
+	}
+	return fallback
+}
+
+func serverNamePriority(serverName string, patterns []string) int {
+	serverName = normalizeHost(serverName)
+	priority := 0
+	for _, v := range patterns {
+		if normalizeHost(v) == serverName {
+			return 2
+		}
+		if MatchHost(serverName, []string{v}) {
+			priority = 1
+		}
+	}
+	return priority
+}
+
+// 连接后端，连接失败时尝试其他目标
+func dialStream(u *Upstream, network string, remote net.Addr, serverName string) (*EndpointTarget, net.Conn, error) {
+	// 负载均衡按请求取值，使用客户端地址与 SNI 构造请求
+	req := &http.Request{Host: serverName, Header: http.Header{}, URL: &url.URL{}}
+	if remote != nil {
+		req.RemoteAddr = remote.String()
+	}
+
+	err := ErrNoStreamTarget
+	tried := []*EndpointTarget{}
+	for range u.Targets {
+		target := u.SelectExclude(req, tried)
+		if target == nil || inTargets(target, tried) {
+			break
+		}
+		tried = append(tried, target)
+
+		dialNetwork := target.Network
+		if network == StreamNetworkUDP {
+			dialNetwork = "udp"
+		} else if dialNetwork == "" {
+			dialNetwork = "tcp"
+		}
+
+		var conn net.Conn
+		conn, err = net.DialTimeout(dialNetwork, target.Address, u.Timeout)
+		u.Report(target, err)
+		if err == nil {
+			return target, conn, nil
+		}
+	}
+	return nil, nil, err
+}
+
+var errServerNameFound = errors.New("server name found")
+
+// 读取 TLS ClientHello 中的 SNI，返回已读取的数据以便转发给后端
+//
+// 非 TLS 连接或未携带 SNI 时返回空字符串
+func peekServerName(r io.Reader) (string, []byte) {
+	buf := &bytes.Buffer{}
+	serverName := ""
+	tls.Server(readOnlyConn{r: io.TeeReader(r, buf)}, &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName = hello.ServerName
+			return nil, errServerNameFound
+		},
+	}).Handshake()
+	return serverName, buf.Bytes()
+}
+
+// 只读连接，用于解析 ClientHello，不向客户端写入数据
+type readOnlyConn struct {
+	r io.Reader
+}
+
+func (c readOnlyConn) Read(b []byte) (int, error)         { return c.r.Read(b) }
+func (c readOnlyConn) Write(b []byte) (int, error)        { return 0, io.ErrClosedPipe }
+func (c readOnlyConn) Close() error                       { return nil }
+func (c readOnlyConn) LocalAddr() net.Addr                { return nil }
+func (c readOnlyConn) RemoteAddr() net.Addr               { return nil }
+func (c readOnlyConn) SetDeadline(t time.Time) error      { return nil }
+func (c readOnlyConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c readOnlyConn) SetWriteDeadline(t time.Time) error { return nil }
@@ -0,0 +1,246 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// 接受连接后写入名称并关闭
+func newNameServer(t *testing.T, name string, hit chan<- string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			hit <- name
+			conn.Write([]byte(name))
+			conn.Close()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func newTestStreamServer(t *testing.T, network string, routes []*StreamRoute, opts ...func(s *StreamServer)) *StreamServer {
+	s := NewStreamServer(network, "127.0.0.1:0")
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.Update(routes)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	})
+	return s
+}
+
+func testUpstream(network, address string) *Upstream {
+	return NewUpstream([]*EndpointTarget{{Network: network, Address: address}}, time.Second)
+}
+
+func waitActive(t *testing.T, stats *TunnelStats) {
+	for i := 0; i < 100 && stats.Active() > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := stats.Active(); n != 0 {
+		t.Fatalf("Active() = %d, want 0", n)
+	}
+}
+
+func TestStreamTCP(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 5)
+				io.ReadFull(conn, buf)
+				conn.Write(append(buf, '!'))
+			}()
+		}
+	}()
+
+	route := &StreamRoute{Upstream: testUpstream("tcp", echo.Addr().String())}
+	s := newTestStreamServer(t, StreamNetworkTCP, []*StreamRoute{route})
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("hello"))
+	got, _ := io.ReadAll(conn)
+	conn.Close()
+	if string(got) != "hello!" {
+		t.Errorf("response = %q, want hello!", got)
+	}
+
+	waitActive(t, route.Stats)
+	if route.Stats.Total() != 1 || route.Stats.BytesIn() != 5 || route.Stats.BytesOut() != 6 {
+		t.Errorf("stats total=%d in=%d out=%d, want 1 5 6", route.Stats.Total(), route.Stats.BytesIn(), route.Stats.BytesOut())
+	}
+}
+
+func TestStreamSNI(t *testing.T) {
+	hit := make(chan string, 1)
+	routes := []*StreamRoute{
+		{Upstream: testUpstream("tcp", newNameServer(t, "default", hit))},
+		{ServerNames: []string{"*.example.com"}, Upstream: testUpstream("tcp", newNameServer(t, "wildcard", hit))},
+		{ServerNames: []string{"a.example.com"}, Upstream: testUpstream("tcp", newNameServer(t, "exact", hit))},
+	}
+	s := newTestStreamServer(t, StreamNetworkTCP, routes)
+
+	tests := []struct {
+		serverName string
+		want       string
+	}{
+		{"a.example.com", "exact"},
+		{"b.example.com", "wildcard"},
+		{"other.com", "default"},
+	}
+
+	for _, tt := range tests {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		tls.Client(conn, &tls.Config{ServerName: tt.serverName, InsecureSkipVerify: true}).Handshake()
+		conn.Close()
+
+		select {
+		case got := <-hit:
+			if got != tt.want {
+				t.Errorf("server name %s routed to %s, want %s", tt.serverName, got, tt.want)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("server name %s not routed", tt.serverName)
+		}
+	}
+
+	// 非 TLS 连接使用默认规则
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("PING\r\n\r\n"))
+	if got := <-hit; got != "default" {
+		t.Errorf("plain connection routed to %s, want default", got)
+	}
+	conn.Close()
+}
+
+func TestStreamUDP(t *testing.T) {
+	echo, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			echo.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	route := &StreamRoute{Upstream: testUpstream("udp", echo.LocalAddr().String())}
+	s := newTestStreamServer(t, StreamNetworkUDP, []*StreamRoute{route}, func(s *StreamServer) {
+		s.IdleTimeout = 100 * time.Millisecond
+	})
+
+	conn, err := net.Dial("udp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for i := 0; i < 2; i++ {
+		conn.Write([]byte("ping"))
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		buf := make([]byte, 16)
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf[:n]) != "ping" {
+			t.Errorf("response = %q, want ping", buf[:n])
+		}
+	}
+
+	if route.Stats.Total() != 1 || route.Stats.BytesIn() != 8 || route.Stats.BytesOut() != 8 {
+		t.Errorf("stats total=%d in=%d out=%d, want 1 8 8", route.Stats.Total(), route.Stats.BytesIn(), route.Stats.BytesOut())
+	}
+
+	// 空闲超时后会话关闭
+	time.Sleep(300 * time.Millisecond)
+	waitActive(t, route.Stats)
+}
+
+func TestStreamShutdown(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	go func() {
+		for {
+			conn, err := backend.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(io.Discard, conn)
+		}
+	}()
+
+	route := &StreamRoute{Upstream: testUpstream("tcp", backend.Addr().String())}
+	s := NewStreamServer(StreamNetworkTCP, "127.0.0.1:0")
+	s.Update([]*StreamRoute{route})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("x"))
+	for i := 0; i < 100 && route.Stats.Active() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	waitActive(t, route.Stats)
+
+	if _, err := net.DialTimeout("tcp", s.Addr().String(), 100*time.Millisecond); err == nil {
+		t.Error("Dial() after Shutdown succeeded")
+	}
+}
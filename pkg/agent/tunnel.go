@@ -0,0 +1,251 @@
+package agent
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrTunnelIdle     = errors.New("tunnel idle timeout")
+	ErrTunnelLifetime = errors.New("tunnel max lifetime exceeded")
+)
+
+// 双向转发连接，如 websocket、CONNECT 与四层转发
+//
+// 一个方向读到 EOF 后半关闭另一端的写入，继续转发另一方向直到结束；
+// 连接不支持半关闭时直接关闭两端
+type Tunnel struct {
+	// 两个方向均无数据的超时，为 0 时不限制
+	IdleTimeout time.Duration
+	// 最长存活时间，为 0 时不限制
+	MaxLifetime time.Duration
+	// 连接与字节统计，可为空
+	Stats *TunnelStats
+}
+
+// 连接与字节统计
+type TunnelStats struct {
+	active   int64
+	total    int64
+	bytesIn  int64
+	bytesOut int64
+}
+
+// 当前连接数
+func (s *TunnelStats) Active() int64 {
+	return atomic.LoadInt64(&s.active)
+}
+
+// 累计连接数
+func (s *TunnelStats) Total() int64 {
+	return atomic.LoadInt64(&s.total)
+}
+
+// 客户端发往后端的字节数
+func (s *TunnelStats) BytesIn() int64 {
+	return atomic.LoadInt64(&s.bytesIn)
+}
+
+// 后端发往客户端的字节数
+func (s *TunnelStats) BytesOut() int64 {
+	return atomic.LoadInt64(&s.bytesOut)
+}
+
+func (s *TunnelStats) connect() {
+	atomic.AddInt64(&s.active, 1)
+	atomic.AddInt64(&s.total, 1)
+}
+
+func (s *TunnelStats) disconnect() {
+	atomic.AddInt64(&s.active, -1)
+}
+
+// 转发数据直到两个方向都结束，返回两个方向的字节数
+//
+// 返回时两端连接可能未关闭，由调用方关闭
+func (t *Tunnel) Run(client, remote net.Conn) (in, out int64, err error) {
+	if t.Stats != nil {
+		t.Stats.connect()
+		defer t.Stats.disconnect()
+	}
+
+	r := &tunnelRun{client: client, remote: remote}
+	r.touch()
+
+	var inCounter, outCounter *int64
+	if t.Stats != nil {
+		inCounter, outCounter = &t.Stats.bytesIn, &t.Stats.bytesOut
+	}
+
+	errCh := make(chan error, 2)
+	go func() {
+		errCh <- r.copy(remote, client, &in, inCounter)
+	}()
+	go func() {
+		errCh <- r.copy(client, remote, &out, outCounter)
+	}()
+
+	done := make(chan struct{})
+	watchDone := make(chan struct{})
+	go func() {
+		defer close(watchDone)
+		t.watch(r, done)
+	}()
+
+	for i := 0; i < 2; i++ {
+		if e := <-errCh; e != nil && err == nil {
+			err = e
+		}
+	}
+	close(done)
+	<-watchDone
+
+	if reason := r.reason(); reason != nil {
+		err = reason
+	}
+	return atomic.LoadInt64(&in), atomic.LoadInt64(&out), err
+}
+
+// 空闲与存活时间检查
+func (t *Tunnel) watch(r *tunnelRun, done <-chan struct{}) {
+	var lifetime, idle <-chan time.Time
+	if t.MaxLifetime > 0 {
+		timer := time.NewTimer(t.MaxLifetime)
+		defer timer.Stop()
+		lifetime = timer.C
+	}
+	var idleTimer *time.Timer
+	if t.IdleTimeout > 0 {
+		idleTimer = time.NewTimer(t.IdleTimeout)
+		defer idleTimer.Stop()
+		idle = idleTimer.C
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-lifetime:
+			r.close(ErrTunnelLifetime)
+			return
+		case <-idle:
+			elapsed := time.Since(time.Unix(0, r.active.Load()))
+			if elapsed >= t.IdleTimeout {
+				r.close(ErrTunnelIdle)
+				return
+			}
+			idleTimer.Reset(t.IdleTimeout - elapsed)
+		}
+	}
+}
+
+type tunnelRun struct {
+	client net.Conn
+	remote net.Conn
+	// 最近一次转发数据的时间
+	active atomic.Int64
+
+	once sync.Once
+	// 主动关闭的原因，关闭后的读写错误不再返回
+	closed atomic.Bool
+	err    error
+}
+
+func (r *tunnelRun) touch() {
+	r.active.Store(time.Now().UnixNano())
+}
+
+// 关闭两端连接，使阻塞的读写返回
+func (r *tunnelRun) close(reason error) {
+	r.once.Do(func() {
+		r.err = reason
+		r.closed.Store(true)
+		r.client.Close()
+		r.remote.Close()
+	})
+}
+
+func (r *tunnelRun) reason() error {
+	if r.closed.Load() {
+		return r.err
+	}
+	return nil
+}
+
+func (r *tunnelRun) copy(dst, src net.Conn, n, counter *int64) error {
+	w := &tunnelWriter{w: dst, r: r, n: n, counter: counter}
+	_, err := io.Copy(w, src)
+	if r.closed.Load() {
+		return nil
+	}
+	if err != nil {
+		r.close(nil)
+		return err
+	}
+	if err := closeWrite(dst); err != nil {
+		r.close(nil)
+	}
+	return nil
+}
+
+// 写入时累计字节数并刷新活跃时间
+type tunnelWriter struct {
+	w       io.Writer
+	r       *tunnelRun
+	n       *int64
+	counter *int64
+}
+
+func (w *tunnelWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	if n > 0 {
+		w.r.touch()
+		atomic.AddInt64(w.n, int64(n))
+		if w.counter != nil {
+			atomic.AddInt64(w.counter, int64(n))
+		}
+	}
+	return n, err
+}
+
+var errNoCloseWrite = errors.New("connection does not support close write")
+
+// 关闭连接写入，包装的连接通过 NetConn 获取底层连接
+func closeWrite(conn net.Conn) error {
+	for {
+		if v, ok := conn.(interface{ CloseWrite() error }); ok {
+			return v.CloseWrite()
+		}
+		v, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			return errNoCloseWrite
+		}
+		conn = v.NetConn()
+	}
+}
+
+// 带读取缓冲的连接，用于转发劫持或复用连接时已缓冲的数据
+type bufferedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func newBufferedConn(conn net.Conn, r *bufio.Reader) net.Conn {
+	if r == nil || r.Buffered() == 0 {
+		return conn
+	}
+	return &bufferedConn{Conn: conn, r: io.MultiReader(io.LimitReader(r, int64(r.Buffered())), conn)}
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func (c *bufferedConn) NetConn() net.Conn {
+	return c.Conn
+}
@@ -0,0 +1,268 @@
+package agent
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// 建立一对相连的 TCP 连接
+func tcpPair(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			accepted <- nil
+			return
+		}
+		accepted <- conn
+	}()
+
+	dialed, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := <-accepted
+	if conn == nil {
+		t.Fatal("accept failed")
+	}
+	return dialed, conn
+}
+
+// 等待测试启动的协程退出
+func checkGoroutines(t *testing.T, before int) {
+	t.Helper()
+	n := runtime.NumGoroutine()
+	for i := 0; i < 100 && n > before; i++ {
+		time.Sleep(20 * time.Millisecond)
+		n = runtime.NumGoroutine()
+	}
+	if n > before {
+		buf := make([]byte, 1<<16)
+		t.Errorf("goroutines = %d, want <= %d\n%s", n, before, buf[:runtime.Stack(buf, true)])
+	}
+}
+
+type tunnelResult struct {
+	in, out int64
+	err     error
+}
+
+func runTunnel(tunnel *Tunnel, client, remote net.Conn) <-chan tunnelResult {
+	ch := make(chan tunnelResult, 1)
+	go func() {
+		in, out, err := tunnel.Run(client, remote)
+		ch <- tunnelResult{in, out, err}
+	}()
+	return ch
+}
+
+func TestTunnelHalfClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	user, client := tcpPair(t)
+	remote, backend := tcpPair(t)
+	defer user.Close()
+	defer client.Close()
+	defer remote.Close()
+	defer backend.Close()
+
+	stats := &TunnelStats{}
+	ch := runTunnel(&Tunnel{Stats: stats}, client, remote)
+
+	// 后端读取到 EOF 后才响应
+	go func() {
+		data, _ := io.ReadAll(backend)
+		backend.Write(append([]byte("echo "), data...))
+		backend.Close()
+	}()
+
+	user.Write([]byte("hello"))
+	user.(*net.TCPConn).CloseWrite()
+
+	data, err := io.ReadAll(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "echo hello" {
+		t.Errorf("read %q, want %q", data, "echo hello")
+	}
+
+	rst := <-ch
+	if rst.err != nil {
+		t.Errorf("Run() error = %v", rst.err)
+	}
+	if rst.in != 5 || rst.out != 10 {
+		t.Errorf("Run() = %d %d, want 5 10", rst.in, rst.out)
+	}
+	if stats.Active() != 0 || stats.Total() != 1 || stats.BytesIn() != 5 || stats.BytesOut() != 10 {
+		t.Errorf("stats active=%d total=%d in=%d out=%d, want 0 1 5 10", stats.Active(), stats.Total(), stats.BytesIn(), stats.BytesOut())
+	}
+
+	user.Close()
+	client.Close()
+	remote.Close()
+	checkGoroutines(t, before)
+}
+
+func TestTunnelTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		tunnel *Tunnel
+		// 持续发送数据
+		active bool
+		want   error
+	}{
+		{"idle", &Tunnel{IdleTimeout: 100 * time.Millisecond}, false, ErrTunnelIdle},
+		{"active", &Tunnel{IdleTimeout: 100 * time.Millisecond, MaxLifetime: 400 * time.Millisecond}, true, ErrTunnelLifetime},
+		{"lifetime", &Tunnel{MaxLifetime: 100 * time.Millisecond}, false, ErrTunnelLifetime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := runtime.NumGoroutine()
+
+			user, client := tcpPair(t)
+			remote, backend := tcpPair(t)
+			defer user.Close()
+			defer backend.Close()
+
+			go io.Copy(io.Discard, backend)
+			stop := make(chan struct{})
+			if tt.active {
+				go func() {
+					ticker := time.NewTicker(20 * time.Millisecond)
+					defer ticker.Stop()
+					for {
+						select {
+						case <-stop:
+							return
+						case <-ticker.C:
+							if _, err := user.Write([]byte("ping")); err != nil {
+								return
+							}
+						}
+					}
+				}()
+			}
+
+			start := time.Now()
+			select {
+			case rst := <-runTunnel(tt.tunnel, client, remote):
+				if !errors.Is(rst.err, tt.want) {
+					t.Errorf("Run() error = %v, want %v", rst.err, tt.want)
+				}
+				if tt.active && time.Since(start) < tt.tunnel.MaxLifetime {
+					t.Errorf("closed after %s, want >= %s", time.Since(start), tt.tunnel.MaxLifetime)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("tunnel not closed")
+			}
+			close(stop)
+
+			// 超时后两端连接被关闭
+			user.SetReadDeadline(time.Now().Add(time.Second))
+			if _, err := user.Read(make([]byte, 1)); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+				t.Errorf("client read error = %v, want closed", err)
+			}
+
+			user.Close()
+			backend.Close()
+			checkGoroutines(t, before)
+		})
+	}
+}
+
+// 转发到固定地址
+type testForward struct {
+	address string
+}
+
+func (f testForward) ForwardTarget(req *http.Request) (string, string, time.Duration) {
+	return "tcp", f.address, time.Second
+}
+
+func TestTunnelWebsocketNoLeak(t *testing.T) {
+	// 后端升级后回显数据
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	go func() {
+		for {
+			conn, err := backend.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				br := bufio.NewReader(conn)
+				if _, err := http.ReadRequest(br); err != nil {
+					return
+				}
+				conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n"))
+				io.Copy(conn, br)
+			}()
+		}
+	}()
+
+	s := New()
+	s.SetTunnelTimeout(time.Second, 0)
+	s.Use(http.HandlerFunc(NewBasicForwardHandler(testForward{backend.Addr().String()}).HandleRequest))
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go s.Serve(l)
+
+	// 等待服务协程启动
+	time.Sleep(20 * time.Millisecond)
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 5; i++ {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 升级请求后紧跟数据，验证劫持缓冲中的数据被转发
+		conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\nhello"))
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+		}
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(br, buf); err != nil || string(buf) != "hello" {
+			t.Fatalf("read %q %v, want hello", buf, err)
+		}
+		conn.Close()
+	}
+
+	for i := 0; i < 100 && s.tunnelCount() > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := s.tunnelCount(); n != 0 {
+		t.Errorf("tunnelCount() = %d, want 0", n)
+	}
+	if stats := s.TunnelStats(); stats.Total() != 5 || stats.Active() != 0 || stats.BytesOut() != 25 {
+		t.Errorf("stats total=%d active=%d out=%d, want 5 0 25", stats.Total(), stats.Active(), stats.BytesOut())
+	}
+	checkGoroutines(t, before)
+}
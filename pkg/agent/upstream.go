@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 后端服务，同一后端的多个路由共享
+type Upstream struct {
+	Targets  []*EndpointTarget
+	Timeout  time.Duration
+	Balancer Balancer
+	// 会话保持，为空不启用
+	Sticky *StickySession
+	// 主动健康检查，为空不启用
+	HealthCheck *HealthCheck
+	// 被动健康检查，为空不启用
+	Passive *PassiveCheck
+	// 连接池，为空不复用连接
+	Pool *ConnPool
+	// 重试策略，为空不重试
+	Retry *RetryPolicy
+
+	mtx    sync.Mutex
+	cancel context.CancelFunc
+}
+
+type StickySession struct {
+	Cookie string
+	MaxAge int
+}
+
+func NewUpstream(targets []*EndpointTarget, timeout time.Duration) *Upstream {
+	return &Upstream{Targets: targets, Timeout: timeout, Balancer: NewRandomBalancer()}
+}
+
+// 选择转发目标，启用会话保持时优先使用 cookie 记录的可用目标
+func (u *Upstream) Select(w http.ResponseWriter, req *http.Request) *EndpointTarget {
+	target := u.SelectExclude(req, nil)
+	u.Stick(w, req, target)
+	return target
+}
+
+// 选择转发目标并排除指定目标，全部排除时从可用目标中选择
+func (u *Upstream) SelectExclude(req *http.Request, exclude []*EndpointTarget) *EndpointTarget {
+	targets := u.available()
+
+	if len(exclude) > 0 {
+		candidates := make([]*EndpointTarget, 0, len(targets))
+		for _, t := range targets {
+			if !inTargets(t, exclude) {
+				candidates = append(candidates, t)
+			}
+		}
+		if len(candidates) > 0 {
+			targets = candidates
+		}
+	}
+
+	if u.Sticky != nil {
+		if c, err := req.Cookie(u.Sticky.Cookie); err == nil {
+			for _, t := range targets {
+				if t.Key() == c.Value {
+					return t
+				}
+			}
+		}
+	}
+
+	return u.Balancer.Select(req, targets)
+}
+
+// 会话保持目标变化时写入 cookie
+func (u *Upstream) Stick(w http.ResponseWriter, req *http.Request, target *EndpointTarget) {
+	if target == nil || u.Sticky == nil {
+		return
+	}
+
+	if c, err := req.Cookie(u.Sticky.Cookie); err == nil && c.Value == target.Key() {
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     u.Sticky.Cookie,
+		Value:    target.Key(),
+		Path:     "/",
+		MaxAge:   u.Sticky.MaxAge,
+		HttpOnly: true,
+	})
+}
+
+func inTargets(target *EndpointTarget, targets []*EndpointTarget) bool {
+	for _, t := range targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+type targetContextKey struct{}
+
+func withTarget(req *http.Request, target *EndpointTarget) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), targetContextKey{}, target))
+}
+
+// 获取请求选中的转发目标
+func TargetFrom(req *http.Request) *EndpointTarget {
+	if v, ok := req.Context().Value(targetContextKey{}).(*EndpointTarget); ok {
+		return v
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package agent
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 )
@@ -28,6 +29,9 @@ func TestPath(pattern, path string) (bool, url.Values, error) {
 			for ; nameEnd < pnN && pattern[nameEnd] != '}'; nameEnd++ {
 			}
 
+			if nameEnd >= pnN {
+				return false, nil, ErrInvalidPattern
+			}
 			name := pattern[j+1 : nameEnd]
 			if pattern[nameEnd] != '}' {
 				return false, nil, ErrInvalidPattern
@@ -67,9 +71,7 @@ func TestPath(pattern, path string) (bool, url.Values, error) {
 func VarFrom(req *http.Request, source, name string) string {
 	switch source {
 	case "cookie":
-		if c, err := req.Cookie(name); err != http.ErrNoCookie {
-			return ""
-		} else if c != nil {
+		if c, err := req.Cookie(name); err == nil {
 			return c.Value
 		}
 	case "header":
@@ -80,10 +82,21 @@ func VarFrom(req *http.Request, source, name string) string {
 		if v := req.URL.Query().Get(name); v != "" {
 			return v
 		}
+	case "ip":
+		return RemoteIP(req)
 	}
 	return ""
 }
 
+// 获取客户端地址
+func RemoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
 func InStringSlice(v string, slice []string) bool {
 	for _, m := range slice {
 		if v == m {
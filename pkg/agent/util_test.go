@@ -23,6 +23,7 @@ func TestMatchPath(t *testing.T) {
 		{"/{name}.{ext}", "/foo.txt", true, url.Values{"name": []string{"foo"}, "ext": []string{"txt"}}, nil},
 		{"/{name}/{value}/{test}/test", "/foo1/foo2/foo3/test", true, url.Values{"name": []string{"foo1"}, "value": []string{"foo2"}, "test": []string{"foo3"}}, nil},
 		{"/{name}/{value}/{test}", "/foo1/foo2/foo3", true, url.Values{"name": []string{"foo1"}, "value": []string{"foo2"}, "test": []string{"foo3"}}, nil},
+		{"/{name", "/foo", false, nil, ErrInvalidPattern},
 	}
 	for _, tt := range tests {
 		t.Run(tt.pattern, func(t *testing.T) {
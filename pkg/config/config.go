@@ -16,6 +16,8 @@ type ConfigProvider interface {
 	Get(name string) (value string, err error)
 	Bind(target interface{}) error
 	Engine() interface{}
+	// 监听配置变更，配置重新加载后调用 fn，返回取消监听的函数
+	Watch(fn func()) (cancel func())
 }
 
 // 从上下文中获取配置
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// 配置项变更
+//
+// 字段通过 env 标签命名，带有 reload:"true" 标签的字段可在运行时重新加载，
+// 其余字段变更后需要重启
+type Change struct {
+	// 配置项名称
+	Name string
+	// 是否需要重启生效
+	Restart bool
+}
+
+// 比较两个相同类型的配置结构，返回变更的配置项
+func Diff(prev, next interface{}) []Change {
+	ov := reflect.Indirect(reflect.ValueOf(prev))
+	nv := reflect.Indirect(reflect.ValueOf(next))
+	if ov.Type() != nv.Type() || ov.Kind() != reflect.Struct {
+		panic("config: Diff requires two structs of the same type")
+	}
+
+	changes := []Change{}
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+
+		name := strings.SplitN(field.Tag.Get("env"), ",", 2)[0]
+		if name == "" {
+			name = field.Name
+		}
+		changes = append(changes, Change{Name: name, Restart: field.Tag.Get("reload") != "true"})
+	}
+	return changes
+}
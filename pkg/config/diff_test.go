@@ -0,0 +1,30 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	type testConfig struct {
+		Path     string        `env:"PATH"`
+		Name     string        `env:"NAME" reload:"true"`
+		Interval time.Duration `env:"INTERVAL" reload:"true"`
+		List     []string      `env:"LIST"`
+	}
+
+	old := testConfig{Path: "a", Name: "n", Interval: time.Second, List: []string{"x"}}
+
+	if got := Diff(&old, old); len(got) != 0 {
+		t.Errorf("Diff() same = %v, want empty", got)
+	}
+
+	next := old
+	next.Name = "m"
+	next.List = []string{"x", "y"}
+	want := []Change{{Name: "NAME"}, {Name: "LIST", Restart: true}}
+	if got := Diff(&old, &next); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+}
@@ -1,16 +1,32 @@
 package env
 
 import (
+	"context"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
 )
 
 type EnvConfigProvider struct {
+	// .env 文件，为空时不从文件加载
+	files []string
+
+	mtx sync.Mutex
+	// 启动时已存在的环境变量，不被文件覆盖
+	preset map[string]bool
+	// 从文件设置的环境变量
+	loaded map[string]string
+	// 文件修改时间
+	modTime  map[string]time.Time
+	watchers map[int]func()
+	watchId  int
 }
 
-func (EnvConfigProvider) Get(name string) (value string, err error) {
+func (p *EnvConfigProvider) Get(name string) (value string, err error) {
 	v := os.Getenv(name)
 	return v, nil
 }
@@ -26,10 +42,137 @@ func (p *EnvConfigProvider) Engine() interface{} {
 	return p
 }
 
-func NewDotEnvConfig() (*EnvConfigProvider, error) {
-	cfg := &EnvConfigProvider{}
-	err := godotenv.Load()
+func (p *EnvConfigProvider) Watch(fn func()) (cancel func()) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.watchId++
+	id := p.watchId
+	p.watchers[id] = fn
+	return func() {
+		p.mtx.Lock()
+		defer p.mtx.Unlock()
+		delete(p.watchers, id)
+	}
+}
+
+// 重新读取 .env 文件并通知监听者
+//
+// 文件中删除的配置项从环境变量中移除，启动时已存在的环境变量保持不变
+func (p *EnvConfigProvider) Reload() error {
+	if err := p.load(); err != nil {
+		return err
+	}
+
+	p.mtx.Lock()
+	watchers := make([]func(), 0, len(p.watchers))
+	for _, fn := range p.watchers {
+		watchers = append(watchers, fn)
+	}
+	p.mtx.Unlock()
+
+	for _, fn := range watchers {
+		fn()
+	}
+	return nil
+}
+
+// 定时检查 .env 文件修改时间，文件变更后重新加载，ctx 结束后返回
+//
+// 加载失败时调用 onError，为空时忽略错误
+func (p *EnvConfigProvider) WatchFile(ctx context.Context, interval time.Duration, onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		if !p.fileChanged() {
+			continue
+		}
+		if err := p.Reload(); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+func (p *EnvConfigProvider) fileChanged() bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for _, name := range p.files {
+		info, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(p.modTime[name]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *EnvConfigProvider) load() error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	modTime := map[string]time.Time{}
+	for _, name := range p.files {
+		if info, err := os.Stat(name); err == nil {
+			modTime[name] = info.ModTime()
+		}
+	}
+
+	values, err := godotenv.Read(p.files...)
 	if err != nil {
+		return err
+	}
+
+	loaded := map[string]string{}
+	for k, v := range values {
+		if p.preset[k] {
+			continue
+		}
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+		loaded[k] = v
+	}
+
+	for k := range p.loaded {
+		if _, ok := loaded[k]; !ok {
+			os.Unsetenv(k)
+		}
+	}
+
+	p.loaded = loaded
+	p.modTime = modTime
+	return nil
+}
+
+// 从 .env 文件加载配置，未指定文件时使用当前目录下的 .env
+func NewDotEnvConfig(files ...string) (*EnvConfigProvider, error) {
+	if len(files) == 0 {
+		files = []string{".env"}
+	}
+
+	cfg := &EnvConfigProvider{
+		files:    files,
+		preset:   map[string]bool{},
+		watchers: map[int]func(){},
+	}
+
+	for _, kv := range os.Environ() {
+		if k, _, ok := strings.Cut(kv, "="); ok {
+			cfg.preset[k] = true
+		}
+	}
+
+	if err := cfg.load(); err != nil {
 		return nil, err
 	}
 	return cfg, nil
@@ -0,0 +1,93 @@
+package env
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDotEnvReload(t *testing.T) {
+	name := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(name, []byte("TEST_DOTENV_A=1\nTEST_DOTENV_B=2\nTEST_DOTENV_PRESET=file\n"), 0644)
+	t.Setenv("TEST_DOTENV_PRESET", "env")
+	t.Cleanup(func() {
+		os.Unsetenv("TEST_DOTENV_A")
+		os.Unsetenv("TEST_DOTENV_B")
+		os.Unsetenv("TEST_DOTENV_C")
+	})
+
+	p, err := NewDotEnvConfig(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notified := 0
+	cancel := p.Watch(func() { notified++ })
+
+	if p.fileChanged() {
+		t.Error("fileChanged() = true before modify")
+	}
+
+	os.WriteFile(name, []byte("TEST_DOTENV_A=3\nTEST_DOTENV_C=4\nTEST_DOTENV_PRESET=file\n"), 0644)
+	if err := p.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"TEST_DOTENV_A":      "3",
+		"TEST_DOTENV_B":      "",
+		"TEST_DOTENV_C":      "4",
+		"TEST_DOTENV_PRESET": "env",
+	}
+	for k, v := range want {
+		if got, _ := p.Get(k); got != v {
+			t.Errorf("Get(%s) = %q, want %q", k, got, v)
+		}
+	}
+	if notified != 1 {
+		t.Errorf("notified = %d, want 1", notified)
+	}
+
+	cancel()
+	p.Reload()
+	if notified != 1 {
+		t.Errorf("notified after cancel = %d, want 1", notified)
+	}
+}
+
+func TestDotEnvWatchFileError(t *testing.T) {
+	name := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(name, []byte("TEST_DOTENV_WATCH=1\n"), 0644)
+	t.Cleanup(func() { os.Unsetenv("TEST_DOTENV_WATCH") })
+
+	p, err := NewDotEnvConfig(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go p.WatchFile(ctx, 10*time.Millisecond, func(err error) {
+		select {
+		case errCh <- err:
+		default:
+		}
+	})
+
+	// 引号未闭合，加载失败
+	os.WriteFile(name, []byte("TEST_DOTENV_WATCH=\"2\n"), 0644)
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(name, future, future)
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Error("onError(nil), want error")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("onError not called")
+	}
+}
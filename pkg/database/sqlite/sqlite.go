@@ -32,3 +32,12 @@ func (s *DataSource) Transaction(fn func(s database.DataSource) error) error {
 		return fn(NewSQLiteDataSource(tx))
 	})
 }
+
+// 关闭数据库连接
+func (s *DataSource) Close() error {
+	db, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
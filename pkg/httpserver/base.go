@@ -1,9 +1,12 @@
 package httpserver
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -34,16 +37,80 @@ func (e *HttpError) Respond(c *gin.Context) {
 func New() *HttpServer {
 	g := gin.Default()
 	g.ContextWithFallback = true
-	s := &HttpServer{engine: g}
+	s := &HttpServer{engine: g, servers: map[net.Listener]*http.Server{}}
 	return s
 }
 
 type HttpServer struct {
 	engine *gin.Engine
+
+	mtx     sync.Mutex
+	closing bool
+	servers map[net.Listener]*http.Server
+}
+
+// 启动服务，Shutdown 后返回 nil
+func (s *HttpServer) Run(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// 在指定监听上提供服务，Shutdown 后返回 nil
+func (s *HttpServer) Serve(l net.Listener) error {
+	svr := &http.Server{Handler: s.engine}
+
+	s.mtx.Lock()
+	if s.closing {
+		s.mtx.Unlock()
+		l.Close()
+		return nil
+	}
+	s.servers[l] = svr
+	s.mtx.Unlock()
+
+	defer func() {
+		s.mtx.Lock()
+		delete(s.servers, l)
+		s.mtx.Unlock()
+	}()
+
+	if err := svr.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// 停止指定监听上的服务，等待其正在处理的请求结束
+func (s *HttpServer) ShutdownListener(ctx context.Context, l net.Listener) error {
+	s.mtx.Lock()
+	svr, ok := s.servers[l]
+	s.mtx.Unlock()
+	if !ok {
+		return l.Close()
+	}
+	return svr.Shutdown(ctx)
 }
 
-func (s *HttpServer) Run(addr ...string) {
-	s.engine.Run(addr...)
+// 停止服务，等待正在处理的请求结束
+func (s *HttpServer) Shutdown(ctx context.Context) error {
+	s.mtx.Lock()
+	s.closing = true
+	servers := make([]*http.Server, 0, len(s.servers))
+	for _, svr := range s.servers {
+		servers = append(servers, svr)
+	}
+	s.mtx.Unlock()
+
+	var rst error
+	for _, svr := range servers {
+		if err := svr.Shutdown(ctx); err != nil && rst == nil {
+			rst = err
+		}
+	}
+	return rst
 }
 
 func (s *HttpServer) Use(middleware ...gin.HandlerFunc) {
@@ -0,0 +1,332 @@
+// PROXY 协议 v1/v2 监听
+//
+// 部署在四层负载均衡后时，通过 PROXY 协议头获取客户端真实地址
+// https://www.haproxy.org/download/2.9/doc/proxy-protocol.txt
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoHeader      = errors.New("proxyproto: missing proxy protocol header")
+	ErrInvalidHeader = errors.New("proxyproto: invalid proxy protocol header")
+)
+
+// v2 协议头签名
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// v1 协议头最大长度
+const v1MaxLength = 107
+
+// 读取协议头默认超时
+const DefaultHeaderTimeout = 5 * time.Second
+
+type Listener struct {
+	net.Listener
+	// 读取协议头超时，为 0 时不限制
+	HeaderTimeout time.Duration
+	// 允许发送协议头的来源地址，其他来源的连接直接关闭；为空时不限制
+	Trusted []*net.IPNet
+}
+
+// 包装监听，接受的连接必须以 PROXY 协议头开始
+func NewListener(l net.Listener) *Listener {
+	return &Listener{Listener: l, HeaderTimeout: DefaultHeaderTimeout}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		// 不可信来源可伪造客户端地址
+		if !l.trusted(conn.RemoteAddr()) {
+			conn.Close()
+			continue
+		}
+		return NewConn(conn, l.HeaderTimeout), nil
+	}
+}
+
+func (l *Listener) trusted(addr net.Addr) bool {
+	if len(l.Trusted) == 0 {
+		return true
+	}
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		// unix socket 只有本机可以连接
+		return addr.Network() == "unix"
+	}
+	for _, v := range l.Trusted {
+		if v.Contains(tcp.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析可信来源，支持 CIDR 与单个 IP
+func ParseTrusted(values []string) ([]*net.IPNet, error) {
+	nets := []*net.IPNet{}
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("proxyproto: invalid trusted address %s", v)
+			}
+			bits := net.IPv6len * 8
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, net.IPv4len*8
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("proxyproto: invalid trusted address %s", v)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// PROXY 协议连接
+//
+// 协议头在首次调用 Read、RemoteAddr 或 LocalAddr 时读取，不阻塞 Accept
+type Conn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+
+	once   sync.Once
+	err    error
+	remote net.Addr
+	local  net.Addr
+}
+
+func NewConn(conn net.Conn, timeout time.Duration) *Conn {
+	return &Conn{Conn: conn, r: bufio.NewReader(conn), timeout: timeout}
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+// 客户端地址，协议头为 LOCAL 命令或未知协议时为实际连接地址
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.local != nil {
+		return c.local
+	}
+	return c.Conn.LocalAddr()
+}
+
+// 底层连接
+func (c *Conn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// 读取协议头错误
+func (c *Conn) HeaderError() error {
+	c.once.Do(c.readHeader)
+	return c.err
+}
+
+func (c *Conn) readHeader() {
+	if c.timeout > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+		defer c.Conn.SetReadDeadline(time.Time{})
+	}
+
+	c.remote, c.local, c.err = ReadHeader(c.r)
+	if c.err != nil {
+		c.Conn.Close()
+	}
+}
+
+// 读取 PROXY 协议头，返回客户端与服务端地址
+//
+// LOCAL 命令与 UNKNOWN 协议返回的地址为 nil
+func ReadHeader(r *bufio.Reader) (remote, local net.Addr, err error) {
+	sig, err := r.Peek(len(v2Signature))
+	if err == nil && bytes.Equal(sig, v2Signature) {
+		return readV2(r)
+	}
+
+	prefix, err := r.Peek(6)
+	if err != nil {
+		return nil, nil, ErrNoHeader
+	}
+	if string(prefix) == "PROXY " {
+		return readV1(r)
+	}
+	return nil, nil, ErrNoHeader
+}
+
+func readV1(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	line := make([]byte, 0, v1MaxLength)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, nil, ErrInvalidHeader
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+		if len(line) >= v1MaxLength {
+			return nil, nil, ErrInvalidHeader
+		}
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, nil, ErrInvalidHeader
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) < 2 {
+		return nil, nil, ErrInvalidHeader
+	}
+
+	switch fields[1] {
+	case "UNKNOWN":
+		return nil, nil, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, nil, ErrInvalidHeader
+	}
+
+	if len(fields) != 6 {
+		return nil, nil, ErrInvalidHeader
+	}
+
+	src, err := parseV1Addr(fields[1], fields[2], fields[4])
+	if err != nil {
+		return nil, nil, err
+	}
+	dst, err := parseV1Addr(fields[1], fields[3], fields[5])
+	if err != nil {
+		return nil, nil, err
+	}
+	return src, dst, nil
+}
+
+func parseV1Addr(proto, ip, port string) (net.Addr, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil || (proto == "TCP4") != (addr.To4() != nil) {
+		return nil, ErrInvalidHeader
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, ErrInvalidHeader
+	}
+	return &net.TCPAddr{IP: addr, Port: int(p)}, nil
+}
+
+const (
+	v2CommandLocal = 0x0
+	v2CommandProxy = 0x1
+
+	v2FamilyUnspec = 0x0
+	v2FamilyInet   = 0x1
+	v2FamilyInet6  = 0x2
+	v2FamilyUnix   = 0x3
+
+	v2TransportStream = 0x1
+	v2TransportDgram  = 0x2
+)
+
+func readV2(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil, ErrInvalidHeader
+	}
+
+	if header[12]>>4 != 0x2 {
+		return nil, nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidHeader, header[12]>>4)
+	}
+
+	command := header[12] & 0xf
+	family := header[13] >> 4
+	transport := header[13] & 0xf
+	length := binary.BigEndian.Uint16(header[14:16])
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, nil, ErrInvalidHeader
+	}
+
+	switch command {
+	case v2CommandLocal:
+		return nil, nil, nil
+	case v2CommandProxy:
+	default:
+		return nil, nil, fmt.Errorf("%w: unsupported command %d", ErrInvalidHeader, command)
+	}
+
+	switch family {
+	case v2FamilyInet, v2FamilyInet6:
+		size := net.IPv4len
+		if family == v2FamilyInet6 {
+			size = net.IPv6len
+		}
+		if len(body) < size*2+4 {
+			return nil, nil, ErrInvalidHeader
+		}
+		srcIP := net.IP(append([]byte{}, body[:size]...))
+		dstIP := net.IP(append([]byte{}, body[size:size*2]...))
+		srcPort := int(binary.BigEndian.Uint16(body[size*2:]))
+		dstPort := int(binary.BigEndian.Uint16(body[size*2+2:]))
+		if transport == v2TransportDgram {
+			return &net.UDPAddr{IP: srcIP, Port: srcPort}, &net.UDPAddr{IP: dstIP, Port: dstPort}, nil
+		}
+		return &net.TCPAddr{IP: srcIP, Port: srcPort}, &net.TCPAddr{IP: dstIP, Port: dstPort}, nil
+	case v2FamilyUnix:
+		const size = 108
+		if len(body) < size*2 {
+			return nil, nil, ErrInvalidHeader
+		}
+		network := "unix"
+		if transport == v2TransportDgram {
+			network = "unixgram"
+		}
+		src := &net.UnixAddr{Name: unixName(body[:size]), Net: network}
+		dst := &net.UnixAddr{Name: unixName(body[size : size*2]), Net: network}
+		return src, dst, nil
+	case v2FamilyUnspec:
+		return nil, nil, nil
+	}
+	return nil, nil, fmt.Errorf("%w: unsupported family %d", ErrInvalidHeader, family)
+}
+
+func unixName(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
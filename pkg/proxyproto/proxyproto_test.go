@@ -0,0 +1,165 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func v2Header(command, family byte, body []byte) []byte {
+	buf := bytes.NewBuffer(nil)
+	buf.Write(v2Signature)
+	buf.WriteByte(0x20 | command)
+	buf.WriteByte(family)
+	binary.Write(buf, binary.BigEndian, uint16(len(body)))
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+func TestReadHeader(t *testing.T) {
+	tcp4 := append(append(net.IPv4(10, 0, 0, 1).To4(), net.IPv4(10, 0, 0, 2).To4()...), 0x30, 0x39, 0x00, 0x50)
+	tcp6 := append(append(net.ParseIP("2001:db8::1").To16(), net.ParseIP("2001:db8::2").To16()...), 0x30, 0x39, 0x01, 0xbb)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		remote string
+		local  string
+		err    error
+	}{
+		{"v1 tcp4", []byte("PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\nGET /"), "192.168.0.1:56324", "192.168.0.11:443", nil},
+		{"v1 tcp6", []byte("PROXY TCP6 ::1 ::2 1 2\r\nGET /"), "[::1]:1", "[::2]:2", nil},
+		{"v1 unknown", []byte("PROXY UNKNOWN\r\nGET /"), "", "", nil},
+		{"v1 family mismatch", []byte("PROXY TCP4 ::1 ::2 1 2\r\nGET /"), "", "", ErrInvalidHeader},
+		{"v1 no crlf", []byte("PROXY TCP4 1.1.1.1 2.2.2.2 1 2\nGET /"), "", "", ErrInvalidHeader},
+		{"v1 too long", []byte("PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n"), "", "", ErrInvalidHeader},
+		{"v2 tcp4", append(v2Header(v2CommandProxy, 0x11, tcp4), "GET /"...), "10.0.0.1:12345", "10.0.0.2:80", nil},
+		{"v2 tcp6", append(v2Header(v2CommandProxy, 0x21, tcp6), "GET /"...), "[2001:db8::1]:12345", "[2001:db8::2]:443", nil},
+		{"v2 local", append(v2Header(v2CommandLocal, 0x00, nil), "GET /"...), "", "", nil},
+		{"v2 short", v2Header(v2CommandProxy, 0x11, tcp4[:6]), "", "", ErrInvalidHeader},
+		{"no header", []byte("GET / HTTP/1.1\r\n"), "", "", ErrNoHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewReader(tt.data))
+			remote, local, err := ReadHeader(r)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ReadHeader() error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if got := addrString(remote); got != tt.remote {
+				t.Errorf("ReadHeader() remote = %q, want %q", got, tt.remote)
+			}
+			if got := addrString(local); got != tt.local {
+				t.Errorf("ReadHeader() local = %q, want %q", got, tt.local)
+			}
+			if rest, _ := io.ReadAll(r); string(rest) != "GET /" {
+				t.Errorf("ReadHeader() rest = %q, want %q", rest, "GET /")
+			}
+		})
+	}
+}
+
+func addrString(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
+
+func TestListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svr := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	})}
+	go svr.Serve(NewListener(l))
+	defer svr.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("PROXY TCP4 203.0.113.7 10.0.0.1 40000 80\r\nGET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "203.0.113.7:40000" {
+		t.Errorf("RemoteAddr = %q, want %q", body, "203.0.113.7:40000")
+	}
+
+	// 缺少协议头的连接被关闭
+	conn2, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn2.Close()
+	conn2.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	if _, err := http.ReadResponse(bufio.NewReader(conn2), nil); err == nil {
+		t.Error("request without header got response")
+	}
+}
+
+// 只接受可信来源的连接，防止伪造客户端地址
+func TestListenerTrusted(t *testing.T) {
+	serve := func(trusted string) string {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		nets, err := ParseTrusted([]string{trusted})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pl := NewListener(l)
+		pl.Trusted = nets
+
+		svr := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.RemoteAddr))
+		})}
+		go svr.Serve(pl)
+		t.Cleanup(func() { svr.Close() })
+		return l.Addr().String()
+	}
+	request := func(addr string) (string, error) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return "", err
+		}
+		defer conn.Close()
+		conn.Write([]byte("PROXY TCP4 203.0.113.7 10.0.0.1 40000 80\r\nGET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			return "", err
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return string(body), nil
+	}
+
+	if got, err := request(serve("127.0.0.0/8")); err != nil || got != "203.0.113.7:40000" {
+		t.Errorf("trusted RemoteAddr = %q %v, want 203.0.113.7:40000", got, err)
+	}
+	if got, err := request(serve("10.0.0.1")); err == nil {
+		t.Errorf("untrusted peer got response %q", got)
+	}
+
+	if _, err := ParseTrusted([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("ParseTrusted() accepted invalid cidr")
+	}
+}
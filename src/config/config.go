@@ -2,14 +2,47 @@ package config
 
 import (
 	"context"
+	"time"
 
 	"dxkite.cn/meownest/pkg/config"
 )
 
+// 服务配置，带有 reload:"true" 标签的配置项可通过 SIGHUP 或修改 .env 重新加载
 type Config struct {
 	DataPath         string `env:"DATA_PATH"`
-	SessionName      string `env:"SESSION_NAME" envDefault:"session_id"`
+	SessionName      string `env:"SESSION_NAME" envDefault:"session_id" reload:"true"`
 	SessionCryptoKey string `env:"SESSION_CRYPTO_KEY" envDefault:"12345678901234567890123456789012"`
+	// 退出时等待请求与长连接结束的时长，超时后强制关闭
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"30s" reload:"true"`
+
+	// 管理接口监听，默认只允许本机访问，格式见 ParseListen
+	AdminListen string `env:"ADMIN_LISTEN" envDefault:"http://127.0.0.1:2333" reload:"true"`
+	// 代理服务监听，多个监听以逗号分隔，格式见 ParseListen，如 http://:80,https://:443
+	AgentListen []string `env:"AGENT_LISTEN" envDefault:"http://:80" reload:"true"`
+	// websocket 等长连接双向无数据的超时，为 0 时不限制
+	AgentTunnelIdleTimeout time.Duration `env:"AGENT_TUNNEL_IDLE_TIMEOUT" envDefault:"10m" reload:"true"`
+	// 长连接最长存活时间，为 0 时不限制
+	AgentTunnelMaxLifetime time.Duration `env:"AGENT_TUNNEL_MAX_LIFETIME" envDefault:"0" reload:"true"`
+
+	// 系统状态统计间隔
+	MonitorInterval time.Duration `env:"MONITOR_INTERVAL" envDefault:"3s" reload:"true"`
+	// 实时数据聚合间隔
+	MonitorRollInterval time.Duration `env:"MONITOR_ROLL_INTERVAL" envDefault:"5m" reload:"true"`
+	// 实时数据保留时长
+	MonitorMaxInterval time.Duration `env:"MONITOR_MAX_INTERVAL" envDefault:"1h" reload:"true"`
+
+	// .env 文件检查间隔，为 0 时只在收到 SIGHUP 时重新加载
+	ConfigWatchInterval time.Duration `env:"CONFIG_WATCH_INTERVAL" envDefault:"5s"`
+
+	// ACME 服务目录地址，为空时不自动签发证书
+	AcmeDirectory string `env:"ACME_DIRECTORY"`
+	AcmeEmail     string `env:"ACME_EMAIL"`
+	// ACME 账号私钥文件，不存在时自动创建
+	AcmeAccountKey  string        `env:"ACME_ACCOUNT_KEY" envDefault:"acme_account.pem"`
+	AcmeRenewBefore time.Duration `env:"ACME_RENEW_BEFORE" envDefault:"720h"`
+	AcmeInterval    time.Duration `env:"ACME_INTERVAL" envDefault:"12h"`
+	// 不校验 ACME 服务证书，仅用于 pebble 等测试服务
+	AcmeInsecure bool `env:"ACME_INSECURE"`
 }
 
 func Get(ctx context.Context) *Config {
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"dxkite.cn/meownest/pkg/proxyproto"
+)
+
+// 监听配置
+type Listen struct {
+	// 原始配置
+	Spec string
+	// tcp 或 unix
+	Network string
+	Address string
+	// 使用 HTTPS
+	TLS bool
+	// 连接以 PROXY 协议头开始，用于四层负载均衡之后
+	ProxyProtocol bool
+	// 允许发送 PROXY 协议头的来源，为空时不限制
+	ProxyTrusted []*net.IPNet
+}
+
+// 解析监听配置
+//
+//	127.0.0.1:2333                     HTTP
+//	http://:80                         HTTP
+//	https://:443                       HTTPS
+//	unix:///run/nest.sock              unix socket 上的 HTTP
+//	http://:8080?proxy_protocol=true   接受 PROXY 协议 v1/v2
+//
+// proxy_trusted 限制可发送 PROXY 协议头的来源，支持 CIDR 与 IP，可重复设置，
+// 如 http://:8080?proxy_protocol=true&proxy_trusted=10.0.0.0/8&proxy_trusted=192.168.1.10
+func ParseListen(spec string) (*Listen, error) {
+	spec = strings.TrimSpace(spec)
+	if !strings.Contains(spec, "://") {
+		spec = "http://" + spec
+	}
+
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen %s: %w", spec, err)
+	}
+
+	ln := &Listen{Spec: spec}
+	switch u.Scheme {
+	case "http":
+		ln.Network, ln.Address = "tcp", u.Host
+	case "https":
+		ln.Network, ln.Address, ln.TLS = "tcp", u.Host, true
+	case "unix":
+		ln.Network, ln.Address = "unix", u.Path
+	default:
+		return nil, fmt.Errorf("invalid listen %s: unsupported scheme %s", spec, u.Scheme)
+	}
+
+	if ln.Address == "" {
+		return nil, fmt.Errorf("invalid listen %s: missing address", spec)
+	}
+
+	if v := u.Query().Get("proxy_protocol"); v != "" {
+		if ln.ProxyProtocol, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid listen %s: proxy_protocol %w", spec, err)
+		}
+	}
+	if v := u.Query()["proxy_trusted"]; len(v) > 0 {
+		if ln.ProxyTrusted, err = proxyproto.ParseTrusted(v); err != nil {
+			return nil, fmt.Errorf("invalid listen %s: %w", spec, err)
+		}
+	}
+	return ln, nil
+}
+
+// 第一个 HTTPS 监听的端口，没有 HTTPS 监听时返回 0
+func HTTPSPort(specs []string) int {
+	for _, spec := range specs {
+		ln, err := ParseListen(spec)
+		if err != nil || !ln.TLS || ln.Network != "tcp" {
+			continue
+		}
+		if _, port, err := net.SplitHostPort(ln.Address); err == nil {
+			if v, err := strconv.Atoi(port); err == nil {
+				return v
+			}
+		}
+	}
+	return 0
+}
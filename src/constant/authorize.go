@@ -1,3 +1,4 @@
 package constant
 
 const AuthorizePrefix = "authorize_"
+const AuthorizeCredentialPrefix = "credential_"
@@ -12,6 +12,8 @@ const (
 	ScopeEndpointWrite    = "endpoint:write"
 	ScopeRouteRead        = "route:read"
 	ScopeRouteWrite       = "route:write"
+	ScopeStreamRead       = "stream:read"
+	ScopeStreamWrite      = "stream:write"
 	ScopeUserRead         = "user:read"
 	ScopeUserWrite        = "user:write"
 )
@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"dxkite.cn/meownest/src/enum"
+)
+
+// 代理服务状态
+type AgentStatus struct {
+	// 当前配置版本，每次加载递增
+	Version uint64 `json:"version"`
+	// 加载时间
+	LoadedAt time.Time `json:"loaded_at"`
+	// 已加载的路由数
+	Routes int `json:"routes"`
+	// 运行中的后端服务数
+	Endpoints int `json:"endpoints"`
+	// 加载失败的路由
+	Errors []*AgentRouteError `json:"errors"`
+	// 运行中的四层转发数
+	Streams int `json:"streams"`
+	// 加载失败的四层转发
+	StreamErrors []*AgentStreamError `json:"stream_errors"`
+	// websocket 等长连接统计
+	Tunnels *TunnelStats `json:"tunnels"`
+}
+
+// 路由加载错误
+type AgentRouteError struct {
+	RouteId string   `json:"route_id"`
+	Name    string   `json:"name"`
+	Method  []string `json:"method"`
+	Path    string   `json:"path"`
+	// 错误信息
+	Error string `json:"error"`
+}
+
+// 四层转发加载错误
+type AgentStreamError struct {
+	StreamId string             `json:"stream_id"`
+	Name     string             `json:"name"`
+	Network  enum.StreamNetwork `json:"network"`
+	Listen   string             `json:"listen"`
+	// 错误信息
+	Error string `json:"error"`
+}
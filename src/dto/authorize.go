@@ -6,6 +6,7 @@ import (
 	"dxkite.cn/meownest/pkg/identity"
 	"dxkite.cn/meownest/src/constant"
 	"dxkite.cn/meownest/src/entity"
+	"dxkite.cn/meownest/src/enum"
 	"dxkite.cn/meownest/src/value"
 )
 
@@ -18,7 +19,7 @@ type Authorize struct {
 	// 鉴权备注
 	Name string `json:"name"`
 	// 鉴权类型
-	Type string `json:"type"`
+	Type enum.AuthorizeType `json:"type"`
 	// 描述
 	Description string `json:"description"`
 	// 鉴权属性
@@ -36,3 +37,17 @@ func NewAuthorize(ent *entity.Authorize) *Authorize {
 	obj.Attribute = ent.Attribute
 	return obj
 }
+
+// 路由生效的鉴权策略
+type AuthorizePolicy struct {
+	// 策略来源 route 或 collection，为空表示未配置鉴权
+	Source string `json:"source,omitempty"`
+	// 来源的路由或集合ID
+	SourceId string `json:"source_id,omitempty"`
+	// 来源关闭了继承的鉴权
+	Disabled bool `json:"disabled"`
+	// 生效的鉴权配置
+	Authorize *Authorize `json:"authorize,omitempty"`
+	// 组合鉴权按顺序执行的成员
+	Members []*AuthorizePolicy `json:"members,omitempty"`
+}
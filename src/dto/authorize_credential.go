@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"time"
+
+	"dxkite.cn/meownest/pkg/identity"
+	"dxkite.cn/meownest/src/constant"
+	"dxkite.cn/meownest/src/entity"
+)
+
+// 鉴权凭证，不返回密码与密钥
+type AuthorizeCredential struct {
+	Id        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// 所属鉴权配置ID
+	AuthorizeId string `json:"authorize_id"`
+	// 用户名或 API Key 的身份
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// 创建的 API Key，只在创建时返回
+	Key string `json:"key,omitempty"`
+}
+
+func NewAuthorizeCredential(ent *entity.AuthorizeCredential) *AuthorizeCredential {
+	obj := new(AuthorizeCredential)
+	obj.Id = identity.Format(constant.AuthorizeCredentialPrefix, ent.Id)
+	obj.CreatedAt = ent.CreatedAt
+	obj.UpdatedAt = ent.UpdatedAt
+	obj.AuthorizeId = identity.Format(constant.AuthorizePrefix, ent.AuthorizeId)
+	obj.Name = ent.Name
+	obj.Description = ent.Description
+	return obj
+}
@@ -28,6 +28,10 @@ type Collection struct {
 	AuthorizeId string `json:"authorize_id,omitempty"`
 	// 鉴权信息
 	Authorize *Authorize `json:"authorize,omitempty"`
+	// 关闭继承的鉴权
+	AuthorizeDisabled bool `json:"authorize_disabled"`
+	// HTTP 请求重定向到 HTTPS
+	HttpsRedirect bool `json:"https_redirect"`
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -41,6 +45,8 @@ func NewCollection(item *entity.Collection) *Collection {
 	obj.ParentId = identity.Format(constant.CollectionPrefix, item.ParentId)
 	obj.EndpointId = identity.Format(constant.EndpointPrefix, item.EndpointId)
 	obj.AuthorizeId = identity.Format(constant.AuthorizePrefix, item.AuthorizeId)
+	obj.AuthorizeDisabled = item.AuthorizeDisabled
+	obj.HttpsRedirect = item.HttpsRedirect
 	obj.CreatedAt = item.CreatedAt
 	obj.UpdatedAt = item.UpdatedAt
 	return obj
@@ -36,3 +36,32 @@ func NewEndpoint(item *entity.Endpoint) *Endpoint {
 	obj.UpdatedAt = item.UpdatedAt
 	return obj
 }
+
+const (
+	EndpointTargetStatusUnknown = "unknown"
+)
+
+// 后端健康状态
+type EndpointHealth struct {
+	Id string `json:"id"`
+	// 是否已被代理服务加载
+	Loaded bool `json:"loaded"`
+	// 目标状态
+	Targets []*EndpointTargetHealth `json:"targets"`
+}
+
+type EndpointTargetHealth struct {
+	Network string `json:"network"`
+	Address string `json:"address"`
+	Weight  int    `json:"weight"`
+	// 状态 healthy unhealthy ejected unknown
+	Status string `json:"status"`
+	// 当前连接数
+	Conns int64 `json:"conns"`
+	// 连续失败次数
+	Fails int `json:"fails"`
+	// 最近错误
+	LastError string `json:"last_error,omitempty"`
+	// 最近主动检查时间
+	CheckedAt time.Time `json:"checked_at"`
+}
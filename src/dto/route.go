@@ -26,14 +26,22 @@ type Route struct {
 	PathRewrite *value.PathRewrite `json:"path_rewrite,omitempty"`
 	// 数据重写规则
 	ModifyOptions []*value.ModifyOption `json:"modify_options"`
+	// CONNECT 隧道配置
+	Connect *value.RouteConnect `json:"connect,omitempty"`
+	// gRPC-Web 转换
+	GrpcWeb bool `json:"grpc_web"`
 	// 后端服务
 	EndpointId string `json:"endpoint_id"`
 	// 路由自定义的后端路由
 	Endpoint *Endpoint `json:"endpoint,omitempty"`
 	// 鉴权信息ID
 	AuthorizeId string `json:"authorize_id"`
-	// 鉴权信息
+	// 关闭继承的鉴权
+	AuthorizeDisabled bool `json:"authorize_disabled"`
+	// 生效的鉴权信息
 	Authorize *Authorize `json:"authorize,omitempty"`
+	// 生效的鉴权策略
+	AuthorizePolicy *AuthorizePolicy `json:"authorize_policy,omitempty"`
 	// 分组ID
 	CollectionId string `json:"collection_id"`
 	// 状态
@@ -53,11 +61,24 @@ func NewRoute(item *entity.Route) *Route {
 	obj.MatchOptions = item.MatchOptions
 	obj.PathRewrite = item.PathRewrite
 	obj.ModifyOptions = item.ModifyOptions
+	obj.Connect = item.Connect
+	obj.GrpcWeb = item.GrpcWeb
 	obj.CollectionId = identity.Format(constant.CollectionPrefix, item.CollectionId)
 	obj.Status = item.Status
 	obj.CreatedAt = item.CreatedAt
 	obj.UpdatedAt = item.UpdatedAt
 	obj.EndpointId = identity.Format(constant.EndpointPrefix, item.CollectionId)
 	obj.AuthorizeId = identity.Format(constant.AuthorizePrefix, item.AuthorizeId)
+	obj.AuthorizeDisabled = item.AuthorizeDisabled
 	return obj
 }
+
+// 路由匹配测试结果
+type RouteMatch struct {
+	// 是否匹配到路由
+	Matched bool `json:"matched"`
+	// 匹配规则
+	Rule string `json:"rule,omitempty"`
+	// 匹配到的路由
+	Route *Route `json:"route,omitempty"`
+}
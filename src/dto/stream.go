@@ -0,0 +1,57 @@
+package dto
+
+import (
+	"time"
+
+	"dxkite.cn/meownest/pkg/identity"
+	"dxkite.cn/meownest/src/constant"
+	"dxkite.cn/meownest/src/entity"
+	"dxkite.cn/meownest/src/enum"
+)
+
+// 四层转发
+type Stream struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// 协议 tcp udp
+	Network enum.StreamNetwork `json:"network"`
+	// 监听地址
+	Listen string `json:"listen"`
+	// TLS SNI 匹配的域名
+	ServerNames []string `json:"server_names"`
+	// 后端服务ID
+	EndpointId string `json:"endpoint_id"`
+	// 后端服务
+	Endpoint *Endpoint `json:"endpoint,omitempty"`
+	// 转发统计，未运行时为空
+	Stats *TunnelStats `json:"stats,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 连接转发统计，服务重启后清零
+type TunnelStats struct {
+	// 当前连接数，UDP 为活跃会话数
+	Active int64 `json:"active"`
+	// 累计连接数
+	Total int64 `json:"total"`
+	// 客户端发往后端的字节数
+	BytesIn int64 `json:"bytes_in"`
+	// 后端发往客户端的字节数
+	BytesOut int64 `json:"bytes_out"`
+}
+
+func NewStream(item *entity.Stream) *Stream {
+	obj := &Stream{Id: identity.Format(constant.StreamPrefix, item.Id)}
+	obj.Name = item.Name
+	obj.Description = item.Description
+	obj.Network = item.Network
+	obj.Listen = item.Listen
+	obj.ServerNames = item.ServerNames
+	obj.EndpointId = identity.Format(constant.EndpointPrefix, item.EndpointId)
+	obj.CreatedAt = item.CreatedAt
+	obj.UpdatedAt = item.UpdatedAt
+	return obj
+}
@@ -3,6 +3,7 @@ package entity
 import (
 	"time"
 
+	"dxkite.cn/meownest/src/enum"
 	"dxkite.cn/meownest/src/value"
 )
 
@@ -13,7 +14,7 @@ type Authorize struct {
 
 	Name        string
 	Description string
-	Type        string
+	Type        enum.AuthorizeType
 
 	Attribute *value.AuthorizeAttribute `gorm:"serializer:json"`
 }
@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"time"
+)
+
+// 鉴权凭证，Basic 的用户名密码或 API Key
+type AuthorizeCredential struct {
+	Id        uint64 `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// 所属鉴权配置
+	AuthorizeId uint64 `gorm:"index"`
+	// 用户名或 API Key 的身份
+	Name        string
+	Description string
+	// 密码或密钥的哈希
+	Hash string
+}
+
+func NewAuthorizeCredential() *AuthorizeCredential {
+	entity := new(AuthorizeCredential)
+	return entity
+}
@@ -23,8 +23,12 @@ type Collection struct {
 	ServerNames []string `gorm:"serializer:json"`
 	// 权限配置ID
 	AuthorizeId uint64 `gorm:"index"`
+	// 关闭继承的鉴权，子集合与路由可重新设置
+	AuthorizeDisabled bool
 	// 后端服务ID
 	EndpointId uint64 `gorm:"index"`
+	// HTTP 请求重定向到 HTTPS
+	HttpsRedirect bool
 }
 
 func NewCollection() *Collection {
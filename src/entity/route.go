@@ -25,10 +25,16 @@ type Route struct {
 	PathRewrite *value.PathRewrite `json:"path_rewrite" gorm:"serializer:json"`
 	// 数据编辑
 	ModifyOptions []*value.ModifyOption `json:"modify_options" gorm:"serializer:json"`
+	// CONNECT 隧道
+	Connect *value.RouteConnect `json:"connect" gorm:"serializer:json"`
+	// gRPC-Web 转换
+	GrpcWeb bool `json:"grpc_web"`
 	// 所属集合ID
 	CollectionId uint64 `gorm:"index"`
 	// 权限配置ID
 	AuthorizeId uint64 `gorm:"index"`
+	// 关闭继承的鉴权
+	AuthorizeDisabled bool
 	// 后端服务ID
 	EndpointId uint64 `gorm:"index"`
 	// 路由状态
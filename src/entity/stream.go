@@ -0,0 +1,18 @@
+package entity
+
+import "dxkite.cn/meownest/src/enum"
+
+// 四层转发
+type Stream struct {
+	Base
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// 协议 tcp udp
+	Network enum.StreamNetwork `json:"network"`
+	// 监听地址
+	Listen string `json:"listen"`
+	// TLS SNI 匹配的域名，为空时作为同一监听地址的默认转发
+	ServerNames []string `json:"server_names" gorm:"serializer:json"`
+	// 后端服务ID
+	EndpointId uint64 `gorm:"index"`
+}
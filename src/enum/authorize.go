@@ -0,0 +1,22 @@
+package enum
+
+type AuthorizeType string
+
+const (
+	// 加密的二进制令牌
+	AuthorizeTypeBinary AuthorizeType = "binary"
+	// JWT 令牌
+	AuthorizeTypeJWT AuthorizeType = "jwt"
+	// HTTP Basic 认证
+	AuthorizeTypeBasic AuthorizeType = "basic"
+	// API Key
+	AuthorizeTypeAPIKey AuthorizeType = "apikey"
+	// 外部鉴权服务
+	AuthorizeTypeForward AuthorizeType = "forward"
+	// OIDC 登录
+	AuthorizeTypeOIDC AuthorizeType = "oidc"
+	// 任意一个鉴权通过
+	AuthorizeTypeAnyOf AuthorizeType = "any_of"
+	// 全部鉴权通过
+	AuthorizeTypeAllOf AuthorizeType = "all_of"
+)
@@ -12,4 +12,10 @@ type RoutePathType string
 const (
 	RoutePathTypeExact  RoutePathType = "exact"
 	RoutePathTypePrefix RoutePathType = "prefix"
+	// 路径参数 /users/{id}
+	RoutePathTypeParam RoutePathType = "param"
+	// 正则表达式
+	RoutePathTypeRegex RoutePathType = "regex"
+	// gRPC 服务与方法 pkg.Service/Method
+	RoutePathTypeGRPC RoutePathType = "grpc"
 )
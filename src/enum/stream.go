@@ -0,0 +1,8 @@
+package enum
+
+type StreamNetwork string
+
+const (
+	StreamNetworkTCP StreamNetwork = "tcp"
+	StreamNetworkUDP StreamNetwork = "udp"
+)
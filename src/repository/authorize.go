@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"dxkite.cn/meownest/pkg/database"
 	"dxkite.cn/meownest/src/entity"
@@ -15,6 +16,8 @@ type Authorize interface {
 	Delete(ctx context.Context, id uint64) error
 	List(ctx context.Context, param *ListAuthorizeParam) (*ListAuthorizeResult, error)
 	BatchGet(ctx context.Context, ids []uint64) ([]*entity.Authorize, error)
+	// 更新修改时间，凭证变更后重新加载鉴权配置
+	Touch(ctx context.Context, id uint64) error
 }
 
 func NewAuthorize() Authorize {
@@ -101,6 +104,13 @@ func (r *authorize) Update(ctx context.Context, id uint64, ent *entity.Authorize
 	return nil
 }
 
+func (r *authorize) Touch(ctx context.Context, id uint64) error {
+	if err := r.dataSource(ctx).Model(entity.Authorize{}).Where("id = ?", id).Update("updated_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
 func (r *authorize) Delete(ctx context.Context, id uint64) error {
 	if err := r.dataSource(ctx).Where("id = ?", id).Delete(entity.Authorize{}).Error; err != nil {
 		return err
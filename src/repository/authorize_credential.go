@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"dxkite.cn/meownest/pkg/database"
+	"dxkite.cn/meownest/src/entity"
+	"gorm.io/gorm"
+)
+
+var ErrAuthorizeCredentialNotExist = errors.New("credential not exist")
+
+type AuthorizeCredential interface {
+	Create(ctx context.Context, credential *entity.AuthorizeCredential) (*entity.AuthorizeCredential, error)
+	Get(ctx context.Context, id uint64) (*entity.AuthorizeCredential, error)
+	GetByName(ctx context.Context, authorizeId uint64, name string) (*entity.AuthorizeCredential, error)
+	Delete(ctx context.Context, authorizeId, id uint64) error
+	DeleteByAuthorize(ctx context.Context, authorizeId uint64) error
+	List(ctx context.Context, param *ListAuthorizeCredentialParam) (*ListAuthorizeCredentialResult, error)
+}
+
+func NewAuthorizeCredential() AuthorizeCredential {
+	return new(authorizeCredential)
+}
+
+type authorizeCredential struct {
+}
+
+func (r *authorizeCredential) Get(ctx context.Context, id uint64) (*entity.AuthorizeCredential, error) {
+	var item entity.AuthorizeCredential
+	if err := r.dataSource(ctx).Where("id = ?", id).First(&item).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *authorizeCredential) GetByName(ctx context.Context, authorizeId uint64, name string) (*entity.AuthorizeCredential, error) {
+	var item entity.AuthorizeCredential
+	if err := r.dataSource(ctx).Where("authorize_id = ? and name = ?", authorizeId, name).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAuthorizeCredentialNotExist
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+type ListAuthorizeCredentialParam struct {
+	AuthorizeId uint64
+	// pagination
+	Page         int
+	PerPage      int
+	IncludeTotal bool
+}
+
+type ListAuthorizeCredentialResult struct {
+	Data  []*entity.AuthorizeCredential
+	Total int64
+}
+
+func (r *authorizeCredential) List(ctx context.Context, param *ListAuthorizeCredentialParam) (*ListAuthorizeCredentialResult, error) {
+	var items []*entity.AuthorizeCredential
+	db := r.dataSource(ctx)
+
+	// condition
+	condition := func(db *gorm.DB) *gorm.DB {
+		return db.Where("authorize_id = ?", param.AuthorizeId)
+	}
+
+	// pagination
+	query := db.Scopes(condition)
+	if param.Page > 0 && param.PerPage > 0 {
+		query.Offset((param.Page - 1) * param.PerPage).Limit(param.PerPage)
+	}
+
+	if err := query.Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	rst := &ListAuthorizeCredentialResult{}
+	rst.Data = items
+
+	if param.IncludeTotal {
+		if err := db.Model(entity.AuthorizeCredential{}).Scopes(condition).Count(&rst.Total).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return rst, nil
+}
+
+func (r *authorizeCredential) Create(ctx context.Context, credential *entity.AuthorizeCredential) (*entity.AuthorizeCredential, error) {
+	if err := r.dataSource(ctx).Create(&credential).Error; err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func (r *authorizeCredential) Delete(ctx context.Context, authorizeId, id uint64) error {
+	if err := r.dataSource(ctx).Where("authorize_id = ? and id = ?", authorizeId, id).Delete(entity.AuthorizeCredential{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *authorizeCredential) DeleteByAuthorize(ctx context.Context, authorizeId uint64) error {
+	if err := r.dataSource(ctx).Where("authorize_id = ?", authorizeId).Delete(entity.AuthorizeCredential{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *authorizeCredential) dataSource(ctx context.Context) *gorm.DB {
+	return database.Get(ctx).Engine().(*gorm.DB)
+}
@@ -12,7 +12,7 @@ import (
 type Collection interface {
 	Create(ctx context.Context, collection *entity.Collection) (*entity.Collection, error)
 	Get(ctx context.Context, id uint64) (*entity.Collection, error)
-	Update(ctx context.Context, id uint64, ent *entity.Collection) error
+	Update(ctx context.Context, id uint64, fields []string, ent *entity.Collection) error
 	Delete(ctx context.Context, id uint64) error
 	List(ctx context.Context, param *ListCollectionParam) (*ListCollectionResult, error)
 	BatchGet(ctx context.Context, ids []uint64) ([]*entity.Collection, error)
@@ -153,8 +153,8 @@ func (r *collection) List(ctx context.Context, param *ListCollectionParam) (*Lis
 	return rst, nil
 }
 
-func (r *collection) Update(ctx context.Context, id uint64, ent *entity.Collection) error {
-	if err := r.dataSource(ctx).Where("id = ?", id).Updates(&ent).Error; err != nil {
+func (r *collection) Update(ctx context.Context, id uint64, fields []string, ent *entity.Collection) error {
+	if err := r.dataSource(ctx).Select(fields).Where("id = ?", id).Updates(&ent).Error; err != nil {
 		return err
 	}
 	return nil
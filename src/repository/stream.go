@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+
+	"dxkite.cn/meownest/pkg/database"
+	"dxkite.cn/meownest/src/entity"
+	"gorm.io/gorm"
+)
+
+type Stream interface {
+	Create(ctx context.Context, stream *entity.Stream) (*entity.Stream, error)
+	Get(ctx context.Context, id uint64) (*entity.Stream, error)
+	List(ctx context.Context, param *ListStreamParam) (*ListStreamResult, error)
+	Update(ctx context.Context, id uint64, fields []string, ent *entity.Stream) error
+	Delete(ctx context.Context, id uint64) error
+}
+
+func NewStream() Stream {
+	return &stream{}
+}
+
+type stream struct {
+}
+
+func (r *stream) Create(ctx context.Context, stream *entity.Stream) (*entity.Stream, error) {
+	if err := r.dataSource(ctx).Create(&stream).Error; err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (r *stream) Get(ctx context.Context, id uint64) (*entity.Stream, error) {
+	var item entity.Stream
+	if err := r.dataSource(ctx).Where("id = ?", id).First(&item).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+type ListStreamParam struct {
+	Name       string
+	Listen     string
+	EndpointId uint64
+	// pagination
+	Page         int
+	PerPage      int
+	IncludeTotal bool
+}
+
+type ListStreamResult struct {
+	Data  []*entity.Stream
+	Total int64
+}
+
+func (r *stream) List(ctx context.Context, param *ListStreamParam) (*ListStreamResult, error) {
+	var items []*entity.Stream
+	db := r.dataSource(ctx)
+
+	// condition
+	condition := func(db *gorm.DB) *gorm.DB {
+		if param.Name != "" {
+			db = db.Where("name like ?", "%"+param.Name+"%")
+		}
+		if param.Listen != "" {
+			db = db.Where("listen = ?", param.Listen)
+		}
+		if param.EndpointId != 0 {
+			db = db.Where("endpoint_id = ?", param.EndpointId)
+		}
+		return db
+	}
+
+	// pagination
+	query := db.Scopes(condition)
+	if param.Page > 0 && param.PerPage > 0 {
+		query = query.Offset((param.Page - 1) * param.PerPage).Limit(param.PerPage)
+	}
+
+	if err := query.Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	rst := &ListStreamResult{}
+	rst.Data = items
+
+	if param.IncludeTotal {
+		if err := db.Model(entity.Stream{}).Scopes(condition).Count(&rst.Total).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return rst, nil
+}
+
+func (r *stream) Update(ctx context.Context, id uint64, fields []string, ent *entity.Stream) error {
+	if err := r.dataSource(ctx).Select(fields).Where("id = ?", id).Updates(&ent).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *stream) Delete(ctx context.Context, id uint64) error {
+	if err := r.dataSource(ctx).Where("id = ?", id).Delete(entity.Stream{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *stream) dataSource(ctx context.Context) *gorm.DB {
+	return database.Get(ctx).Engine().(*gorm.DB)
+}
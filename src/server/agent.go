@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"dxkite.cn/meownest/pkg/httpserver"
+	"dxkite.cn/meownest/src/constant"
 	"dxkite.cn/meownest/src/service"
 	"github.com/gin-gonic/gin"
 )
@@ -28,12 +29,97 @@ func NewAgent(s service.Agent) *Agent {
 // @Failure      500  {object} httpserver.HttpError
 // @Router       /agent/reload [post]
 func (s *Agent) Reload(c *gin.Context) {
-	s.s.LoadRoute(c)
+	if err := s.s.LoadRoute(c); err != nil {
+		httpserver.ResultError(c, err)
+		return
+	}
 	c.Status(http.StatusOK)
 }
 
+// 后端健康状态
+//
+// @Summary      后端健康状态
+// @Description  后端健康状态
+// @Tags         Endpoint
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Endpoint ID"
+// @Success      200  {object} dto.EndpointHealth
+// @Failure      400  {object} httpserver.HttpError
+// @Failure      500  {object} httpserver.HttpError
+// @Router       /endpoints/{id}/health [get]
+func (s *Agent) EndpointHealth(c *gin.Context) {
+	var param service.GetEndpointHealthParam
+
+	if err := c.ShouldBindUri(&param); err != nil {
+		httpserver.ResultErrorBind(c, err)
+		return
+	}
+
+	rst, err := s.s.EndpointHealth(c, &param)
+	if err != nil {
+		httpserver.ResultError(c, err)
+		return
+	}
+	httpserver.Result(c, http.StatusOK, rst)
+}
+
+// 路由匹配测试
+//
+// @Summary      路由匹配测试
+// @Description  测试请求会匹配的路由，不转发请求
+// @Tags         Agent
+// @Accept       json
+// @Produce      json
+// @Param        method query string false "请求方法"
+// @Param        host query string false "请求域名"
+// @Param        path query string true "请求路径"
+// @Param        header query []string false "请求头 Name: Value" collectionFormat(multi)
+// @Success      200  {object} dto.RouteMatch
+// @Failure      400  {object} httpserver.HttpError
+// @Failure      500  {object} httpserver.HttpError
+// @Router       /agent/match [get]
+func (s *Agent) Match(c *gin.Context) {
+	var param service.MatchRouteParam
+
+	if err := c.ShouldBindQuery(&param); err != nil {
+		httpserver.ResultErrorBind(c, err)
+		return
+	}
+
+	rst, err := s.s.Match(c, &param)
+	if err != nil {
+		httpserver.ResultError(c, err)
+		return
+	}
+	httpserver.Result(c, http.StatusOK, rst)
+}
+
+// 代理服务状态
+//
+// @Summary      代理服务状态
+// @Description  当前配置版本与路由加载错误
+// @Tags         Agent
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} dto.AgentStatus
+// @Failure      400  {object} httpserver.HttpError
+// @Failure      500  {object} httpserver.HttpError
+// @Router       /agent/status [get]
+func (s *Agent) Status(c *gin.Context) {
+	rst, err := s.s.Status(c)
+	if err != nil {
+		httpserver.ResultError(c, err)
+		return
+	}
+	httpserver.Result(c, http.StatusOK, rst)
+}
+
 func (s *Agent) API() httpserver.RouteHandleFunc {
 	return func(r gin.IRouter) {
 		r.POST("/agent/reload", s.Reload)
+		r.GET("/agent/status", httpserver.ScopeRequired(constant.ScopeRouteRead), s.Status)
+		r.GET("/agent/match", httpserver.ScopeRequired(constant.ScopeRouteRead), s.Match)
+		r.GET("/endpoints/:id/health", httpserver.ScopeRequired(constant.ScopeEndpointRead), s.EndpointHealth)
 	}
 }
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"net/http"
+
+	"dxkite.cn/meownest/pkg/httpserver"
+	"dxkite.cn/meownest/src/constant"
+	"dxkite.cn/meownest/src/service"
+	"github.com/gin-gonic/gin"
+)
+
+func NewAuthorizeCredential(s service.AuthorizeCredential) *AuthorizeCredential {
+	return &AuthorizeCredential{s: s}
+}
+
+type AuthorizeCredential struct {
+	s service.AuthorizeCredential
+}
+
+// Create AuthorizeCredential
+//
+// @Summary      Create AuthorizeCredential
+// @Description  创建 Basic 用户或 API Key，API Key 只在创建时返回
+// @Tags         Authorize
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Authorize ID"
+// @Param        body body service.CreateAuthorizeCredentialParam true "数据"
+// @Success      200  {object} dto.AuthorizeCredential
+// @Failure      400  {object} httpserver.HttpError
+// @Failure      500  {object} httpserver.HttpError
+// @Router       /authorizes/{id}/credentials [post]
+func (s *AuthorizeCredential) Create(c *gin.Context) {
+	var param service.CreateAuthorizeCredentialParam
+	param.Id = c.Param("id")
+
+	if err := c.ShouldBind(&param); err != nil {
+		httpserver.ResultErrorBind(c, err)
+		return
+	}
+
+	rst, err := s.s.Create(c, &param)
+	if err != nil {
+		httpserver.ResultError(c, err)
+		return
+	}
+
+	httpserver.Result(c, http.StatusCreated, rst)
+}
+
+// AuthorizeCredential列表
+//
+// @Summary      AuthorizeCredential列表
+// @Description  AuthorizeCredential列表
+// @Tags         Authorize
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Authorize ID"
+// @Param		 include_total query bool false "是否包含total"
+// @Param        page query int false "页码"
+// @Param        pre_page query int false "每页数量"
+// @Success      200  {object} service.ListAuthorizeCredentialResult
+// @Failure      400  {object} httpserver.HttpError
+// @Failure      500  {object} httpserver.HttpError
+// @Router       /authorizes/{id}/credentials [get]
+func (s *AuthorizeCredential) List(c *gin.Context) {
+	var param service.ListAuthorizeCredentialParam
+	param.Id = c.Param("id")
+
+	if err := c.ShouldBindQuery(&param); err != nil {
+		httpserver.ResultErrorBind(c, err)
+		return
+	}
+
+	rst, err := s.s.List(c, &param)
+	if err != nil {
+		httpserver.ResultError(c, err)
+		return
+	}
+
+	httpserver.Result(c, http.StatusOK, rst)
+}
+
+// Delete AuthorizeCredential
+//
+// @Summary      Delete AuthorizeCredential
+// @Description  Delete AuthorizeCredential
+// @Tags         Authorize
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Authorize ID"
+// @Param        credential_id path string true "Credential ID"
+// @Success      200
+// @Failure      400  {object} httpserver.HttpError
+// @Failure      500  {object} httpserver.HttpError
+// @Router       /authorizes/{id}/credentials/{credential_id} [delete]
+func (s *AuthorizeCredential) Delete(c *gin.Context) {
+	var param service.DeleteAuthorizeCredentialParam
+
+	if err := c.ShouldBindUri(&param); err != nil {
+		httpserver.ResultErrorBind(c, err)
+		return
+	}
+
+	err := s.s.Delete(c, &param)
+	if err != nil {
+		httpserver.ResultError(c, err)
+		return
+	}
+
+	httpserver.ResultEmpty(c, http.StatusOK)
+}
+
+func (s *AuthorizeCredential) API() httpserver.RouteHandleFunc {
+	return func(route gin.IRouter) {
+		route.POST("/authorizes/:id/credentials", httpserver.ScopeRequired(constant.ScopeAuthorizeWrite), s.Create)
+		route.GET("/authorizes/:id/credentials", httpserver.ScopeRequired(constant.ScopeAuthorizeRead), s.List)
+		route.DELETE("/authorizes/:id/credentials/:credential_id", httpserver.ScopeRequired(constant.ScopeAuthorizeWrite), s.Delete)
+	}
+}
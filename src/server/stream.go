@@ -0,0 +1,181 @@
+package server
+
+import (
+	"net/http"
+
+	"dxkite.cn/meownest/pkg/httpserver"
+	"dxkite.cn/meownest/src/constant"
+	"dxkite.cn/meownest/src/service"
+	"github.com/gin-gonic/gin"
+)
+
+func NewStream(s service.Stream) *Stream {
+	return &Stream{s: s}
+}
+
+type Stream struct {
+	s service.Stream
+}
+
+// Create Stream
+//
+// @Summary      Create Stream
+// @Description  Create Stream
+// @Tags         Stream
+// @Accept       json
+// @Produce      json
+// @Param        body body service.CreateStreamParam true "Stream data"
+// @Success      200  {object} dto.Stream
+// @Failure      400  {object} httpserver.HttpError
+// @Failure      500  {object} httpserver.HttpError
+// @Router       /streams [post]
+func (s *Stream) Create(c *gin.Context) {
+	var param service.CreateStreamParam
+
+	if err := c.ShouldBind(&param); err != nil {
+		httpserver.ResultErrorBind(c, err)
+		return
+	}
+
+	rst, err := s.s.Create(c, &param)
+	if err != nil {
+		httpserver.ResultError(c, err)
+		return
+	}
+
+	httpserver.Result(c, http.StatusCreated, rst)
+}
+
+// Get Stream
+//
+// @Summary      Get Stream
+// @Description  Get Stream
+// @Tags         Stream
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Stream ID"
+// @Param        expand query []string false "expand attribute list"
+// @Success      200  {object} dto.Stream
+// @Failure      400  {object} httpserver.HttpError
+// @Failure      500  {object} httpserver.HttpError
+// @Router       /streams/{id} [get]
+func (s *Stream) Get(c *gin.Context) {
+	var param service.GetStreamParam
+
+	param.Id = c.Param("id")
+
+	if err := c.ShouldBindQuery(&param); err != nil {
+		httpserver.ResultErrorBind(c, err)
+		return
+	}
+
+	rst, err := s.s.Get(c, &param)
+	if err != nil {
+		httpserver.ResultError(c, err)
+		return
+	}
+	httpserver.Result(c, http.StatusOK, rst)
+}
+
+// List Stream
+//
+// @Summary      Stream list
+// @Description  Stream list
+// @Tags         Stream
+// @Accept       json
+// @Produce      json
+// @Param        name query string false "Stream"
+// @Param        listen query string false "监听地址"
+// @Param        endpoint_id query string false "后端服务ID"
+// @Param		 include_total query bool false "是否包含total"
+// @Param        page query int false "页码"
+// @Param        pre_page query int false "每页数量"
+// @Param        expand query []string false "展开数据"
+// @Success      200  {object} service.ListStreamResult
+// @Failure      400  {object} httpserver.HttpError
+// @Failure      500  {object} httpserver.HttpError
+// @Router       /streams [get]
+func (s *Stream) List(c *gin.Context) {
+	var param service.ListStreamParam
+
+	if err := c.ShouldBindQuery(&param); err != nil {
+		httpserver.ResultErrorBind(c, err)
+		return
+	}
+
+	rst, err := s.s.List(c, &param)
+	if err != nil {
+		httpserver.ResultError(c, err)
+		return
+	}
+
+	httpserver.Result(c, http.StatusOK, rst)
+}
+
+// Update Stream
+//
+// @Summary      Update Stream
+// @Description  Update Stream
+// @Tags         Stream
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Stream ID"
+// @Param        body body service.UpdateStreamParam true "data"
+// @Success      200  {object} dto.Stream
+// @Failure      400  {object} httpserver.HttpError
+// @Failure      500  {object} httpserver.HttpError
+// @Router       /streams/{id} [post]
+func (s *Stream) Update(c *gin.Context) {
+	var param service.UpdateStreamParam
+	param.Id = c.Param("id")
+
+	if err := c.ShouldBind(&param); err != nil {
+		httpserver.ResultErrorBind(c, err)
+		return
+	}
+
+	rst, err := s.s.Update(c, &param)
+	if err != nil {
+		httpserver.ResultError(c, err)
+		return
+	}
+	httpserver.Result(c, http.StatusOK, rst)
+}
+
+// Delete Stream
+//
+// @Summary      Delete Stream
+// @Description  Delete Stream
+// @Tags         Stream
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Stream ID"
+// @Success      200
+// @Failure      400  {object} httpserver.HttpError
+// @Failure      500  {object} httpserver.HttpError
+// @Router       /streams/{id} [delete]
+func (s *Stream) Delete(c *gin.Context) {
+	var param service.DeleteStreamParam
+
+	if err := c.ShouldBindUri(&param); err != nil {
+		httpserver.ResultErrorBind(c, err)
+		return
+	}
+	err := s.s.Delete(c, &param)
+	if err != nil {
+		httpserver.ResultError(c, err)
+		return
+	}
+
+	httpserver.ResultEmpty(c, http.StatusOK)
+}
+
+func (s *Stream) API() httpserver.RouteHandleFunc {
+	return func(route gin.IRouter) {
+		route.POST("/streams", httpserver.ScopeRequired(constant.ScopeStreamWrite), s.Create)
+		route.GET("/streams", httpserver.ScopeRequired(constant.ScopeStreamRead), s.List)
+		route.GET("/streams/:id", httpserver.ScopeRequired(constant.ScopeStreamRead), s.Get)
+		route.POST("/streams/:id", httpserver.ScopeRequired(constant.ScopeStreamWrite), s.Update)
+		route.DELETE("/streams/:id", httpserver.ScopeRequired(constant.ScopeStreamWrite), s.Delete)
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"net/http"
+	"sync/atomic"
 
 	"dxkite.cn/meownest/pkg/httpserver"
 	"dxkite.cn/meownest/src/constant"
@@ -10,12 +11,24 @@ import (
 )
 
 func NewUser(s service.User, session string) *User {
-	return &User{s: s, session: session}
+	u := &User{s: s}
+	u.SetSessionName(session)
+	return u
 }
 
 type User struct {
 	s       service.User
-	session string
+	session atomic.Value
+}
+
+// 会话 Cookie 名称
+func (s *User) SessionName() string {
+	return s.session.Load().(string)
+}
+
+// 修改会话 Cookie 名称，已登录的会话需要重新登录
+func (s *User) SetSessionName(name string) {
+	s.session.Store(name)
 }
 
 // Create User
@@ -195,7 +208,7 @@ func (s *User) CreateSession(c *gin.Context) {
 		return
 	}
 
-	c.SetCookie(s.session, rst.Token, 360, "", "", true, true)
+	c.SetCookie(s.SessionName(), rst.Token, 360, "", "", true, true)
 
 	httpserver.Result(c, http.StatusOK, rst)
 }
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"dxkite.cn/meownest/pkg/acme"
+	"dxkite.cn/meownest/src/entity"
+	"dxkite.cn/meownest/src/repository"
+)
+
+// 证书名前缀，ACME 签发的证书以此区分
+const acmeCertificatePrefix = "acme:"
+
+type Acme interface {
+	// 定时检查并续期证书，直到 ctx 结束
+	Run(ctx context.Context)
+	// 为集合绑定的域名签发或续期证书
+	Renew(ctx context.Context) error
+}
+
+type AcmeConfig struct {
+	// 证书过期前多久续期
+	RenewBefore time.Duration
+	// 检查间隔
+	Interval time.Duration
+}
+
+func NewAcme(cfg *AcmeConfig, client *acme.Client, rc repository.Collection, rct repository.Certificate, loader CertificateLoader) Acme {
+	return &acmeService{cfg: cfg, client: client, rc: rc, rct: rct, loader: loader}
+}
+
+type acmeService struct {
+	cfg    *AcmeConfig
+	client *acme.Client
+	rc     repository.Collection
+	rct    repository.Certificate
+	loader CertificateLoader
+}
+
+// 注册账号失败时在下次检查时重试，注册成功前不签发证书
+func (s *acmeService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	registered := false
+	for {
+		if !registered {
+			if err := s.client.Register(ctx); err != nil {
+				printLog("acme register error: %s\n", err.Error())
+			} else {
+				registered = true
+			}
+		}
+
+		if registered {
+			if err := s.Renew(ctx); err != nil {
+				printLog("acme renew error: %s\n", err.Error())
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *acmeService) Renew(ctx context.Context) error {
+	domains, err := s.serverNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	certs, err := s.rct.List(ctx, &repository.ListCertificateParam{})
+	if err != nil {
+		return err
+	}
+
+	renewAt := time.Now().Add(s.cfg.RenewBefore)
+	updated := 0
+
+	for _, domain := range domains {
+		var exist *entity.Certificate
+		valid := false
+
+		for _, v := range certs.Data {
+			if v.Name == acmeCertificatePrefix+domain {
+				exist = v
+			}
+			if coverDomain(v.DNSNames, domain) && v.NotAfter.After(renewAt) {
+				valid = true
+			}
+		}
+
+		if valid {
+			continue
+		}
+
+		if err := s.obtain(ctx, domain, exist); err != nil {
+			printLog("acme obtain %s error: %s\n", domain, err.Error())
+			continue
+		}
+		updated++
+	}
+
+	if updated > 0 && s.loader != nil {
+		return s.loader.LoadCertificate(ctx)
+	}
+	return nil
+}
+
+func (s *acmeService) obtain(ctx context.Context, domain string, exist *entity.Certificate) error {
+	certPEM, keyPEM, err := s.client.Obtain(ctx, []string{domain})
+	if err != nil {
+		return err
+	}
+
+	ent, err := entity.NewCertificateWithCertificateKey(string(certPEM), string(keyPEM))
+	if err != nil {
+		return err
+	}
+
+	ent.Name = acmeCertificatePrefix + domain
+
+	if exist != nil {
+		return s.rct.Update(ctx, exist.Id, ent)
+	}
+
+	_, err = s.rct.Create(ctx, ent)
+	return err
+}
+
+// 集合绑定的域名，HTTP-01 不支持通配符域名与 IP
+func (s *acmeService) serverNames(ctx context.Context) ([]string, error) {
+	rst, err := s.rc.List(ctx, &repository.ListCollectionParam{})
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	exist := map[string]bool{}
+
+	for _, coll := range rst.Data {
+		for _, name := range coll.ServerNames {
+			if host, _, err := net.SplitHostPort(name); err == nil {
+				name = host
+			}
+
+			name = strings.ToLower(strings.TrimSuffix(name, "."))
+			if name == "" || strings.Contains(name, "*") || net.ParseIP(name) != nil || exist[name] {
+				continue
+			}
+
+			exist[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func coverDomain(names []string, domain string) bool {
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if name == domain {
+			return true
+		}
+		if strings.HasPrefix(name, "*.") {
+			if i := strings.IndexByte(domain, '.'); i > 0 && domain[i:] == name[1:] {
+				return true
+			}
+		}
+	}
+	return false
+}
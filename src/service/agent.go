@@ -2,19 +2,75 @@ package service
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"strconv"
+	"net"
+	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	ag "dxkite.cn/meownest/pkg/agent"
+	"dxkite.cn/meownest/pkg/identity"
+	"dxkite.cn/meownest/src/constant"
+	"dxkite.cn/meownest/src/dto"
 	"dxkite.cn/meownest/src/entity"
+	"dxkite.cn/meownest/src/enum"
 	"dxkite.cn/meownest/src/repository"
+	"dxkite.cn/meownest/src/value"
 )
 
 type Agent interface {
-	Run(addr string)
+	Serve(l net.Listener) error
+	ServeTLS(l net.Listener) error
+	// 停止指定监听，用于切换监听地址
+	ShutdownListener(ctx context.Context, l net.Listener) error
+	// 停止服务，等待请求与长连接结束后停止后端服务
+	Shutdown(ctx context.Context) error
 	LoadRoute(ctx context.Context) error
+	// 在后台重新加载路由，加载期间的多次请求合并为一次
+	ReloadRoute(ctx context.Context)
+	LoadCertificate(ctx context.Context) error
+	EndpointHealth(ctx context.Context, param *GetEndpointHealthParam) (*dto.EndpointHealth, error)
+	Match(ctx context.Context, param *MatchRouteParam) (*dto.RouteMatch, error)
+	Status(ctx context.Context) (*dto.AgentStatus, error)
+	LoadStream(ctx context.Context) error
+	// 在后台重新加载四层转发，加载期间的多次请求合并为一次
+	ReloadStream(ctx context.Context)
+	StreamStats(id uint64) *dto.TunnelStats
+	// 注册鉴权类型
+	RegisterAuthorizeType(typ enum.AuthorizeType, factory AuthorizeHandlerFactory)
+}
+
+// 路由加载，路由相关配置变更后重新加载
+type RouteLoader interface {
+	ReloadRoute(ctx context.Context)
+}
+
+func reloadRoute(ctx context.Context, loader RouteLoader) {
+	if loader == nil {
+		return
+	}
+	loader.ReloadRoute(ctx)
+}
+
+// 保留上下文中的值，如数据库连接，不随请求结束取消
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
 }
 
 type agent struct {
@@ -23,39 +79,396 @@ type agent struct {
 	rr  repository.Route
 	re  repository.Endpoint
 	ra  repository.Authorize
+	rac repository.AuthorizeCredential
+	rct repository.Certificate
+	rs  repository.Stream
+
+	// HTTPS 证书
+	certs *ag.CertificateStore
+	// 当前使用的配置快照
+	snapshot atomic.Pointer[agentSnapshot]
+	// 加载路由互斥
+	mtx    *sync.Mutex
+	closed bool
+
+	// 后台重新加载
+	routeReload  *backgroundLoad
+	streamReload *backgroundLoad
+
+	// 四层转发
+	streams   *streamState
+	streamMtx *sync.Mutex
+
+	// 鉴权类型
+	authorizeTypes map[enum.AuthorizeType]AuthorizeHandlerFactory
 }
 
-func NewAgent(svr *ag.Server, rr repository.Route, rc repository.Collection, re repository.Endpoint, ra repository.Authorize) Agent {
-	return &agent{svr: svr, rr: rr, rc: rc, re: re, ra: ra}
+// 路由配置快照，生成后不再修改
+type agentSnapshot struct {
+	version  uint64
+	loadedAt time.Time
+	handler  *ag.Handler
+	// 路由 ID 到已加载的路由
+	routes map[uint64]*routeItem
+	// 规则到路由
+	forwards map[ag.ForwardHandler]*entity.Route
+	// 后端服务 ID 到运行中的后端
+	upstreams map[uint64]*endpointUpstream
+	// 鉴权配置 ID 到鉴权处理
+	authorizes map[uint64]*authorizeHandler
+	errors     []*dto.AgentRouteError
 }
 
-func (s *agent) Run(addr string) {
-	s.svr.Run(addr)
+type routeItem struct {
+	// 路由及其依赖配置的版本，未变化时复用已加载的规则
+	key     string
+	forward ag.ForwardHandler
+	// 使用的鉴权处理，鉴权处理重建时路由需重建
+	auth ag.AuthorizeHandler
 }
 
+type endpointUpstream struct {
+	updatedAt time.Time
+	upstream  *ag.Upstream
+}
+
+func NewAgent(svr *ag.Server, rr repository.Route, rc repository.Collection, re repository.Endpoint, ra repository.Authorize, rac repository.AuthorizeCredential, rct repository.Certificate, rs repository.Stream) Agent {
+	s := &agent{
+		svr: svr, rr: rr, rc: rc, re: re, ra: ra, rac: rac, rct: rct, rs: rs,
+		certs:     ag.NewCertificateStore(),
+		mtx:       &sync.Mutex{},
+		streams:   newStreamState(),
+		streamMtx: &sync.Mutex{},
+	}
+	s.routeReload = &backgroundLoad{name: "route", load: s.LoadRoute}
+	s.streamReload = &backgroundLoad{name: "stream", load: s.LoadStream}
+	s.authorizeTypes = s.newAuthorizeTypes()
+	return s
+}
+
+func (s *agent) Serve(l net.Listener) error {
+	return s.svr.Serve(l)
+}
+
+func (s *agent) ServeTLS(l net.Listener) error {
+	return s.svr.ServeTLS(l, &tls.Config{GetCertificate: s.certs.GetCertificate})
+}
+
+func (s *agent) ShutdownListener(ctx context.Context, l net.Listener) error {
+	return s.svr.ShutdownListener(ctx, l)
+}
+
+func (s *agent) Shutdown(ctx context.Context) error {
+	streamErr := make(chan error, 1)
+	go func() { streamErr <- s.shutdownStream(ctx) }()
+
+	err := s.svr.Shutdown(ctx)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.closed = true
+
+	if cur := s.snapshot.Load(); cur != nil {
+		for _, v := range cur.upstreams {
+			v.upstream.Stop()
+		}
+		for _, v := range cur.authorizes {
+			if l, ok := v.handler.(authorizeLifecycle); ok {
+				l.Stop()
+			}
+		}
+	}
+	return errors.Join(err, <-streamErr)
+}
+
+// 加载全部证书
+func (s *agent) LoadCertificate(ctx context.Context) error {
+	rst, err := s.rct.List(ctx, &repository.ListCertificateParam{})
+	if err != nil {
+		return err
+	}
+
+	certs := []*tls.Certificate{}
+	for _, v := range rst.Data {
+		cert, err := tls.X509KeyPair([]byte(v.Certificate), []byte(v.Key))
+		if err != nil {
+			printLog("skip certificate %s %s\n", v.Name, err.Error())
+			continue
+		}
+		certs = append(certs, &cert)
+	}
+
+	if err := s.certs.Update(certs); err != nil {
+		return err
+	}
+
+	printLog("load %d certificates\n", len(certs))
+	return nil
+}
+
+// 加载路由，生成新的配置快照后原子替换，未变化的路由与后端服务沿用已加载的实例
 func (s *agent) LoadRoute(ctx context.Context) error {
-	route := ag.NewHandler()
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	// 停止后不再启动后端服务
+	if s.closed {
+		return nil
+	}
+
+	prev := s.snapshot.Load()
+	if prev == nil {
+		prev = &agentSnapshot{}
+	}
+
+	next := &agentSnapshot{
+		version:    prev.version + 1,
+		handler:    ag.NewHandler(),
+		routes:     map[uint64]*routeItem{},
+		forwards:   map[ag.ForwardHandler]*entity.Route{},
+		upstreams:  map[uint64]*endpointUpstream{},
+		authorizes: map[uint64]*authorizeHandler{},
+		errors:     []*dto.AgentRouteError{},
+	}
+
 	if err := s.rr.Batch(ctx, func(item *entity.Route) error {
-		forward, err := s.createForwardItem(ctx, item)
+		if item.Status == enum.RouteStatusInactive {
+			return nil
+		}
+
+		route, err := s.createForwardItem(ctx, item, prev, next)
 		if err != nil {
 			printLog("skip route %v %s %s\n", item.Method, item.Path, err.Error())
+			next.errors = append(next.errors, &dto.AgentRouteError{
+				RouteId: identity.Format(constant.RoutePrefix, item.Id),
+				Name:    item.Name,
+				Method:  item.Method,
+				Path:    item.Path,
+				Error:   err.Error(),
+			})
 			return nil
 		}
-		route.Add(forward)
 
-		printLog("load route %v %s\n", item.Method, item.Path)
+		next.handler.Add(route.forward)
+		next.routes[item.Id] = route
+		next.forwards[route.forward] = item
 		return nil
 	}); err != nil {
 		return err
 	}
 
-	route.Sort()
-	s.svr.Use(route)
+	next.handler.Sort()
+	next.loadedAt = time.Now()
+
+	for id, v := range next.upstreams {
+		if old, ok := prev.upstreams[id]; !ok || old.upstream != v.upstream {
+			v.upstream.Start()
+		}
+	}
+	for id, v := range next.authorizes {
+		if old, ok := prev.authorizes[id]; !ok || old.handler != v.handler {
+			if l, ok := v.handler.(authorizeLifecycle); ok {
+				l.Start()
+			}
+		}
+	}
+
+	s.snapshot.Store(next)
+	s.svr.Use(next.handler)
+
+	// 停止不再使用的后端服务与鉴权处理
+	for id, v := range prev.upstreams {
+		if cur, ok := next.upstreams[id]; !ok || cur.upstream != v.upstream {
+			v.upstream.Stop()
+		}
+	}
+	for id, v := range prev.authorizes {
+		if cur, ok := next.authorizes[id]; !ok || cur.handler != v.handler {
+			if l, ok := v.handler.(authorizeLifecycle); ok {
+				l.Stop()
+			}
+		}
+	}
+
+	printLog("load %d routes version %d, %d errors\n", len(next.routes), next.version, len(next.errors))
 	return nil
 }
 
-func (s *agent) createForwardItem(ctx context.Context, item *entity.Route) (ag.ForwardHandler, error) {
-	collectionIdList, err := s.getCollectionList(ctx, item)
+// 配置写入后触发，不阻塞请求；加载中收到的请求在本次加载结束后再加载一次
+func (s *agent) ReloadRoute(ctx context.Context) {
+	s.routeReload.trigger(ctx)
+}
+
+// 后台加载，加载期间的多次请求合并为一次，错误记录日志
+type backgroundLoad struct {
+	name string
+	load func(ctx context.Context) error
+
+	mtx     sync.Mutex
+	ctx     context.Context
+	running bool
+	pending bool
+}
+
+func (b *backgroundLoad) trigger(ctx context.Context) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.ctx = detachedContext{ctx}
+	if b.running {
+		b.pending = true
+		return
+	}
+	b.running = true
+	go b.run()
+}
+
+func (b *backgroundLoad) run() {
+	for {
+		b.mtx.Lock()
+		ctx := b.ctx
+		b.pending = false
+		b.mtx.Unlock()
+
+		if err := b.load(ctx); err != nil {
+			printLog("reload %s error: %s\n", b.name, err.Error())
+		}
+
+		b.mtx.Lock()
+		if !b.pending {
+			b.running = false
+			b.ctx = nil
+			b.mtx.Unlock()
+			return
+		}
+		b.mtx.Unlock()
+	}
+}
+
+func (s *agent) Status(ctx context.Context) (*dto.AgentStatus, error) {
+	obj := &dto.AgentStatus{Errors: []*dto.AgentRouteError{}}
+	obj.Tunnels = newTunnelStats(s.svr.TunnelStats())
+
+	s.streamMtx.Lock()
+	obj.Streams = len(s.streams.routes)
+	obj.StreamErrors = s.streams.errors
+	s.streamMtx.Unlock()
+
+	snapshot := s.snapshot.Load()
+	if snapshot == nil {
+		return obj, nil
+	}
+
+	obj.Version = snapshot.version
+	obj.LoadedAt = snapshot.loadedAt
+	obj.Routes = len(snapshot.routes)
+	obj.Endpoints = len(snapshot.upstreams)
+	obj.Errors = snapshot.errors
+	return obj, nil
+}
+
+type MatchRouteParam struct {
+	// 请求方法
+	Method string `json:"method" form:"method"`
+	// 请求域名
+	Host string `json:"host" form:"host"`
+	// 请求路径，可包含查询参数
+	Path string `json:"path" form:"path" binding:"required"`
+	// 请求头，格式为 Name: Value
+	Header []string `json:"header" form:"header"`
+}
+
+// 测试请求会匹配的路由，不转发请求
+func (s *agent) Match(ctx context.Context, param *MatchRouteParam) (*dto.RouteMatch, error) {
+	method := param.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, param.Path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.RequestURI = req.URL.RequestURI()
+	if param.Host != "" {
+		req.Host = param.Host
+	}
+	for _, v := range param.Header {
+		name, value, _ := strings.Cut(v, ":")
+		req.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	obj := &dto.RouteMatch{}
+	snapshot := s.snapshot.Load()
+	if snapshot == nil {
+		return obj, nil
+	}
+
+	if item := snapshot.handler.Match(req); item != nil {
+		obj.Matched = true
+		obj.Rule = fmt.Sprint(item)
+		if route, ok := snapshot.forwards[item]; ok {
+			obj.Route = dto.NewRoute(route)
+		}
+	}
+	return obj, nil
+}
+
+type GetEndpointHealthParam struct {
+	Id string `json:"id" uri:"id" binding:"required"`
+}
+
+func (s *agent) EndpointHealth(ctx context.Context, param *GetEndpointHealthParam) (*dto.EndpointHealth, error) {
+	id := identity.Parse(constant.EndpointPrefix, param.Id)
+	endpoint, err := s.re.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var upstream *ag.Upstream
+	if snapshot := s.snapshot.Load(); snapshot != nil {
+		if v, ok := snapshot.upstreams[id]; ok {
+			upstream = v.upstream
+		}
+	}
+
+	obj := &dto.EndpointHealth{Id: param.Id, Loaded: upstream != nil, Targets: []*dto.EndpointTargetHealth{}}
+
+	if upstream != nil {
+		for _, v := range upstream.Targets {
+			health := v.Health()
+			obj.Targets = append(obj.Targets, &dto.EndpointTargetHealth{
+				Network:   v.Network,
+				Address:   v.Address,
+				Weight:    v.Weight,
+				Status:    health.Status,
+				Conns:     v.Conns(),
+				Fails:     health.Fails,
+				LastError: health.LastError,
+				CheckedAt: health.CheckedAt,
+			})
+		}
+		return obj, nil
+	}
+
+	// 未被路由使用的后端没有运行状态
+	if endpoint.Endpoint != nil && endpoint.Endpoint.Static != nil {
+		for _, v := range endpoint.Endpoint.Static.Address {
+			obj.Targets = append(obj.Targets, &dto.EndpointTargetHealth{
+				Network: v.Network,
+				Address: v.Address,
+				Weight:  v.Weight,
+				Status:  dto.EndpointTargetStatusUnknown,
+			})
+		}
+	}
+	return obj, nil
+}
+
+// 同一后端的路由共享 upstream，以便负载均衡状态一致
+func (s *agent) createForwardItem(ctx context.Context, item *entity.Route, prev, next *agentSnapshot) (*routeItem, error) {
+	collectionIdList, err := getRouteCollectionList(ctx, s.rc, item)
 	if err != nil {
 		return nil, err
 	}
@@ -75,7 +488,8 @@ func (s *agent) createForwardItem(ctx context.Context, item *entity.Route) (ag.F
 		return nil, err
 	}
 
-	if endpoint == nil {
+	// CONNECT 路由直接连接请求的目标，不使用后端服务
+	if endpoint == nil && item.Connect == nil {
 		return nil, errors.New("missing endpoint")
 	}
 
@@ -84,27 +498,174 @@ func (s *agent) createForwardItem(ctx context.Context, item *entity.Route) (ag.F
 		return nil, err
 	}
 
-	forwardItem := NewForwardHandler(item, []string{}, endpoint, authorize)
-	return forwardItem, nil
+	var upstream *endpointUpstream
+	if item.Connect == nil {
+		var ok bool
+		upstream, ok = next.upstreams[endpoint.Id]
+		if !ok {
+			if old, ok := prev.upstreams[endpoint.Id]; ok && old.updatedAt.Equal(endpoint.UpdatedAt) {
+				upstream = old
+			} else {
+				u, err := NewEndpointUpstream(endpoint)
+				if err != nil {
+					return nil, err
+				}
+				upstream = &endpointUpstream{updatedAt: endpoint.UpdatedAt, upstream: u}
+			}
+		}
+	}
+
+	var authHandler ag.AuthorizeHandler
+	if authorize != nil {
+		authHandler, err = s.getAuthorizeHandler(ctx, authorize, prev, next)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	key := routeItemKey(item, collectionIdList, collectionMap, endpoint, authorize)
+	route, ok := prev.routes[item.Id]
+	if !ok || route.key != key || route.auth != authHandler {
+		var forward ag.ForwardHandler
+		if item.Connect != nil {
+			forward, err = NewConnectHandler(item, authHandler)
+		} else {
+			serverNames := s.getServerNames(collectionIdList, collectionMap)
+			httpsRedirect := s.isHttpsRedirect(collectionIdList, collectionMap)
+			forward, err = NewForwardHandler(item, serverNames, upstream.upstream, authHandler, httpsRedirect)
+		}
+		if err != nil {
+			return nil, err
+		}
+		route = &routeItem{key: key, forward: forward, auth: authHandler}
+	}
+
+	if upstream != nil {
+		next.upstreams[endpoint.Id] = upstream
+	}
+	return route, nil
 }
 
-func NewEndpointForwardHandler(endpoint *entity.Endpoint) ag.RequestForwardHandler {
+// 路由及其依赖的集合、后端、鉴权的更新时间
+func routeItemKey(item *entity.Route, collectionIdList []uint64, collectionMap map[uint64]*entity.Collection, endpoint *entity.Endpoint, auth *entity.Authorize) string {
+	key := &strings.Builder{}
+	fmt.Fprintf(key, "r%d:%d", item.Id, item.UpdatedAt.UnixNano())
+	for _, v := range collectionIdList {
+		if coll, ok := collectionMap[v]; ok {
+			fmt.Fprintf(key, ",c%d:%d", coll.Id, coll.UpdatedAt.UnixNano())
+		}
+	}
+	if endpoint != nil {
+		fmt.Fprintf(key, ",e%d:%d", endpoint.Id, endpoint.UpdatedAt.UnixNano())
+	}
+	if auth != nil {
+		fmt.Fprintf(key, ",a%d:%d", auth.Id, auth.UpdatedAt.UnixNano())
+	}
+	return key.String()
+}
+
+const (
+	defaultPoolMaxIdle     = 32
+	defaultPoolIdleTimeout = 90 * time.Second
+)
+
+func NewEndpointUpstream(endpoint *entity.Endpoint) (*ag.Upstream, error) {
+	if endpoint.Endpoint == nil || endpoint.Endpoint.Static == nil {
+		return nil, errors.New("missing static endpoint")
+	}
+
+	static := endpoint.Endpoint.Static
 	targets := []*ag.EndpointTarget{}
-	for _, v := range endpoint.Endpoint.Static.Address {
-		targets = append(targets, &ag.EndpointTarget{
-			Network: v.Network,
-			Address: v.Address,
-		})
+	for _, v := range static.Address {
+		target := &ag.EndpointTarget{
+			Network:  v.Network,
+			Address:  v.Address,
+			Weight:   v.Weight,
+			Protocol: v.Protocol,
+		}
+		if v.TLSInsecureSkipVerify {
+			target.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+		targets = append(targets, target)
+	}
+
+	upstream := ag.NewUpstream(targets, time.Duration(static.Timeout)*time.Millisecond)
+
+	if pool := static.Pool; pool != nil {
+		upstream.Pool = ag.NewConnPool(pool.MaxIdle, time.Duration(pool.IdleTimeout)*time.Millisecond, pool.MaxConns)
+	} else {
+		upstream.Pool = ag.NewConnPool(defaultPoolMaxIdle, defaultPoolIdleTimeout, 0)
+	}
+
+	if balance := static.Balance; balance != nil {
+		switch balance.Type {
+		case ag.BalanceRandom, "":
+			upstream.Balancer = ag.NewRandomBalancer()
+		case ag.BalanceRoundRobin:
+			upstream.Balancer = ag.NewRoundRobinBalancer()
+		case ag.BalanceLeastConn:
+			upstream.Balancer = ag.NewLeastConnBalancer()
+		case ag.BalanceHash:
+			upstream.Balancer = ag.NewHashBalancer(targets, balance.Source, balance.Name)
+		default:
+			return nil, fmt.Errorf("unknown balance type %s", balance.Type)
+		}
+
+		if balance.Sticky != nil {
+			upstream.Sticky = &ag.StickySession{Cookie: balance.Sticky.Cookie, MaxAge: balance.Sticky.MaxAge}
+		}
 	}
 
-	handler := ag.NewStaticForwardHandler(targets, endpoint.Endpoint.Static.Timeout)
-	return handler
+	if retry := static.Retry; retry != nil {
+		upstream.Retry = &ag.RetryPolicy{
+			MaxAttempts:   retry.MaxAttempts,
+			RetryOn:       retry.RetryOn,
+			RetryStatus:   retry.RetryStatus,
+			NonIdempotent: retry.NonIdempotent,
+			PerTryTimeout: time.Duration(retry.PerTryTimeout) * time.Millisecond,
+			MaxBodySize:   retry.MaxBodySize,
+		}
+	}
+
+	if check := static.HealthCheck; check != nil {
+		if active := check.Active; active != nil {
+			upstream.HealthCheck = &ag.HealthCheck{
+				Type:               active.Type,
+				Interval:           time.Duration(active.Interval) * time.Millisecond,
+				Timeout:            time.Duration(active.Timeout) * time.Millisecond,
+				Path:               active.Path,
+				ExpectStatus:       active.ExpectStatus,
+				HealthyThreshold:   active.HealthyThreshold,
+				UnhealthyThreshold: active.UnhealthyThreshold,
+			}
+		}
+
+		if passive := check.Passive; passive != nil {
+			upstream.Passive = &ag.PassiveCheck{
+				MaxFails:    passive.MaxFails,
+				FailTimeout: time.Duration(passive.FailTimeout) * time.Millisecond,
+			}
+		}
+	}
+
+	return upstream, nil
 }
 
-func NewForwardHandler(item *entity.Route, serverNameList []string, endpoint *entity.Endpoint, auth *entity.Authorize) ag.ForwardHandler {
+func NewForwardHandler(item *entity.Route, serverNameList []string, upstream *ag.Upstream, authHandler ag.AuthorizeHandler, httpsRedirect bool) (ag.ForwardHandler, error) {
 	matcher := ag.NewBasicMatcher()
-	matcher.Path = ag.NewRequestPathMatcher(item.Path)
+	path, err := NewRoutePathMatcher(item.PathType, item.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher.Path = path
 	matcher.Method = item.Method
+
+	// 只有参数匹配的路由支持 {name} 路径参数，正则中的 { 不是参数
+	pattern := ""
+	if path.MatchPathType() == ag.PathTypeParam {
+		pattern = item.Path
+	}
 	matcher.Extra = []*ag.ExtraMatchOption{}
 
 	matcher.Host = serverNameList
@@ -118,22 +679,96 @@ func NewForwardHandler(item *entity.Route, serverNameList []string, endpoint *en
 		})
 	}
 
-	var authHandler ag.AuthorizeHandler
-	if auth != nil {
-		authHandler = NewAuthorizeHandler(auth)
+	handler := ag.NewStaticForwardHandler(upstream)
+
+	if len(item.ModifyOptions) > 0 {
+		options := []*ag.ModifyOption{}
+		for _, v := range item.ModifyOptions {
+			options = append(options, &ag.ModifyOption{
+				Type:   v.Type,
+				Source: v.Source,
+				Name:   v.Name,
+				Value:  v.Value,
+			})
+		}
+		modifier, err := ag.NewModifier(pattern, options)
+		if err != nil {
+			return nil, err
+		}
+		handler.AddRequestRewrite(modifier)
+		handler.AddResponseRewrite(modifier)
+	}
+
+	if rewrite := item.PathRewrite; rewrite != nil && (rewrite.Regex != "" || rewrite.Replace != "") {
+		pathRewrite, err := ag.NewPathRewrite(pattern, rewrite.Regex, rewrite.Replace)
+		if err != nil {
+			return nil, err
+		}
+		handler.AddRequestRewrite(pathRewrite)
+	}
+
+	var forward ag.RequestForwardHandler = handler
+	if item.GrpcWeb {
+		forward = ag.NewGRPCWebHandler(handler)
+	}
+	if httpsRedirect {
+		return ag.NewHTTPSForwardHandler(matcher, forward, authHandler), nil
+	}
+	return ag.NewForwardHandler(matcher, forward, authHandler), nil
+}
+
+// CONNECT 路由，目标域名由请求指定，不匹配分组域名
+func NewConnectHandler(item *entity.Route, authHandler ag.AuthorizeHandler) (ag.ForwardHandler, error) {
+	path, err := ag.NewConnectMatcher(item.Connect.Allow)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := ag.NewBasicMatcher()
+	matcher.Path = path
+	matcher.Extra = []*ag.ExtraMatchOption{}
+	for _, v := range item.MatchOptions {
+		matcher.Extra = append(matcher.Extra, &ag.ExtraMatchOption{
+			Source: v.Source,
+			Type:   v.Type,
+			Name:   v.Name,
+			Value:  v.Value,
+		})
 	}
 
-	handler := NewEndpointForwardHandler(endpoint)
-	return ag.NewForwardHandler(matcher, handler, authHandler)
+	handler := ag.NewConnectForwardHandler(time.Duration(item.Connect.Timeout) * time.Millisecond)
+	return ag.NewForwardHandler(matcher, handler, authHandler), nil
 }
 
-func NewAuthorizeHandler(auth *entity.Authorize) ag.AuthorizeHandler {
-	binary := auth.Attribute.Binary
-	source := []*ag.AuthorizeSource{}
-	for _, v := range binary.Sources {
-		source = append(source, &ag.AuthorizeSource{Source: v.Source, Name: v.Name})
+func NewRoutePathMatcher(typ enum.RoutePathType, path string) (ag.RequestPathMatcher, error) {
+	switch typ {
+	case enum.RoutePathTypeExact:
+		return ag.NewRequestPathMatcherWithType(ag.PathTypeFull, path), nil
+	case enum.RoutePathTypePrefix:
+		// 兼容区分类型前按 { 推断参数匹配时保存的前缀路由
+		if strings.IndexByte(path, '{') >= 0 {
+			return ag.NewRequestPathMatcherWithType(ag.PathTypeParam, path), nil
+		}
+		return ag.NewRequestPathMatcherWithType(ag.PathTypePrefix, path), nil
+	case enum.RoutePathTypeParam:
+		return ag.NewRequestPathMatcherWithType(ag.PathTypeParam, path), nil
+	case enum.RoutePathTypeRegex:
+		return ag.NewRegexPathMatcher(path)
+	case enum.RoutePathTypeGRPC:
+		return ag.NewGRPCMatcher(path)
+	case "":
+		// 未设置类型时按路径推断
+		return ag.NewRequestPathMatcher(path), nil
 	}
-	return ag.NewBinaryAuth(binary.Key, binary.Header, source)
+	return nil, fmt.Errorf("unknown path type %s", typ)
+}
+
+func newAuthorizeSources(sources []*value.AuthorizeSource) []*ag.AuthorizeSource {
+	rst := []*ag.AuthorizeSource{}
+	for _, v := range sources {
+		rst = append(rst, &ag.AuthorizeSource{Source: v.Source, Name: v.Name})
+	}
+	return rst
 }
 
 func (s *agent) getEndpoint(ctx context.Context, route *entity.Route, collectionIdList []uint64, collectionMap map[uint64]*entity.Collection) (*entity.Endpoint, error) {
@@ -159,48 +794,33 @@ func (s *agent) getEndpoint(ctx context.Context, route *entity.Route, collection
 	return nil, nil
 }
 
+// 路由生效的鉴权配置，关闭了鉴权时返回 nil
 func (s *agent) getAuthorize(ctx context.Context, route *entity.Route, collectionIdList []uint64, collectionMap map[uint64]*entity.Collection) (*entity.Authorize, error) {
-	if route.AuthorizeId != 0 {
-		item, err := s.ra.Get(ctx, route.AuthorizeId)
-		if err != nil {
-			return nil, err
-		}
-		return item, nil
+	scope := findAuthorizeScope(route, collectionIdList, collectionMap)
+	if scope == nil || scope.disabled {
+		return nil, nil
 	}
+	return s.ra.Get(ctx, scope.authorizeId)
+}
 
+// 使用最近一级绑定了域名的集合的域名
+func (s *agent) getServerNames(collectionIdList []uint64, collectionMap map[uint64]*entity.Collection) []string {
 	for _, v := range collectionIdList {
-		if coll, ok := collectionMap[v]; ok {
-			if coll.AuthorizeId != 0 {
-				item, err := s.ra.Get(ctx, coll.AuthorizeId)
-				if err != nil {
-					return nil, err
-				}
-				return item, nil
-			}
+		if coll, ok := collectionMap[v]; ok && len(coll.ServerNames) > 0 {
+			return coll.ServerNames
 		}
 	}
-	return nil, nil
+	return []string{}
 }
 
-func (s *agent) getCollectionList(ctx context.Context, item *entity.Route) ([]uint64, error) {
-	collection := []uint64{}
-	source, err := s.rc.Get(ctx, item.CollectionId)
-	if err != nil {
-		return nil, err
-	}
-
-	collection = append(collection, source.Id)
-
-	idList := strings.Split(source.Index, ".")
-
-	for i := len(idList) - 1; i >= 0; i-- {
-		id, _ := strconv.ParseUint(idList[i], 10, 64)
-		if id > 0 {
-			collection = append(collection, id)
+// 任意上级集合开启 HTTPS 跳转时生效
+func (s *agent) isHttpsRedirect(collectionIdList []uint64, collectionMap map[uint64]*entity.Collection) bool {
+	for _, v := range collectionIdList {
+		if coll, ok := collectionMap[v]; ok && coll.HttpsRedirect {
+			return true
 		}
 	}
-
-	return collection, nil
+	return false
 }
 
 func printLog(format string, values ...interface{}) {
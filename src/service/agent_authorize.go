@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	ag "dxkite.cn/meownest/pkg/agent"
+	"dxkite.cn/meownest/pkg/identity"
+	"dxkite.cn/meownest/pkg/token"
+	"dxkite.cn/meownest/src/constant"
+	"dxkite.cn/meownest/src/entity"
+	"dxkite.cn/meownest/src/enum"
+	"dxkite.cn/meownest/src/repository"
+)
+
+// 按鉴权类型创建鉴权处理
+type AuthorizeHandlerFactory func(ctx context.Context, auth *entity.Authorize) (ag.AuthorizeHandler, error)
+
+type authorizeHandler struct {
+	// 鉴权配置及其依赖的版本
+	key     string
+	handler ag.AuthorizeHandler
+}
+
+// 需要启动、停止的鉴权处理，如外部鉴权服务的连接
+type authorizeLifecycle interface {
+	Start()
+	Stop()
+}
+
+func (s *agent) newAuthorizeTypes() map[enum.AuthorizeType]AuthorizeHandlerFactory {
+	return map[enum.AuthorizeType]AuthorizeHandlerFactory{
+		enum.AuthorizeTypeBinary:  NewBinaryAuthorizeHandler,
+		enum.AuthorizeTypeJWT:     NewJWTAuthorizeHandler,
+		enum.AuthorizeTypeBasic:   s.newBasicAuthorizeHandler,
+		enum.AuthorizeTypeAPIKey:  s.newAPIKeyAuthorizeHandler,
+		enum.AuthorizeTypeForward: s.newForwardAuthorizeHandler,
+		enum.AuthorizeTypeOIDC:    NewOIDCAuthorizeHandler,
+		enum.AuthorizeTypeAnyOf:   s.newAnyOfAuthorizeHandler,
+		enum.AuthorizeTypeAllOf:   s.newAllOfAuthorizeHandler,
+	}
+}
+
+// 注册鉴权类型，需在加载路由前调用
+func (s *agent) RegisterAuthorizeType(typ enum.AuthorizeType, factory AuthorizeHandlerFactory) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.authorizeTypes[typ] = factory
+}
+
+func (s *agent) newAuthorizeHandler(ctx context.Context, auth *entity.Authorize) (ag.AuthorizeHandler, error) {
+	if auth.Attribute == nil {
+		return nil, errors.New("missing authorize attribute")
+	}
+	factory, ok := s.authorizeTypes[auth.Type]
+	if !ok {
+		// 兼容未区分类型的二进制令牌配置
+		if auth.Attribute.Binary == nil {
+			return nil, fmt.Errorf("unknown authorize type %s", auth.Type)
+		}
+		factory = NewBinaryAuthorizeHandler
+	}
+	return factory(ctx, auth)
+}
+
+// 同一鉴权配置的路由共用鉴权处理，配置未变化时复用，保留 JWKS 等缓存
+func (s *agent) getAuthorizeHandler(ctx context.Context, auth *entity.Authorize, prev, next *agentSnapshot) (ag.AuthorizeHandler, error) {
+	if v, ok := next.authorizes[auth.Id]; ok {
+		return v.handler, nil
+	}
+
+	key, err := s.authorizeKey(ctx, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := prev.authorizes[auth.Id]
+	if !ok || v.key != key {
+		handler, err := s.newAuthorizeHandler(ctx, auth)
+		if err != nil {
+			return nil, err
+		}
+		v = &authorizeHandler{key: key, handler: handler}
+	}
+	next.authorizes[auth.Id] = v
+	return v.handler, nil
+}
+
+// 鉴权配置的更新时间，外部鉴权包含鉴权服务的更新时间，组合鉴权包含成员的版本
+func (s *agent) authorizeKey(ctx context.Context, auth *entity.Authorize) (string, error) {
+	key := &strings.Builder{}
+	if err := s.writeAuthorizeKey(ctx, key, auth, 0); err != nil {
+		return "", err
+	}
+	return key.String(), nil
+}
+
+func (s *agent) writeAuthorizeKey(ctx context.Context, key *strings.Builder, auth *entity.Authorize, depth int) error {
+	if depth > maxAuthorizePolicyDepth {
+		return ErrAuthorizePolicyDepth
+	}
+
+	fmt.Fprintf(key, "a%d:%d", auth.Id, auth.UpdatedAt.UnixNano())
+	if auth.Attribute == nil {
+		return nil
+	}
+
+	switch {
+	case auth.Type == enum.AuthorizeTypeForward && auth.Attribute.Forward != nil:
+		endpoint, err := s.re.Get(ctx, identity.Parse(constant.EndpointPrefix, auth.Attribute.Forward.EndpointId))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(key, ",e%d:%d", endpoint.Id, endpoint.UpdatedAt.UnixNano())
+	case isAuthorizePolicy(auth):
+		members, err := getAuthorizePolicyMembers(ctx, s.ra, auth)
+		if err != nil {
+			return err
+		}
+		key.WriteString("(")
+		for _, v := range members {
+			if err := s.writeAuthorizeKey(ctx, key, v, depth+1); err != nil {
+				return err
+			}
+			key.WriteString(";")
+		}
+		key.WriteString(")")
+	}
+	return nil
+}
+
+func NewBinaryAuthorizeHandler(ctx context.Context, auth *entity.Authorize) (ag.AuthorizeHandler, error) {
+	binary := auth.Attribute.Binary
+	if binary == nil {
+		return nil, errors.New("missing binary attribute")
+	}
+	return ag.NewBinaryAuth(binary.Key, binary.Header, newAuthorizeSources(binary.Sources)), nil
+}
+
+func NewJWTAuthorizeHandler(ctx context.Context, auth *entity.Authorize) (ag.AuthorizeHandler, error) {
+	attr := auth.Attribute.JWT
+	if attr == nil {
+		return nil, errors.New("missing jwt attribute")
+	}
+
+	config := &ag.JWTAuthConfig{
+		Algorithms: attr.Algorithms,
+		Issuer:     attr.Issuer,
+		Audience:   attr.Audience,
+		Leeway:     time.Duration(attr.Leeway) * time.Second,
+		RequireExp: attr.RequireExp == nil || *attr.RequireExp,
+		Sources:    newAuthorizeSources(attr.Sources),
+	}
+
+	switch {
+	case attr.JWKS != "":
+		config.Keys = ag.NewJWKS(attr.JWKS, time.Duration(attr.JWKSCacheTTL)*time.Second)
+	case attr.Key != "":
+		keys, err := ag.NewJWTStaticKey(attr.Key)
+		if err != nil {
+			return nil, err
+		}
+		config.Keys = keys
+	default:
+		return nil, errors.New("missing jwt key or jwks")
+	}
+
+	for _, v := range attr.ClaimHeaders {
+		config.ClaimHeaders = append(config.ClaimHeaders, &ag.JWTClaimHeader{Claim: v.Claim, Header: v.Header})
+	}
+	return ag.NewJWTAuth(config), nil
+}
+
+func NewOIDCAuthorizeHandler(ctx context.Context, auth *entity.Authorize) (ag.AuthorizeHandler, error) {
+	attr := auth.Attribute.OIDC
+	if attr == nil {
+		return nil, errors.New("missing oidc attribute")
+	}
+	if n := len(attr.CookieKey); n != 16 && n != 24 && n != 32 {
+		return nil, errors.New("invalid oidc cookie key size")
+	}
+
+	config := &ag.OIDCAuthConfig{
+		Issuer:       attr.Issuer,
+		ClientId:     attr.ClientId,
+		ClientSecret: attr.ClientSecret,
+		CallbackPath: attr.CallbackPath,
+		Scopes:       attr.Scopes,
+		Algorithms:   attr.Algorithms,
+		Crypto:       token.NewAesCrypto([]byte(attr.CookieKey)),
+		CookieName:   attr.CookieName,
+		CookieSecure: attr.CookieSecure,
+		SessionTTL:   time.Duration(attr.SessionTTL) * time.Second,
+		Leeway:       time.Duration(attr.Leeway) * time.Second,
+	}
+	for _, v := range attr.ClaimHeaders {
+		config.ClaimHeaders = append(config.ClaimHeaders, &ag.JWTClaimHeader{Claim: v.Claim, Header: v.Header})
+	}
+	return ag.NewOIDCAuth(config), nil
+}
+
+func (s *agent) newBasicAuthorizeHandler(ctx context.Context, auth *entity.Authorize) (ag.AuthorizeHandler, error) {
+	attr := auth.Attribute.Basic
+	if attr == nil {
+		return nil, errors.New("missing basic attribute")
+	}
+	table, err := s.getCredentialTable(ctx, auth, func(item *entity.AuthorizeCredential) string {
+		return item.Name
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ag.NewBasicAuth(attr.Realm, attr.Header, newAuthorizeSources(attr.Sources), table), nil
+}
+
+func (s *agent) newAPIKeyAuthorizeHandler(ctx context.Context, auth *entity.Authorize) (ag.AuthorizeHandler, error) {
+	attr := auth.Attribute.APIKey
+	if attr == nil {
+		return nil, errors.New("missing apikey attribute")
+	}
+	// API Key 以凭证 ID 查找
+	table, err := s.getCredentialTable(ctx, auth, func(item *entity.AuthorizeCredential) string {
+		return identity.Format(constant.AuthorizeCredentialPrefix, item.Id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ag.NewAPIKeyAuth(attr.Header, newAuthorizeSources(attr.Sources), table), nil
+}
+
+func (s *agent) getCredentialTable(ctx context.Context, auth *entity.Authorize, key func(item *entity.AuthorizeCredential) string) (ag.CredentialTable, error) {
+	rst, err := s.rac.List(ctx, &repository.ListAuthorizeCredentialParam{AuthorizeId: auth.Id})
+	if err != nil {
+		return nil, err
+	}
+	table := ag.CredentialTable{}
+	for _, v := range rst.Data {
+		table[key(v)] = &ag.Credential{Principal: v.Name, Hash: v.Hash}
+	}
+	return table, nil
+}
+
+func (s *agent) newForwardAuthorizeHandler(ctx context.Context, auth *entity.Authorize) (ag.AuthorizeHandler, error) {
+	attr := auth.Attribute.Forward
+	if attr == nil {
+		return nil, errors.New("missing forward attribute")
+	}
+	endpoint, err := s.re.Get(ctx, identity.Parse(constant.EndpointPrefix, attr.EndpointId))
+	if err != nil {
+		return nil, err
+	}
+	upstream, err := NewEndpointUpstream(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return ag.NewForwardAuth(&ag.ForwardAuthConfig{
+		Upstream:        upstream,
+		Method:          attr.Method,
+		Path:            attr.Path,
+		Headers:         attr.Headers,
+		Cookies:         attr.Cookies,
+		ResponseHeaders: attr.ResponseHeaders,
+		CacheTTL:        time.Duration(attr.CacheTTL) * time.Second,
+	}), nil
+}
+
+func (s *agent) newAnyOfAuthorizeHandler(ctx context.Context, auth *entity.Authorize) (ag.AuthorizeHandler, error) {
+	handlers, err := s.newPolicyMemberHandlers(ctx, auth)
+	if err != nil {
+		return nil, err
+	}
+	return ag.NewAnyOfAuth(handlers...), nil
+}
+
+func (s *agent) newAllOfAuthorizeHandler(ctx context.Context, auth *entity.Authorize) (ag.AuthorizeHandler, error) {
+	handlers, err := s.newPolicyMemberHandlers(ctx, auth)
+	if err != nil {
+		return nil, err
+	}
+	return ag.NewAllOfAuth(handlers...), nil
+}
+
+type authorizePolicyDepthKey struct{}
+
+// 创建组合鉴权的成员，嵌套层数记录在 ctx 中
+func (s *agent) newPolicyMemberHandlers(ctx context.Context, auth *entity.Authorize) ([]ag.AuthorizeHandler, error) {
+	depth, _ := ctx.Value(authorizePolicyDepthKey{}).(int)
+	if depth >= maxAuthorizePolicyDepth {
+		return nil, ErrAuthorizePolicyDepth
+	}
+	ctx = context.WithValue(ctx, authorizePolicyDepthKey{}, depth+1)
+
+	members, err := getAuthorizePolicyMembers(ctx, s.ra, auth)
+	if err != nil {
+		return nil, err
+	}
+	handlers := []ag.AuthorizeHandler{}
+	for _, v := range members {
+		handler, err := s.newAuthorizeHandler(ctx, v)
+		if err != nil {
+			return nil, err
+		}
+		handlers = append(handlers, handler)
+	}
+	return handlers, nil
+}
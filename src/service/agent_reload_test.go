@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ag "dxkite.cn/meownest/pkg/agent"
+	"dxkite.cn/meownest/src/entity"
+	"dxkite.cn/meownest/src/repository"
+)
+
+type blockingRouteRepository struct {
+	repository.Route
+	calls   int32
+	release chan struct{}
+}
+
+func (r *blockingRouteRepository) Batch(ctx context.Context, batchFn func(item *entity.Route) error) error {
+	if atomic.AddInt32(&r.calls, 1) == 1 {
+		<-r.release
+	}
+	return ctx.Err()
+}
+
+// 加载期间的多次重新加载合并为一次，请求结束不影响后台加载
+func TestAgentReloadRouteCoalesce(t *testing.T) {
+	rr := &blockingRouteRepository{release: make(chan struct{})}
+	s := NewAgent(ag.New(), rr, nil, nil, nil, nil, nil, nil).(*agent)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 5; i++ {
+		s.ReloadRoute(ctx)
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	close(rr.release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.routeReload.mtx.Lock()
+		reloading := s.routeReload.running
+		s.routeReload.mtx.Unlock()
+		if !reloading {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("reload not finished")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := atomic.LoadInt32(&rr.calls); n != 2 {
+		t.Errorf("Batch() calls = %d, want 2", n)
+	}
+	if s.snapshot.Load() == nil {
+		t.Error("snapshot not loaded with canceled request context")
+	}
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	ag "dxkite.cn/meownest/pkg/agent"
+	"dxkite.cn/meownest/pkg/identity"
+	"dxkite.cn/meownest/src/constant"
+	"dxkite.cn/meownest/src/dto"
+	"dxkite.cn/meownest/src/entity"
+	"dxkite.cn/meownest/src/enum"
+	"dxkite.cn/meownest/src/repository"
+)
+
+// 删除或变更监听地址的四层转发等待连接结束的时长
+const streamDrainTimeout = 30 * time.Second
+
+// 运行中的四层转发
+type streamState struct {
+	// 协议与监听地址到服务
+	servers map[string]*ag.StreamServer
+	// 转发 ID 到规则
+	routes map[uint64]*ag.StreamRoute
+	// 后端服务 ID 到运行中的后端
+	upstreams map[uint64]*endpointUpstream
+	errors    []*dto.AgentStreamError
+	// 已停止，不再启动监听
+	closed bool
+}
+
+func newStreamState() *streamState {
+	return &streamState{
+		servers:   map[string]*ag.StreamServer{},
+		routes:    map[uint64]*ag.StreamRoute{},
+		upstreams: map[uint64]*endpointUpstream{},
+		errors:    []*dto.AgentStreamError{},
+	}
+}
+
+// 加载全部四层转发
+//
+// 监听地址不变的服务保留监听并替换规则，已建立的连接不受影响
+func (s *agent) LoadStream(ctx context.Context) error {
+	s.streamMtx.Lock()
+	defer s.streamMtx.Unlock()
+
+	if s.streams.closed {
+		return nil
+	}
+
+	rst, err := s.rs.List(ctx, &repository.ListStreamParam{})
+	if err != nil {
+		return err
+	}
+
+	endpointIds := []uint64{}
+	for _, v := range rst.Data {
+		endpointIds = append(endpointIds, v.EndpointId)
+	}
+	endpoints, err := s.re.BatchGet(ctx, endpointIds)
+	if err != nil {
+		return err
+	}
+	endpointMap := map[uint64]*entity.Endpoint{}
+	for i, v := range endpoints {
+		endpointMap[v.Id] = endpoints[i]
+	}
+
+	prev := s.streams
+	next := newStreamState()
+	groups := map[string][]*ag.StreamRoute{}
+	groupStreams := map[string][]*entity.Stream{}
+
+	for _, item := range rst.Data {
+		route, err := s.createStreamRoute(item, endpointMap[item.EndpointId], prev, next)
+		if err != nil {
+			printLog("skip stream %s %s %s\n", item.Name, item.Listen, err.Error())
+			next.errors = append(next.errors, newAgentStreamError(item, err))
+			continue
+		}
+		key := streamServerKey(item)
+		groups[key] = append(groups[key], route)
+		groupStreams[key] = append(groupStreams[key], item)
+		next.routes[item.Id] = route
+	}
+
+	for key, routes := range groups {
+		svr, ok := prev.servers[key]
+		if !ok {
+			item := groupStreams[key][0]
+			svr = ag.NewStreamServer(string(item.Network), item.Listen)
+			svr.Update(routes)
+			if err := svr.Start(); err != nil {
+				printLog("listen stream %s error: %s\n", key, err.Error())
+				for _, v := range groupStreams[key] {
+					next.errors = append(next.errors, newAgentStreamError(v, err))
+					delete(next.routes, v.Id)
+				}
+				continue
+			}
+		}
+		svr.Update(routes)
+		next.servers[key] = svr
+	}
+
+	for _, v := range next.upstreams {
+		v.upstream.Start()
+	}
+
+	s.streams = next
+
+	// 停止不再使用的监听与后端服务
+	for key, svr := range prev.servers {
+		if _, ok := next.servers[key]; !ok {
+			go func(svr *ag.StreamServer) {
+				ctx, cancel := context.WithTimeout(context.Background(), streamDrainTimeout)
+				defer cancel()
+				svr.Shutdown(ctx)
+			}(svr)
+		}
+	}
+	for id, v := range prev.upstreams {
+		if cur, ok := next.upstreams[id]; !ok || cur.upstream != v.upstream {
+			v.upstream.Stop()
+		}
+	}
+
+	printLog("load %d streams, %d errors\n", len(next.routes), len(next.errors))
+	return nil
+}
+
+// 配置写入后触发，不阻塞请求
+func (s *agent) ReloadStream(ctx context.Context) {
+	s.streamReload.trigger(ctx)
+}
+
+func (s *agent) createStreamRoute(item *entity.Stream, endpoint *entity.Endpoint, prev, next *streamState) (*ag.StreamRoute, error) {
+	if endpoint == nil {
+		return nil, errors.New("missing endpoint")
+	}
+
+	upstream, ok := next.upstreams[endpoint.Id]
+	if !ok {
+		if old, ok := prev.upstreams[endpoint.Id]; ok && old.updatedAt.Equal(endpoint.UpdatedAt) {
+			upstream = old
+		} else {
+			u, err := NewEndpointUpstream(endpoint)
+			if err != nil {
+				return nil, err
+			}
+			// 四层转发不复用连接
+			u.Pool = nil
+			upstream = &endpointUpstream{updatedAt: endpoint.UpdatedAt, upstream: u}
+		}
+		next.upstreams[endpoint.Id] = upstream
+	}
+
+	route := &ag.StreamRoute{Upstream: upstream.upstream}
+	if item.Network == enum.StreamNetworkTCP {
+		route.ServerNames = item.ServerNames
+	}
+	// 统计跨重新加载保留
+	if old, ok := prev.routes[item.Id]; ok {
+		route.Stats = old.Stats
+	}
+	return route, nil
+}
+
+// 相同监听地址的转发使用同一服务，:9000、0.0.0.0:9000 与 [::]:9000 视为相同
+func streamServerKey(item *entity.Stream) string {
+	return string(item.Network) + "://" + normalizeStreamListen(string(item.Network), item.Listen)
+}
+
+func normalizeStreamListen(network, listen string) string {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return listen
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.IsUnspecified() {
+			host = ""
+		} else {
+			host = ip.String()
+		}
+	}
+	if v, err := net.LookupPort(network, port); err == nil {
+		port = strconv.Itoa(v)
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func newAgentStreamError(item *entity.Stream, err error) *dto.AgentStreamError {
+	return &dto.AgentStreamError{
+		StreamId: identity.Format(constant.StreamPrefix, item.Id),
+		Name:     item.Name,
+		Network:  item.Network,
+		Listen:   item.Listen,
+		Error:    err.Error(),
+	}
+}
+
+// 四层转发运行统计，未运行时返回 nil
+func (s *agent) StreamStats(id uint64) *dto.TunnelStats {
+	s.streamMtx.Lock()
+	defer s.streamMtx.Unlock()
+
+	route, ok := s.streams.routes[id]
+	if !ok {
+		return nil
+	}
+	return newTunnelStats(route.Stats)
+}
+
+func newTunnelStats(stats *ag.TunnelStats) *dto.TunnelStats {
+	return &dto.TunnelStats{
+		Active:   stats.Active(),
+		Total:    stats.Total(),
+		BytesIn:  stats.BytesIn(),
+		BytesOut: stats.BytesOut(),
+	}
+}
+
+// 停止全部四层转发
+func (s *agent) shutdownStream(ctx context.Context) error {
+	s.streamMtx.Lock()
+	defer s.streamMtx.Unlock()
+	s.streams.closed = true
+
+	var wg sync.WaitGroup
+	errs := make([]error, 0, len(s.streams.servers))
+	var errMtx sync.Mutex
+	for _, svr := range s.streams.servers {
+		wg.Add(1)
+		go func(svr *ag.StreamServer) {
+			defer wg.Done()
+			if err := svr.Shutdown(ctx); err != nil {
+				errMtx.Lock()
+				errs = append(errs, err)
+				errMtx.Unlock()
+			}
+		}(svr)
+	}
+	wg.Wait()
+
+	for _, v := range s.streams.upstreams {
+		v.upstream.Stop()
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"testing"
+
+	"dxkite.cn/meownest/src/entity"
+	"dxkite.cn/meownest/src/enum"
+)
+
+// 写法不同的相同监听地址使用同一服务
+func TestStreamServerKey(t *testing.T) {
+	key := func(network enum.StreamNetwork, listen string) string {
+		return streamServerKey(&entity.Stream{Network: network, Listen: listen})
+	}
+
+	want := key(enum.StreamNetworkTCP, ":9000")
+	for _, v := range []string{"0.0.0.0:9000", "[::]:9000", ":09000"} {
+		if got := key(enum.StreamNetworkTCP, v); got != want {
+			t.Errorf("streamServerKey(%q) = %q, want %q", v, got, want)
+		}
+	}
+
+	for _, v := range []string{"127.0.0.1:9000", ":9001"} {
+		if got := key(enum.StreamNetworkTCP, v); got == want {
+			t.Errorf("streamServerKey(%q) = %q, want different from :9000", v, got)
+		}
+	}
+	if got := key(enum.StreamNetworkUDP, ":9000"); got == want {
+		t.Errorf("udp key = %q, want different from tcp", got)
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ag "dxkite.cn/meownest/pkg/agent"
+	"dxkite.cn/meownest/src/entity"
+	"dxkite.cn/meownest/src/enum"
+	"dxkite.cn/meownest/src/value"
+)
+
+// 区分路径类型前保存为 prefix 的参数路由仍按参数匹配
+func TestRoutePathMatcherPrefixParam(t *testing.T) {
+	path, err := NewRoutePathMatcher(enum.RoutePathTypePrefix, "/users/{id}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path.MatchPathType() != ag.PathTypeParam {
+		t.Errorf("path type = %v, want param", path.MatchPathType())
+	}
+	if !path.MatchRequest(httptest.NewRequest(http.MethodGet, "/users/1", nil)) {
+		t.Error("/users/1 not matched")
+	}
+
+	path, _ = NewRoutePathMatcher(enum.RoutePathTypePrefix, "/users/")
+	if path.MatchPathType() != ag.PathTypePrefix {
+		t.Errorf("path type = %v, want prefix", path.MatchPathType())
+	}
+}
+
+// 正则路由中的 { 不作为路径参数
+func TestRegexRouteRewrite(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Header().Set("X-Name", r.Header.Get("X-Name"))
+	}))
+	defer backend.Close()
+
+	upstream := ag.NewUpstream([]*ag.EndpointTarget{{Network: "tcp", Address: backend.Listener.Addr().String()}}, time.Second)
+	defer upstream.Stop()
+
+	route := &entity.Route{
+		Method:        []string{http.MethodGet},
+		Path:          "/v{1,2}/",
+		PathType:      enum.RoutePathTypeRegex,
+		PathRewrite:   &value.PathRewrite{Regex: "^/v+/(.*)$", Replace: "/{1,2}/$1"},
+		ModifyOptions: []*value.ModifyOption{{Type: ag.ModifyTypeSet, Source: ag.ModifySourceHeader, Name: "X-Name", Value: "{path.1,2}"}},
+	}
+	forward, err := NewForwardHandler(route, nil, upstream, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := ag.NewHandler()
+	h.Add(forward)
+	h.Sort()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/vv/items", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (%s)", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("X-Path"); got != "/{1,2}/items" {
+		t.Errorf("upstream path = %q, want /{1,2}/items", got)
+	}
+	if got := w.Header().Get("X-Name"); got != "" {
+		t.Errorf("X-Name = %q, want empty", got)
+	}
+}
@@ -3,10 +3,12 @@ package service
 import (
 	"context"
 
+	"dxkite.cn/meownest/pkg/database"
 	"dxkite.cn/meownest/pkg/identity"
 	"dxkite.cn/meownest/src/constant"
 	"dxkite.cn/meownest/src/dto"
 	"dxkite.cn/meownest/src/entity"
+	"dxkite.cn/meownest/src/enum"
 	"dxkite.cn/meownest/src/repository"
 	"dxkite.cn/meownest/src/value"
 )
@@ -22,16 +24,18 @@ type Authorize interface {
 type CreateAuthorizeParam struct {
 	Name        string                    `json:"name" binding:"required"`
 	Description string                    `json:"description"`
-	Type        string                    `json:"type" binding:"required"`
+	Type        enum.AuthorizeType        `json:"type" binding:"required"`
 	Attribute   *value.AuthorizeAttribute `json:"attribute"  binding:"required"`
 }
 
-func NewAuthorize(r repository.Authorize) Authorize {
-	return &authorize{r: r}
+func NewAuthorize(r repository.Authorize, rac repository.AuthorizeCredential, loader RouteLoader) Authorize {
+	return &authorize{r: r, rac: rac, loader: loader}
 }
 
 type authorize struct {
-	r repository.Authorize
+	r      repository.Authorize
+	rac    repository.AuthorizeCredential
+	loader RouteLoader
 }
 
 func (s *authorize) Create(ctx context.Context, param *CreateAuthorizeParam) (*dto.Authorize, error) {
@@ -69,10 +73,17 @@ type DeleteAuthorizeParam struct {
 }
 
 func (s *authorize) Delete(ctx context.Context, param *DeleteAuthorizeParam) error {
-	err := s.r.Delete(ctx, identity.Parse(constant.AuthorizePrefix, param.Id))
+	id := identity.Parse(constant.AuthorizePrefix, param.Id)
+	err := database.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.r.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.rac.DeleteByAuthorize(ctx, id)
+	})
 	if err != nil {
 		return err
 	}
+	reloadRoute(ctx, s.loader)
 	return nil
 }
 
@@ -142,5 +153,6 @@ func (s *authorize) Update(ctx context.Context, param *UpdateAuthorizeParam) (*d
 		return nil, err
 	}
 
+	reloadRoute(ctx, s.loader)
 	return s.Get(ctx, &GetAuthorizeParam{Id: param.Id})
 }
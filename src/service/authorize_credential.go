@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+
+	"dxkite.cn/meownest/pkg/database"
+	"dxkite.cn/meownest/pkg/identity"
+	"dxkite.cn/meownest/pkg/passwd"
+	"dxkite.cn/meownest/src/constant"
+	"dxkite.cn/meownest/src/dto"
+	"dxkite.cn/meownest/src/entity"
+	"dxkite.cn/meownest/src/enum"
+	"dxkite.cn/meownest/src/repository"
+)
+
+var ErrCredentialExist = errors.New("credential exist")
+var ErrCredentialNotSupported = errors.New("authorize type not support credential")
+var ErrCredentialPasswordRequired = errors.New("password required")
+
+const apiKeySecretSize = 24
+
+type AuthorizeCredential interface {
+	Create(ctx context.Context, param *CreateAuthorizeCredentialParam) (*dto.AuthorizeCredential, error)
+	List(ctx context.Context, param *ListAuthorizeCredentialParam) (*ListAuthorizeCredentialResult, error)
+	Delete(ctx context.Context, param *DeleteAuthorizeCredentialParam) error
+}
+
+func NewAuthorizeCredential(r repository.AuthorizeCredential, ra repository.Authorize, loader RouteLoader) AuthorizeCredential {
+	return &authorizeCredential{r: r, ra: ra, loader: loader}
+}
+
+type authorizeCredential struct {
+	r      repository.AuthorizeCredential
+	ra     repository.Authorize
+	loader RouteLoader
+}
+
+type CreateAuthorizeCredentialParam struct {
+	// 鉴权配置ID
+	Id string `json:"id" uri:"id" binding:"required"`
+	// 用户名或 API Key 的身份
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	// Basic 认证的密码，API Key 由服务生成
+	Password string `json:"password"`
+}
+
+func (s *authorizeCredential) Create(ctx context.Context, param *CreateAuthorizeCredentialParam) (*dto.AuthorizeCredential, error) {
+	authorizeId := identity.Parse(constant.AuthorizePrefix, param.Id)
+	auth, err := s.ra.Get(ctx, authorizeId)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := param.Password
+	switch auth.Type {
+	case enum.AuthorizeTypeBasic:
+		if secret == "" {
+			return nil, ErrCredentialPasswordRequired
+		}
+		item, err := s.r.GetByName(ctx, authorizeId, param.Name)
+		if err != nil && !errors.Is(err, repository.ErrAuthorizeCredentialNotExist) {
+			return nil, err
+		}
+		if item != nil {
+			return nil, ErrCredentialExist
+		}
+	case enum.AuthorizeTypeAPIKey:
+		if secret, err = newAPIKeySecret(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrCredentialNotSupported
+	}
+
+	hash, err := passwd.NewHash(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj *dto.AuthorizeCredential
+	err = database.Transaction(ctx, func(ctx context.Context) error {
+		ent := entity.NewAuthorizeCredential()
+		ent.AuthorizeId = authorizeId
+		ent.Name = param.Name
+		ent.Description = param.Description
+		ent.Hash = hash
+
+		resp, err := s.r.Create(ctx, ent)
+		if err != nil {
+			return err
+		}
+		if err := s.ra.Touch(ctx, authorizeId); err != nil {
+			return err
+		}
+
+		obj = dto.NewAuthorizeCredential(resp)
+		if auth.Type == enum.AuthorizeTypeAPIKey {
+			obj.Key = obj.Id + "." + secret
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reloadRoute(ctx, s.loader)
+	return obj, nil
+}
+
+func newAPIKeySecret() (string, error) {
+	b := make([]byte, apiKeySecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+type ListAuthorizeCredentialParam struct {
+	// 鉴权配置ID
+	Id string `json:"id" uri:"id" binding:"required"`
+
+	// pagination
+	Page         int  `json:"page" form:"page"`
+	PerPage      int  `json:"per_page" form:"per_page" binding:"max=1000"`
+	IncludeTotal bool `json:"include_total" form:"include_total"`
+}
+
+type ListAuthorizeCredentialResult struct {
+	Data  []*dto.AuthorizeCredential `json:"data"`
+	Total int64                      `json:"total,omitempty"`
+}
+
+func (s *authorizeCredential) List(ctx context.Context, param *ListAuthorizeCredentialParam) (*ListAuthorizeCredentialResult, error) {
+	if param.Page == 0 {
+		param.Page = 1
+	}
+
+	if param.PerPage == 0 {
+		param.PerPage = 10
+	}
+
+	listRst, err := s.r.List(ctx, &repository.ListAuthorizeCredentialParam{
+		AuthorizeId:  identity.Parse(constant.AuthorizePrefix, param.Id),
+		Page:         param.Page,
+		PerPage:      param.PerPage,
+		IncludeTotal: param.IncludeTotal,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*dto.AuthorizeCredential, len(listRst.Data))
+	for i, v := range listRst.Data {
+		items[i] = dto.NewAuthorizeCredential(v)
+	}
+
+	rst := &ListAuthorizeCredentialResult{}
+	rst.Data = items
+	rst.Total = listRst.Total
+	return rst, nil
+}
+
+type DeleteAuthorizeCredentialParam struct {
+	// 鉴权配置ID
+	Id string `json:"id" uri:"id" binding:"required"`
+	// 凭证ID
+	CredentialId string `json:"credential_id" uri:"credential_id" binding:"required"`
+}
+
+func (s *authorizeCredential) Delete(ctx context.Context, param *DeleteAuthorizeCredentialParam) error {
+	authorizeId := identity.Parse(constant.AuthorizePrefix, param.Id)
+	err := database.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.r.Delete(ctx, authorizeId, identity.Parse(constant.AuthorizeCredentialPrefix, param.CredentialId)); err != nil {
+			return err
+		}
+		return s.ra.Touch(ctx, authorizeId)
+	})
+	if err != nil {
+		return err
+	}
+
+	reloadRoute(ctx, s.loader)
+	return nil
+}
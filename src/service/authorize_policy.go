@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"dxkite.cn/meownest/pkg/identity"
+	"dxkite.cn/meownest/src/constant"
+	"dxkite.cn/meownest/src/dto"
+	"dxkite.cn/meownest/src/entity"
+	"dxkite.cn/meownest/src/enum"
+	"dxkite.cn/meownest/src/repository"
+)
+
+// 组合鉴权最大嵌套层数，防止循环引用
+const maxAuthorizePolicyDepth = 4
+
+var ErrAuthorizePolicyDepth = errors.New("authorize policy too deep")
+
+// 生效的鉴权配置来源
+type authorizeScope struct {
+	// route 或 collection
+	source      string
+	sourceId    uint64
+	disabled    bool
+	authorizeId uint64
+}
+
+// 路由及集合由近及远查找，第一个设置了鉴权或关闭了鉴权的层级生效，未找到时返回 nil
+func findAuthorizeScope(route *entity.Route, collectionIdList []uint64, collectionMap map[uint64]*entity.Collection) *authorizeScope {
+	if route.AuthorizeDisabled || route.AuthorizeId != 0 {
+		return &authorizeScope{source: "route", sourceId: route.Id, disabled: route.AuthorizeDisabled, authorizeId: route.AuthorizeId}
+	}
+
+	for _, v := range collectionIdList {
+		if coll, ok := collectionMap[v]; ok {
+			if coll.AuthorizeDisabled || coll.AuthorizeId != 0 {
+				return &authorizeScope{source: "collection", sourceId: coll.Id, disabled: coll.AuthorizeDisabled, authorizeId: coll.AuthorizeId}
+			}
+		}
+	}
+	return nil
+}
+
+// 路由所属集合及其上级集合，由近及远
+func getRouteCollectionList(ctx context.Context, rc repository.Collection, item *entity.Route) ([]uint64, error) {
+	collection := []uint64{}
+	source, err := rc.Get(ctx, item.CollectionId)
+	if err != nil {
+		return nil, err
+	}
+
+	collection = append(collection, source.Id)
+
+	idList := strings.Split(source.Index, ".")
+
+	for i := len(idList) - 1; i >= 0; i-- {
+		id, _ := strconv.ParseUint(idList[i], 10, 64)
+		if id > 0 {
+			collection = append(collection, id)
+		}
+	}
+
+	return collection, nil
+}
+
+func isAuthorizePolicy(auth *entity.Authorize) bool {
+	return auth.Type == enum.AuthorizeTypeAnyOf || auth.Type == enum.AuthorizeTypeAllOf
+}
+
+// 组合鉴权的成员
+func getAuthorizePolicyMembers(ctx context.Context, ra repository.Authorize, auth *entity.Authorize) ([]*entity.Authorize, error) {
+	if auth.Attribute == nil || auth.Attribute.Policy == nil {
+		return nil, errors.New("missing policy attribute")
+	}
+	members := []*entity.Authorize{}
+	for _, v := range auth.Attribute.Policy.AuthorizeIds {
+		item, err := ra.Get(ctx, identity.Parse(constant.AuthorizePrefix, v))
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, item)
+	}
+	return members, nil
+}
+
+// 生效的鉴权策略，展开组合鉴权的成员
+func newAuthorizePolicy(ctx context.Context, ra repository.Authorize, auth *entity.Authorize, depth int) (*dto.AuthorizePolicy, error) {
+	if depth > maxAuthorizePolicyDepth {
+		return nil, ErrAuthorizePolicyDepth
+	}
+
+	obj := &dto.AuthorizePolicy{Authorize: dto.NewAuthorize(auth)}
+	if !isAuthorizePolicy(auth) {
+		return obj, nil
+	}
+
+	members, err := getAuthorizePolicyMembers(ctx, ra, auth)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range members {
+		member, err := newAuthorizePolicy(ctx, ra, v, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		obj.Members = append(obj.Members, member)
+	}
+	return obj, nil
+}
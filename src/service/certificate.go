@@ -24,12 +24,27 @@ type CreateCertificateParam struct {
 	Certificate string `json:"certificate" form:"key" binding:"required"`
 }
 
-func NewCertificate(r repository.Certificate) Certificate {
-	return &certificate{r: r}
+// 证书加载，证书变更后重新加载
+type CertificateLoader interface {
+	LoadCertificate(ctx context.Context) error
+}
+
+func NewCertificate(r repository.Certificate, loader CertificateLoader) Certificate {
+	return &certificate{r: r, loader: loader}
 }
 
 type certificate struct {
-	r repository.Certificate
+	r      repository.Certificate
+	loader CertificateLoader
+}
+
+func (s *certificate) reload(ctx context.Context) {
+	if s.loader == nil {
+		return
+	}
+	if err := s.loader.LoadCertificate(ctx); err != nil {
+		printLog("reload certificate error: %s\n", err.Error())
+	}
 }
 
 func (s *certificate) Create(ctx context.Context, param *CreateCertificateParam) (*dto.Certificate, error) {
@@ -45,6 +60,7 @@ func (s *certificate) Create(ctx context.Context, param *CreateCertificateParam)
 		return nil, err
 	}
 
+	s.reload(ctx)
 	return dto.NewCertificate(resp), nil
 }
 
@@ -73,6 +89,7 @@ func (s *certificate) Delete(ctx context.Context, param *DeleteCertificateParam)
 	if err != nil {
 		return err
 	}
+	s.reload(ctx)
 	return nil
 }
 
@@ -142,5 +159,7 @@ func (s *certificate) Update(ctx context.Context, param *UpdateCertificateParam)
 		return nil, err
 	}
 
+	s.reload(ctx)
+
 	return s.Get(ctx, &GetCertificateParam{Id: param.Id})
 }
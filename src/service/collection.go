@@ -20,21 +20,22 @@ type Collection interface {
 	List(ctx context.Context, param *ListCollectionParam) (*ListCollectionResult, error)
 }
 
-func NewCollection(r repository.Collection, rr repository.Route, re repository.Endpoint, ra repository.Authorize) Collection {
-	return &collection{r: r, rr: rr, re: re, ra: ra}
+func NewCollection(r repository.Collection, rr repository.Route, re repository.Endpoint, ra repository.Authorize, loader RouteLoader) Collection {
+	return &collection{r: r, rr: rr, re: re, ra: ra, loader: loader}
 }
 
 type collection struct {
-	r  repository.Collection
-	rr repository.Route
-	re repository.Endpoint
-	ra repository.Authorize
+	r      repository.Collection
+	rr     repository.Route
+	re     repository.Endpoint
+	ra     repository.Authorize
+	loader RouteLoader
 }
 
 type CreateCollectionParam struct {
 	// 父级节点
 	ParentId string `json:"parent_id" form:"parent_id"`
-	// 绑定的域名
+	// 绑定的域名，支持通配符 *.example.com，未设置时继承上级集合
 	ServerNames []string `json:"server_names" form:"server_names"`
 	// 分组名
 	Name string `json:"name" form:"name" binding:"required"`
@@ -44,6 +45,10 @@ type CreateCollectionParam struct {
 	EndpointId string `json:"endpoint_id" form:"endpoint_id"`
 	// 鉴权配置
 	AuthorizeId string `json:"authorize_id" form:"authorize_id"`
+	// 关闭继承的鉴权，用于公开的子集合
+	AuthorizeDisabled bool `json:"authorize_disabled" form:"authorize_disabled"`
+	// HTTP 请求重定向到 HTTPS，子集合与路由继承
+	HttpsRedirect bool `json:"https_redirect" form:"https_redirect"`
 }
 
 func (s *collection) Create(ctx context.Context, param *CreateCollectionParam) (*dto.Collection, error) {
@@ -51,12 +56,14 @@ func (s *collection) Create(ctx context.Context, param *CreateCollectionParam) (
 
 	database.Transaction(ctx, func(txCtx context.Context) error {
 		item, err := s.r.Create(ctx, &entity.Collection{
-			Name:        param.Name,
-			Description: param.Description,
-			ServerNames: param.ServerNames,
-			ParentId:    identity.Parse(constant.CollectionPrefix, param.ParentId),
-			AuthorizeId: identity.Parse(constant.AuthorizePrefix, param.AuthorizeId),
-			EndpointId:  identity.Parse(constant.EndpointPrefix, param.EndpointId),
+			Name:              param.Name,
+			Description:       param.Description,
+			ServerNames:       param.ServerNames,
+			ParentId:          identity.Parse(constant.CollectionPrefix, param.ParentId),
+			AuthorizeId:       identity.Parse(constant.AuthorizePrefix, param.AuthorizeId),
+			AuthorizeDisabled: param.AuthorizeDisabled,
+			EndpointId:        identity.Parse(constant.EndpointPrefix, param.EndpointId),
+			HttpsRedirect:     param.HttpsRedirect,
 		})
 
 		if err != nil {
@@ -164,11 +171,15 @@ func (s *collection) Update(ctx context.Context, param *UpdateCollectionParam) (
 	database.Transaction(ctx, func(txCtx context.Context) error {
 		id := identity.Parse(constant.CollectionPrefix, param.Id)
 
-		err := s.r.Update(ctx, id, &entity.Collection{
-			Name:        param.Name,
-			ServerNames: param.ServerNames,
-			AuthorizeId: identity.Parse(constant.AuthorizePrefix, param.AuthorizeId),
-			EndpointId:  identity.Parse(constant.EndpointPrefix, param.EndpointId),
+		fields := []string{"name", "description", "server_names", "authorize_id", "authorize_disabled", "endpoint_id", "https_redirect"}
+		err := s.r.Update(ctx, id, fields, &entity.Collection{
+			Name:              param.Name,
+			Description:       param.Description,
+			ServerNames:       param.ServerNames,
+			AuthorizeId:       identity.Parse(constant.AuthorizePrefix, param.AuthorizeId),
+			AuthorizeDisabled: param.AuthorizeDisabled,
+			EndpointId:        identity.Parse(constant.EndpointPrefix, param.EndpointId),
+			HttpsRedirect:     param.HttpsRedirect,
 		})
 
 		if err != nil {
@@ -178,6 +189,7 @@ func (s *collection) Update(ctx context.Context, param *UpdateCollectionParam) (
 		return nil
 	})
 
+	reloadRoute(ctx, s.loader)
 	return s.Get(ctx, &GetCollectionParam{Id: param.Id})
 }
 
@@ -190,5 +202,6 @@ func (s *collection) Delete(ctx context.Context, param *DeleteCollectionParam) e
 	if err != nil {
 		return err
 	}
+	reloadRoute(ctx, s.loader)
 	return nil
 }
@@ -36,12 +36,14 @@ type Endpoint interface {
 	Update(ctx context.Context, param *UpdateEndpointParam) (*dto.Endpoint, error)
 }
 
-func NewEndpoint(r repository.Endpoint) Endpoint {
-	return &endpoint{r: r}
+func NewEndpoint(r repository.Endpoint, loader RouteLoader, streamLoader StreamLoader) Endpoint {
+	return &endpoint{r: r, loader: loader, streamLoader: streamLoader}
 }
 
 type endpoint struct {
-	r repository.Endpoint
+	r            repository.Endpoint
+	loader       RouteLoader
+	streamLoader StreamLoader
 }
 
 func (s *endpoint) Create(ctx context.Context, param *CreateEndpointParam) (*dto.Endpoint, error) {
@@ -74,6 +76,8 @@ func (s *endpoint) Delete(ctx context.Context, param *DeleteEndpointParam) error
 	if err != nil {
 		return err
 	}
+	reloadRoute(ctx, s.loader)
+	reloadStream(ctx, s.streamLoader)
 	return nil
 }
 
@@ -141,5 +145,7 @@ func (s *endpoint) Update(ctx context.Context, param *UpdateEndpointParam) (*dto
 		return nil, err
 	}
 
+	reloadRoute(ctx, s.loader)
+	reloadStream(ctx, s.streamLoader)
 	return s.Get(ctx, &GetEndpointParam{Id: param.Id})
 }
@@ -15,6 +15,10 @@ import (
 
 type Monitor interface {
 	Collection(ctx context.Context) error
+	// 写入未聚合的统计数据
+	Flush(ctx context.Context) error
+	// 更新统计配置，下次统计时生效
+	SetConfig(cfg *MonitorConfig)
 	ListDynamicStat(ctx context.Context, param *ListDynamicStatParam) (*DynamicStatResult, error)
 }
 
@@ -42,13 +46,26 @@ type monitor struct {
 
 func NewMonitor(cfg *MonitorConfig, r repository.Monitor) Monitor {
 	m := &monitor{r: r}
-	m.interval = cfg.Interval
-	m.maxInterval = cfg.MaxInterval
-	m.rollInterval = cfg.RollInterval
 	m.mtx = &sync.Mutex{}
+	m.SetConfig(cfg)
 	return m
 }
 
+func (s *monitor) SetConfig(cfg *MonitorConfig) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.interval = cfg.Interval
+	s.maxInterval = cfg.MaxInterval
+	s.rollInterval = cfg.RollInterval
+}
+
+func (s *monitor) getInterval() time.Duration {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return time.Duration(s.interval) * time.Second
+}
+
 type DynamicStatResult struct {
 	Collection      *dto.DynamicStatCollection `json:"collection"`
 	MemSwapTotal    uint64                     `json:"mem_swap_total"`
@@ -61,9 +78,15 @@ type ListDynamicStatParam struct {
 	EndTime   string `json:"end_time" form:"end_time"`
 }
 
-func (m monitor) ListDynamicStat(ctx context.Context, param *ListDynamicStatParam) (*DynamicStatResult, error) {
+func (s *monitor) ListDynamicStat(ctx context.Context, param *ListDynamicStatParam) (*DynamicStatResult, error) {
 	var startTime, endTime uint64
 
+	s.mtx.Lock()
+	maxInterval := s.maxInterval
+	status := s.status
+	memSwapTotal, memVirtualTotal, diskTotal := s.memSwapTotal, s.memVirtualTotal, s.diskTotal
+	s.mtx.Unlock()
+
 	if param.StartTime != "" {
 		v, err := time.Parse(time.RFC3339, param.StartTime)
 		if err != nil {
@@ -71,7 +94,7 @@ func (m monitor) ListDynamicStat(ctx context.Context, param *ListDynamicStatPara
 		}
 		startTime = uint64(v.Unix())
 	} else {
-		startTime = uint64(time.Now().Add(time.Duration(-m.maxInterval) * time.Second).Unix())
+		startTime = uint64(time.Now().Add(time.Duration(-maxInterval) * time.Second).Unix())
 	}
 
 	if param.EndTime != "" {
@@ -85,13 +108,13 @@ func (m monitor) ListDynamicStat(ctx context.Context, param *ListDynamicStatPara
 	}
 
 	realTimeStart := uint64(time.Now().Unix())
-	if len(m.status) > 0 {
-		realTimeStart = m.status[0].Time
+	if len(status) > 0 {
+		realTimeStart = status[0].Time
 	}
 
 	// 取实时数据
 	output := []*entity.DynamicStat{}
-	for _, v := range m.status {
+	for _, v := range status {
 		if v.Time < startTime {
 			continue
 		}
@@ -104,7 +127,7 @@ func (m monitor) ListDynamicStat(ctx context.Context, param *ListDynamicStatPara
 	// 取历史数据 -> 实时数据
 
 	if startTime < realTimeStart {
-		entities, err := m.r.ListDynamicStat(ctx, &repository.ListDynamicStatParam{
+		entities, err := s.r.ListDynamicStat(ctx, &repository.ListDynamicStatParam{
 			StartTime: startTime,
 			EndTime:   realTimeStart,
 		})
@@ -116,15 +139,25 @@ func (m monitor) ListDynamicStat(ctx context.Context, param *ListDynamicStatPara
 	}
 
 	resp := &DynamicStatResult{}
-	resp.Collection = dto.NewDynamicStatCollection(output, m.memSwapTotal, m.memVirtualTotal, m.diskTotal)
-	resp.MemSwapTotal = m.memSwapTotal
-	resp.MemVirtualTotal = m.memVirtualTotal
-	resp.DiskTotal = m.diskTotal
+	resp.Collection = dto.NewDynamicStatCollection(output, memSwapTotal, memVirtualTotal, diskTotal)
+	resp.MemSwapTotal = memSwapTotal
+	resp.MemVirtualTotal = memVirtualTotal
+	resp.DiskTotal = diskTotal
 	return resp, nil
 }
 
+// 定时采集统计数据，ctx 结束后返回
 func (s *monitor) Collection(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
 	for {
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		timer.Reset(s.getInterval())
 
 		v := &entity.DynamicStat{}
 		v.Time = uint64(time.Now().Unix())
@@ -146,12 +179,24 @@ func (s *monitor) Collection(ctx context.Context) error {
 
 		s.collect(ctx, v)
 
+		s.mtx.Lock()
 		s.memSwapTotal = vv.MemSwapTotal
 		s.memVirtualTotal = vv.MemVirtualTotal
 		s.diskTotal = vv.DiskTotal
+		s.mtx.Unlock()
+	}
+}
 
-		time.Sleep(time.Duration(s.interval) * time.Second)
+func (s *monitor) Flush(ctx context.Context) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if len(s.roll) == 0 {
+		return nil
 	}
+	err := s.rollCollect(ctx, s.roll)
+	s.roll = s.roll[0:0]
+	return err
 }
 
 func (s *monitor) collect(ctx context.Context, ent *entity.DynamicStat) {
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"dxkite.cn/meownest/src/entity"
+	"dxkite.cn/meownest/src/repository"
+)
+
+type memoryMonitorRepository struct{}
+
+func (memoryMonitorRepository) SaveDynamicStat(ctx context.Context, ent *entity.DynamicStat) (*entity.DynamicStat, error) {
+	return ent, nil
+}
+
+func (memoryMonitorRepository) ListDynamicStat(ctx context.Context, param *repository.ListDynamicStatParam) ([]*entity.DynamicStat, error) {
+	return nil, nil
+}
+
+func (memoryMonitorRepository) DeleteBefore(ctx context.Context, timeBefore uint64) error {
+	return nil
+}
+
+// 查询统计与更新配置、采集数据并发执行，使用 -race 检查
+func TestMonitorListDynamicStatConcurrent(t *testing.T) {
+	m := NewMonitor(&MonitorConfig{Interval: 1, MaxInterval: 60, RollInterval: 60}, memoryMonitorRepository{})
+	s := m.(*monitor)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			m.SetConfig(&MonitorConfig{Interval: 1, MaxInterval: 60 + i, RollInterval: 60})
+			s.collect(context.Background(), &entity.DynamicStat{Time: uint64(i)})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if _, err := m.ListDynamicStat(context.Background(), &ListDynamicStatParam{}); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
@@ -27,15 +27,16 @@ type Route interface {
 	Delete(ctx context.Context, param *DeleteRouteParam) error
 }
 
-func NewRoute(r repository.Route, re repository.Endpoint, rc repository.Collection, ra repository.Authorize) Route {
-	return &route{r: r, re: re, rc: rc, ra: ra}
+func NewRoute(r repository.Route, re repository.Endpoint, rc repository.Collection, ra repository.Authorize, loader RouteLoader) Route {
+	return &route{r: r, re: re, rc: rc, ra: ra, loader: loader}
 }
 
 type route struct {
-	r  repository.Route
-	re repository.Endpoint
-	rc repository.Collection
-	ra repository.Authorize
+	r      repository.Route
+	re     repository.Endpoint
+	rc     repository.Collection
+	ra     repository.Authorize
+	loader RouteLoader
 }
 
 type CreateRouteParam struct {
@@ -45,22 +46,28 @@ type CreateRouteParam struct {
 	Description string `json:"description" form:"description"`
 	// 支持方法
 	Method []string `json:"method" form:"method" binding:"required"`
+	// 匹配路径，CONNECT 路由可为空
+	Path string `json:"path" form:"path" binding:"required_without=Connect"`
 	// 匹配路径
-	Path string `json:"path" form:"path" binding:"required"`
-	// 匹配路径
-	PathType enum.RoutePathType `json:"path_type" form:"path" binding:"required"`
+	PathType enum.RoutePathType `json:"path_type" form:"path_type" binding:"required_without=Connect,omitempty,oneof=exact prefix param regex grpc"`
 	// 特殊匹配规则
 	MatchOptions []*value.MatchOption `json:"match_options" form:"match_options" binding:"dive,required"`
 	// 路径重写
 	PathRewrite *value.PathRewrite `json:"path_rewrite" form:"path_rewrite"`
 	// 数据编辑
-	ModifyOptions []*value.ModifyOption `json:"modify_options" form:"modify_options"`
+	ModifyOptions []*value.ModifyOption `json:"modify_options" form:"modify_options" binding:"dive,required"`
+	// CONNECT 隧道，设置后路由只处理目标在允许列表中的 CONNECT 请求
+	Connect *value.RouteConnect `json:"connect" form:"connect"`
+	// 将 gRPC-Web 请求转换为 gRPC 转发
+	GrpcWeb bool `json:"grpc_web" form:"grpc_web"`
 	// 路由分组ID
 	CollectionId string `json:"collection_id" form:"collection_id" binding:"required"`
 	// 绑定的后端服务
 	EndpointId string `json:"endpoint_id" form:"endpoint_id"`
 	// 鉴权配置
 	AuthorizeId string `json:"authorize_id" form:"authorize_id"`
+	// 关闭继承的鉴权，用于公开的路由
+	AuthorizeDisabled bool `json:"authorize_disabled" form:"authorize_disabled"`
 }
 
 func (s *route) Create(ctx context.Context, param *CreateRouteParam) (*dto.Route, error) {
@@ -68,18 +75,21 @@ func (s *route) Create(ctx context.Context, param *CreateRouteParam) (*dto.Route
 	err := database.Transaction(ctx, func(ctx context.Context) error {
 
 		ent, err := s.r.Create(ctx, &entity.Route{
-			Name:          param.Name,
-			Description:   param.Description,
-			Method:        param.Method,
-			Path:          param.Path,
-			PathType:      param.PathType,
-			MatchOptions:  param.MatchOptions,
-			PathRewrite:   param.PathRewrite,
-			ModifyOptions: param.ModifyOptions,
-			Status:        enum.RouteStatusInactive,
-			CollectionId:  identity.Parse(constant.CollectionPrefix, param.CollectionId),
-			AuthorizeId:   identity.Parse(constant.AuthorizePrefix, param.AuthorizeId),
-			EndpointId:    identity.Parse(constant.EndpointPrefix, param.EndpointId),
+			Name:              param.Name,
+			Description:       param.Description,
+			Method:            param.Method,
+			Path:              param.Path,
+			PathType:          param.PathType,
+			MatchOptions:      param.MatchOptions,
+			PathRewrite:       param.PathRewrite,
+			ModifyOptions:     param.ModifyOptions,
+			Connect:           param.Connect,
+			GrpcWeb:           param.GrpcWeb,
+			Status:            enum.RouteStatusInactive,
+			CollectionId:      identity.Parse(constant.CollectionPrefix, param.CollectionId),
+			AuthorizeId:       identity.Parse(constant.AuthorizePrefix, param.AuthorizeId),
+			AuthorizeDisabled: param.AuthorizeDisabled,
+			EndpointId:        identity.Parse(constant.EndpointPrefix, param.EndpointId),
 		})
 
 		if err != nil {
@@ -89,7 +99,12 @@ func (s *route) Create(ctx context.Context, param *CreateRouteParam) (*dto.Route
 		obj = dto.NewRoute(ent)
 		return nil
 	})
-	return obj, err
+	if err != nil {
+		return nil, err
+	}
+
+	reloadRoute(ctx, s.loader)
+	return obj, nil
 }
 
 func (s *route) Get(ctx context.Context, param *GetRouteParam) (*dto.Route, error) {
@@ -108,15 +123,59 @@ func (s *route) Get(ctx context.Context, param *GetRouteParam) (*dto.Route, erro
 	}
 
 	if utils.InStringSlice("authorize", param.Expand) {
-		ent, err := s.ra.Get(ctx, rst.AuthorizeId)
+		policy, err := s.getAuthorizePolicy(ctx, rst)
 		if err != nil {
 			return nil, err
 		}
-		obj.Authorize = dto.NewAuthorize(ent)
+		obj.Authorize = policy.Authorize
+		obj.AuthorizePolicy = policy
 	}
 	return obj, nil
 }
 
+// 路由生效的鉴权策略，包括从集合继承的鉴权
+func (s *route) getAuthorizePolicy(ctx context.Context, item *entity.Route) (*dto.AuthorizePolicy, error) {
+	collectionIdList, err := getRouteCollectionList(ctx, s.rc, item)
+	if err != nil {
+		return nil, err
+	}
+
+	collections, err := s.rc.BatchGet(ctx, collectionIdList)
+	if err != nil {
+		return nil, err
+	}
+
+	collectionMap := map[uint64]*entity.Collection{}
+	for i, v := range collections {
+		collectionMap[v.Id] = collections[i]
+	}
+
+	scope := findAuthorizeScope(item, collectionIdList, collectionMap)
+	if scope == nil {
+		return &dto.AuthorizePolicy{}, nil
+	}
+
+	policy := &dto.AuthorizePolicy{}
+	if !scope.disabled {
+		auth, err := s.ra.Get(ctx, scope.authorizeId)
+		if err != nil {
+			return nil, err
+		}
+		if policy, err = newAuthorizePolicy(ctx, s.ra, auth, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	policy.Source = scope.source
+	policy.Disabled = scope.disabled
+	if scope.source == "route" {
+		policy.SourceId = identity.Format(constant.RoutePrefix, scope.sourceId)
+	} else {
+		policy.SourceId = identity.Format(constant.CollectionPrefix, scope.sourceId)
+	}
+	return policy, nil
+}
+
 type ListRouteParam struct {
 	Name         string `json:"name" form:"name"`
 	Path         string `json:"path" form:"path"`
@@ -193,6 +252,7 @@ func (s *route) Delete(ctx context.Context, param *DeleteRouteParam) error {
 	if err != nil {
 		return err
 	}
+	reloadRoute(ctx, s.loader)
 	return nil
 }
 
@@ -208,21 +268,27 @@ type UpdateRouteParam struct {
 	// 匹配路径
 	Path *string `json:"path" form:"path"`
 	// 匹配路径类型
-	PathType *enum.RoutePathType `json:"path_type" form:"path_type"`
+	PathType *enum.RoutePathType `json:"path_type" form:"path_type" binding:"omitempty,oneof=exact prefix param regex grpc"`
 	// 特殊匹配规则
 	MatchOptions []*value.MatchOption `json:"match_options" form:"match_options" binding:"dive,required"`
 	// 路径重写
 	PathRewrite *value.PathRewrite `json:"path_rewrite" form:"path_rewrite"`
 	// 数据编辑
-	ModifyOptions []*value.ModifyOption `json:"modify_options" form:"modify_options"`
+	ModifyOptions []*value.ModifyOption `json:"modify_options" form:"modify_options" binding:"dive,required"`
+	// CONNECT 隧道
+	Connect *value.RouteConnect `json:"connect" form:"connect"`
+	// 将 gRPC-Web 请求转换为 gRPC 转发
+	GrpcWeb *bool `json:"grpc_web" form:"grpc_web"`
 	// 路由分组ID
 	CollectionId *string `json:"collection_id" form:"collection_id"`
 	// 绑定的后端服务
 	EndpointId *string `json:"endpoint_id" form:"endpoint_id"`
 	// 鉴权配置
 	AuthorizeId *string `json:"authorize_id" form:"authorize_id"`
+	// 关闭继承的鉴权
+	AuthorizeDisabled *bool `json:"authorize_disabled" form:"authorize_disabled"`
 	// 路由状态
-	Status *enum.RouteStatus `json:"status"`
+	Status *enum.RouteStatus `json:"status" binding:"omitempty,oneof=active inactive"`
 }
 
 func (s *route) Update(ctx context.Context, param *UpdateRouteParam) (*dto.Route, error) {
@@ -272,6 +338,16 @@ func (s *route) Update(ctx context.Context, param *UpdateRouteParam) (*dto.Route
 		ent.PathRewrite = param.PathRewrite
 	}
 
+	if param.Connect != nil {
+		updateFields = append(updateFields, "connect")
+		ent.Connect = param.Connect
+	}
+
+	if param.GrpcWeb != nil {
+		updateFields = append(updateFields, "grpc_web")
+		ent.GrpcWeb = *param.GrpcWeb
+	}
+
 	if param.Status != nil {
 		updateFields = append(updateFields, "status")
 		ent.Status = *param.Status
@@ -287,6 +363,11 @@ func (s *route) Update(ctx context.Context, param *UpdateRouteParam) (*dto.Route
 		ent.AuthorizeId = identity.Parse(constant.AuthorizePrefix, *param.AuthorizeId)
 	}
 
+	if param.AuthorizeDisabled != nil {
+		updateFields = append(updateFields, "authorize_disabled")
+		ent.AuthorizeDisabled = *param.AuthorizeDisabled
+	}
+
 	if param.EndpointId != nil {
 		updateFields = append(updateFields, "endpoint_id")
 		ent.EndpointId = identity.Parse(constant.EndpointPrefix, *param.EndpointId)
@@ -298,5 +379,7 @@ func (s *route) Update(ctx context.Context, param *UpdateRouteParam) (*dto.Route
 		return nil, err
 	}
 
+	reloadRoute(ctx, s.loader)
+
 	return s.Get(ctx, &GetRouteParam{Id: param.Id})
 }
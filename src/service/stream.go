@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+
+	"dxkite.cn/meownest/pkg/identity"
+	"dxkite.cn/meownest/src/constant"
+	"dxkite.cn/meownest/src/dto"
+	"dxkite.cn/meownest/src/entity"
+	"dxkite.cn/meownest/src/enum"
+	"dxkite.cn/meownest/src/repository"
+	"dxkite.cn/meownest/src/utils"
+)
+
+type Stream interface {
+	Create(ctx context.Context, param *CreateStreamParam) (*dto.Stream, error)
+	Get(ctx context.Context, param *GetStreamParam) (*dto.Stream, error)
+	Delete(ctx context.Context, param *DeleteStreamParam) error
+	List(ctx context.Context, param *ListStreamParam) (*ListStreamResult, error)
+	Update(ctx context.Context, param *UpdateStreamParam) (*dto.Stream, error)
+}
+
+// 四层转发加载，配置变更后重新加载并提供运行统计
+type StreamLoader interface {
+	ReloadStream(ctx context.Context)
+	StreamStats(id uint64) *dto.TunnelStats
+}
+
+func reloadStream(ctx context.Context, loader StreamLoader) {
+	if loader == nil {
+		return
+	}
+	loader.ReloadStream(ctx)
+}
+
+func NewStream(r repository.Stream, re repository.Endpoint, loader StreamLoader) Stream {
+	return &stream{r: r, re: re, loader: loader}
+}
+
+type stream struct {
+	r      repository.Stream
+	re     repository.Endpoint
+	loader StreamLoader
+}
+
+type CreateStreamParam struct {
+	Name        string `json:"name" form:"name" binding:"required"`
+	Description string `json:"description" form:"description"`
+	// 协议 tcp udp
+	Network enum.StreamNetwork `json:"network" form:"network" binding:"required,oneof=tcp udp"`
+	// 监听地址，如 :3306
+	Listen string `json:"listen" form:"listen" binding:"required,hostname_port"`
+	// TLS SNI 匹配的域名，支持通配符 *.example.com，仅 tcp 有效
+	// 相同监听地址的多个转发按 SNI 选择，未设置的作为默认转发
+	// 设置后该监听地址的每个连接都需等待客户端发送数据（最长 5 秒）后才连接后端，
+	// 服务端先发送数据的协议（如 MySQL、SMTP）需使用单独的监听地址
+	ServerNames []string `json:"server_names" form:"server_names"`
+	// 后端服务ID
+	EndpointId string `json:"endpoint_id" form:"endpoint_id" binding:"required"`
+}
+
+func (s *stream) Create(ctx context.Context, param *CreateStreamParam) (*dto.Stream, error) {
+	endpointId := identity.Parse(constant.EndpointPrefix, param.EndpointId)
+	if _, err := s.re.Get(ctx, endpointId); err != nil {
+		return nil, err
+	}
+
+	rst, err := s.r.Create(ctx, &entity.Stream{
+		Name:        param.Name,
+		Description: param.Description,
+		Network:     param.Network,
+		Listen:      param.Listen,
+		ServerNames: param.ServerNames,
+		EndpointId:  endpointId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reloadStream(ctx, s.loader)
+	return s.Get(ctx, &GetStreamParam{Id: identity.Format(constant.StreamPrefix, rst.Id)})
+}
+
+type GetStreamParam struct {
+	Id     string   `json:"id" uri:"id" binding:"required"`
+	Expand []string `json:"expand" form:"expand"`
+}
+
+func (s *stream) Get(ctx context.Context, param *GetStreamParam) (*dto.Stream, error) {
+	rst, err := s.r.Get(ctx, identity.Parse(constant.StreamPrefix, param.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	obj := s.newStream(rst)
+
+	if utils.InStringSlice("endpoint", param.Expand) {
+		ent, err := s.re.Get(ctx, rst.EndpointId)
+		if err != nil {
+			return nil, err
+		}
+		obj.Endpoint = dto.NewEndpoint(ent)
+	}
+	return obj, nil
+}
+
+func (s *stream) newStream(item *entity.Stream) *dto.Stream {
+	obj := dto.NewStream(item)
+	if s.loader != nil {
+		obj.Stats = s.loader.StreamStats(item.Id)
+	}
+	return obj
+}
+
+type DeleteStreamParam struct {
+	Id string `json:"id" uri:"id" binding:"required"`
+}
+
+func (s *stream) Delete(ctx context.Context, param *DeleteStreamParam) error {
+	err := s.r.Delete(ctx, identity.Parse(constant.StreamPrefix, param.Id))
+	if err != nil {
+		return err
+	}
+	reloadStream(ctx, s.loader)
+	return nil
+}
+
+type ListStreamParam struct {
+	Name       string `form:"name"`
+	Listen     string `form:"listen"`
+	EndpointId string `form:"endpoint_id"`
+
+	// pagination
+	Page         int  `json:"page" form:"page"`
+	PerPage      int  `json:"per_page" form:"per_page" binding:"max=1000"`
+	IncludeTotal bool `json:"include_total" form:"include_total"`
+}
+
+type ListStreamResult struct {
+	Data  []*dto.Stream `json:"data"`
+	Total int64         `json:"total,omitempty"`
+}
+
+func (s *stream) List(ctx context.Context, param *ListStreamParam) (*ListStreamResult, error) {
+	if param.Page == 0 {
+		param.Page = 1
+	}
+
+	if param.PerPage == 0 {
+		param.PerPage = 10
+	}
+
+	listRst, err := s.r.List(ctx, &repository.ListStreamParam{
+		Name:         param.Name,
+		Listen:       param.Listen,
+		EndpointId:   identity.Parse(constant.EndpointPrefix, param.EndpointId),
+		Page:         param.Page,
+		PerPage:      param.PerPage,
+		IncludeTotal: param.IncludeTotal,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(listRst.Data)
+
+	items := make([]*dto.Stream, n)
+
+	for i, v := range listRst.Data {
+		items[i] = s.newStream(v)
+	}
+
+	rst := &ListStreamResult{}
+	rst.Data = items
+	rst.Total = listRst.Total
+	return rst, nil
+}
+
+type UpdateStreamParam struct {
+	Id string `json:"id" uri:"id" binding:"required"`
+	CreateStreamParam
+}
+
+func (s *stream) Update(ctx context.Context, param *UpdateStreamParam) (*dto.Stream, error) {
+	endpointId := identity.Parse(constant.EndpointPrefix, param.EndpointId)
+	if _, err := s.re.Get(ctx, endpointId); err != nil {
+		return nil, err
+	}
+
+	id := identity.Parse(constant.StreamPrefix, param.Id)
+	fields := []string{"name", "description", "network", "listen", "server_names", "endpoint_id"}
+	err := s.r.Update(ctx, id, fields, &entity.Stream{
+		Name:        param.Name,
+		Description: param.Description,
+		Network:     param.Network,
+		Listen:      param.Listen,
+		ServerNames: param.ServerNames,
+		EndpointId:  endpointId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reloadStream(ctx, s.loader)
+	return s.Get(ctx, &GetStreamParam{Id: param.Id})
+}
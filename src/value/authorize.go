@@ -1,7 +1,13 @@
 package value
 
 type AuthorizeAttribute struct {
-	Binary *AuthorizeAttributeBinary `json:"binary,omitempty"`
+	Binary  *AuthorizeAttributeBinary  `json:"binary,omitempty"`
+	JWT     *AuthorizeAttributeJWT     `json:"jwt,omitempty"`
+	Basic   *AuthorizeAttributeBasic   `json:"basic,omitempty"`
+	APIKey  *AuthorizeAttributeAPIKey  `json:"apikey,omitempty"`
+	Forward *AuthorizeAttributeForward `json:"forward,omitempty"`
+	OIDC    *AuthorizeAttributeOIDC    `json:"oidc,omitempty"`
+	Policy  *AuthorizeAttributePolicy  `json:"policy,omitempty"`
 }
 
 type AuthorizeAttributeBinary struct {
@@ -14,3 +20,98 @@ type AuthorizeSource struct {
 	Source string `json:"source" binding:"required"` // 匹配源
 	Name   string `json:"name" binding:"required"`   // 匹配值
 }
+
+type AuthorizeAttributeJWT struct {
+	// 允许的签名算法
+	Algorithms []string `json:"algorithms" binding:"required,min=1,dive,oneof=HS256 RS256 ES256"`
+	// HS256 共享密钥或 PEM 格式公钥
+	Key string `json:"key"`
+	// JWKS 地址，http(s) URL 或文件路径，设置后忽略 Key
+	JWKS string `json:"jwks"`
+	// JWKS 缓存时间，单位秒
+	JWKSCacheTTL int `json:"jwks_cache_ttl" binding:"min=0"`
+	// 签发者
+	Issuer string `json:"issuer"`
+	// 受众，匹配任意一个
+	Audience []string `json:"audience"`
+	// 时间校验误差，单位秒
+	Leeway int `json:"leeway" binding:"min=0"`
+	// 是否要求令牌包含 exp 声明，默认要求
+	RequireExp *bool `json:"require_exp"`
+	// 令牌来源，为空时使用 Authorization: Bearer
+	Sources []*AuthorizeSource `json:"sources" binding:"dive,required"`
+	// 转发到后端的声明
+	ClaimHeaders []*AuthorizeClaimHeader `json:"claim_headers" binding:"dive,required"`
+}
+
+type AuthorizeClaimHeader struct {
+	Claim  string `json:"claim" binding:"required"`  // 声明名称
+	Header string `json:"header" binding:"required"` // 请求头
+}
+
+// 凭证通过 /authorizes/{id}/credentials 管理
+type AuthorizeAttributeBasic struct {
+	// 认证域
+	Realm string `json:"realm"`
+	// 认证通过后写入用户名的请求头
+	Header string `json:"header"`
+	// 凭证来源，为空时使用 Authorization 请求头
+	Sources []*AuthorizeSource `json:"sources" binding:"dive,required"`
+}
+
+type AuthorizeAttributeAPIKey struct {
+	// 认证通过后写入凭证名称的请求头
+	Header string `json:"header"`
+	// 凭证来源，为空时使用 X-API-Key 请求头
+	Sources []*AuthorizeSource `json:"sources" binding:"dive,required"`
+}
+
+// 转发子请求到鉴权服务，2xx 放行，401/403 拒绝
+type AuthorizeAttributeForward struct {
+	// 鉴权服务的后端服务ID
+	EndpointId string `json:"endpoint_id" binding:"required"`
+	// 子请求方法，默认 GET
+	Method string `json:"method"`
+	// 子请求路径，默认 /
+	Path string `json:"path"`
+	// 转发到鉴权服务的请求头
+	Headers []string `json:"headers"`
+	// 转发到鉴权服务的 Cookie
+	Cookies []string `json:"cookies"`
+	// 鉴权通过后复制到后端请求的响应头
+	ResponseHeaders []string `json:"response_headers"`
+	// 鉴权结果缓存时间，单位秒，为 0 或未配置请求头与 Cookie 时不缓存
+	// 按请求方法、域名、地址、客户端 IP 与凭证缓存
+	CacheTTL int `json:"cache_ttl" binding:"min=0"`
+}
+
+// 浏览器跳转到 OIDC 提供方登录，登录后使用加密的会话 Cookie
+type AuthorizeAttributeOIDC struct {
+	// 提供方 issuer
+	Issuer       string `json:"issuer" binding:"required"`
+	ClientId     string `json:"client_id" binding:"required"`
+	ClientSecret string `json:"client_secret"`
+	// 登录回调路径，需能匹配到使用此鉴权的路由
+	CallbackPath string `json:"callback_path" binding:"required"`
+	// 为空时使用 openid profile email
+	Scopes []string `json:"scopes"`
+	// ID Token 允许的签名算法，为空时使用 RS256 与 ES256
+	Algorithms []string `json:"algorithms" binding:"dive,oneof=RS256 ES256"`
+	// 会话 Cookie 加密密钥，长度 16、24 或 32
+	CookieKey  string `json:"cookie_key" binding:"required"`
+	CookieName string `json:"cookie_name"`
+	// 始终设置 Cookie 的 Secure 标记，TLS 由前置代理终止时开启
+	CookieSecure bool `json:"cookie_secure"`
+	// 会话有效期，单位秒，为 0 时使用 ID Token 的过期时间
+	SessionTTL int `json:"session_ttl" binding:"min=0"`
+	// 时间校验误差，单位秒
+	Leeway int `json:"leeway" binding:"min=0"`
+	// 转发到后端的声明
+	ClaimHeaders []*AuthorizeClaimHeader `json:"claim_headers" binding:"dive,required"`
+}
+
+// 组合鉴权，any_of 任意一个通过，all_of 全部通过
+type AuthorizeAttributePolicy struct {
+	// 按顺序执行的鉴权配置ID
+	AuthorizeIds []string `json:"authorize_ids" binding:"required,min=1"`
+}
@@ -27,12 +27,97 @@ type ForwardEndpoint struct {
 type ForwardEndpointStatic struct {
 	Timeout int                      `json:"timeout"`
 	Address []*ForwardEndpointTarget `json:"address" binding:"required"`
+	// 负载均衡，默认加权随机
+	Balance *ForwardEndpointBalance `json:"balance,omitempty"`
+	// 健康检查
+	HealthCheck *ForwardEndpointHealthCheck `json:"health_check,omitempty"`
+	// 连接池，默认启用
+	Pool *ForwardEndpointPool `json:"pool,omitempty"`
+	// 重试策略
+	Retry *ForwardEndpointRetry `json:"retry,omitempty"`
+}
+
+type ForwardEndpointRetry struct {
+	// 最大尝试次数，包括首次请求
+	MaxAttempts int `json:"max_attempts" binding:"required,min=1,max=10"`
+	// 重试的错误类型 connect_error read_error
+	RetryOn []string `json:"retry_on" binding:"dive,oneof=connect_error read_error"`
+	// 重试的响应状态码
+	RetryStatus []int `json:"retry_status" binding:"dive,min=100,max=599"`
+	// 允许重试非幂等请求（POST/PATCH）
+	NonIdempotent bool `json:"non_idempotent"`
+	// 单次尝试超时（毫秒），0 不限制
+	PerTryTimeout int `json:"per_try_timeout" binding:"min=0"`
+	// 缓存请求体的最大长度（字节），超过时不重试，默认 1MB
+	MaxBodySize int64 `json:"max_body_size" binding:"min=0"`
+}
+
+type ForwardEndpointPool struct {
+	// 每个目标最大空闲连接数，0 不复用连接
+	MaxIdle int `json:"max_idle" binding:"min=0"`
+	// 空闲超时（毫秒），0 不超时
+	IdleTimeout int `json:"idle_timeout" binding:"min=0"`
+	// 每个目标最大连接数，0 不限制
+	MaxConns int `json:"max_conns" binding:"min=0"`
+}
+
+type ForwardEndpointHealthCheck struct {
+	// 主动检查
+	Active *ForwardEndpointActiveCheck `json:"active,omitempty"`
+	// 被动检查
+	Passive *ForwardEndpointPassiveCheck `json:"passive,omitempty"`
+}
+
+type ForwardEndpointActiveCheck struct {
+	// 检查方式
+	Type string `json:"type" binding:"required,oneof=tcp http"`
+	// 检查间隔（毫秒）
+	Interval int `json:"interval" binding:"required,min=100"`
+	// 检查超时（毫秒），默认为检查间隔
+	Timeout int `json:"timeout"`
+	// HTTP 检查路径
+	Path string `json:"path" binding:"required_if=Type http"`
+	// HTTP 期望状态码，默认 2xx/3xx
+	ExpectStatus []int `json:"expect_status"`
+	// 连续成功次数达到后恢复
+	HealthyThreshold int `json:"healthy_threshold"`
+	// 连续失败次数达到后标记不健康
+	UnhealthyThreshold int `json:"unhealthy_threshold"`
+}
+
+type ForwardEndpointPassiveCheck struct {
+	// 连续连接失败次数达到后摘除
+	MaxFails int `json:"max_fails" binding:"required,min=1"`
+	// 摘除时长（毫秒）
+	FailTimeout int `json:"fail_timeout" binding:"required"`
+}
+
+type ForwardEndpointBalance struct {
+	// 均衡策略
+	Type string `json:"type" binding:"required,oneof=random round_robin least_conn hash"`
+	// 一致性哈希取值来源 header cookie query ip
+	Source string `json:"source" binding:"required_if=Type hash"`
+	// 一致性哈希取值名称
+	Name string `json:"name"`
+	// 会话保持
+	Sticky *ForwardEndpointSticky `json:"sticky,omitempty"`
+}
+
+type ForwardEndpointSticky struct {
+	// 会话保持 cookie 名
+	Cookie string `json:"cookie" binding:"required"`
+	// cookie 有效期（秒），0 为会话 cookie
+	MaxAge int `json:"max_age"`
 }
 
 type ForwardEndpointTarget struct {
 	Network string `json:"network" binding:"required"`
 	Address string `json:"address" binding:"required"`
 	Weight  int    `json:"weight" binding:"required"`
+	// 后端协议 http1 h2 h2c，默认 http1，h2 使用 TLS 连接
+	Protocol string `json:"protocol,omitempty" binding:"omitempty,oneof=http1 h2 h2c"`
+	// h2 不验证后端证书
+	TLSInsecureSkipVerify bool `json:"tls_insecure_skip_verify,omitempty"`
 }
 
 // 证书对象
@@ -47,8 +132,16 @@ type PathRewrite struct {
 }
 
 type ModifyOption struct {
-	Type   string `json:"type" binding:"required"`   // 编辑类型
-	Source string `json:"source" binding:"required"` // 编辑源
-	Name   string `json:"name" binding:"required"`   // 名称
-	Value  string `json:"value"`                     // 值
+	Type   string `json:"type" binding:"required,oneof=add set delete"`                        // 编辑类型
+	Source string `json:"source" binding:"required,oneof=header query cookie response_header"` // 编辑源
+	Name   string `json:"name" binding:"required"`                                             // 名称
+	Value  string `json:"value"`                                                               // 值，支持模板变量 {path.name} {header.name} {query.name} {cookie.name} {ip}
+}
+
+// CONNECT 隧道配置，设置后路由只处理 CONNECT 请求，不使用后端服务
+type RouteConnect struct {
+	// 允许的目标，格式 host:port，host 支持 *.example.com、* 与 CIDR，省略端口匹配任意端口
+	Allow []string `json:"allow" binding:"required,min=1"`
+	// 连接目标超时，单位毫秒
+	Timeout int `json:"timeout"`
 }